@@ -0,0 +1,221 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"golang.org/x/image/vector"
+)
+
+// arrowHeadAngle is the angle between the shaft and each barb.
+const arrowHeadAngle = math.Pi / 7
+
+// arrowStyle is the shape of the heads of an Arrow stroke.
+type arrowStyle uint8
+
+const (
+	arrowOpen   arrowStyle = iota // two barbs, a V
+	arrowClosed                   // a filled triangle
+	arrowBarbed                   // a filled triangle notched at the back
+)
+
+var arrowStyleNames = [...]string{
+	arrowOpen:   "open",
+	arrowClosed: "closed",
+	arrowBarbed: "barbed",
+}
+
+func (st arrowStyle) String() string { return arrowStyleNames[st] }
+
+// filled reports whether heads of this style are filled shapes.
+func (st arrowStyle) filled() bool { return st != arrowOpen }
+
+// parseArrowStyle is the inverse of String; "" is the open head.
+func parseArrowStyle(name string) (arrowStyle, bool) {
+	if name == "" {
+		return arrowOpen, true
+	}
+	for st, n := range arrowStyleNames {
+		if n == name {
+			return arrowStyle(st), true
+		}
+	}
+	return 0, false
+}
+
+// cycleArrowStyle steps the heads of new arrows through open, closed and
+// barbed, at the end and then at both ends.
+func (a *Annotator) cycleArrowStyle() {
+	if a.arrowStyle++; int(a.arrowStyle) == len(arrowStyleNames) {
+		a.arrowStyle, a.arrowBoth = arrowOpen, !a.arrowBoth
+	}
+	if a.arrowBoth {
+		a.notify("Arrowheads: %v, both ends", a.arrowStyle)
+	} else {
+		a.notify("Arrowheads: %v", a.arrowStyle)
+	}
+}
+
+// arrowHead returns the two barb tips of an arrow pointing from from to
+// to. The head scales with the pen width so thick arrows stay legible.
+func arrowHead(from, to f32.Point, width float32) (left, right f32.Point) {
+	l := max(4*width, 14)
+	th := math.Atan2(float64(to.Y-from.Y), float64(to.X-from.X))
+	barb := func(a float64) f32.Point {
+		return f32.Pt(to.X-l*float32(math.Cos(a)), to.Y-l*float32(math.Sin(a)))
+	}
+	return barb(th - arrowHeadAngle), barb(th + arrowHeadAngle)
+}
+
+// arrowPoints builds an arrow from from to to as a single polyline
+// (shaft, one barb, back to the tip, the other barb), densified like a
+// freehand stroke so every renderer draws it.
+func arrowPoints(from, to f32.Point, width float32) []f32.Point {
+	left, right := arrowHead(from, to, width)
+	return polylinePoints([]f32.Point{from, to, left, to, right}, width/2)
+}
+
+// arrowifyLast replaces the last committed stroke with a straight arrow
+// from its first to its last point, keeping its color and width, with
+// the current arrowheads.
+func (a *Annotator) arrowifyLast() bool {
+	if len(a.strokes) == 0 {
+		return false
+	}
+	s := &a.strokes[len(a.strokes)-1]
+	from, to := s.Pts[0], s.Pts[len(s.Pts)-1]
+	if dist(from, to) < 1 {
+		return false
+	}
+	s.Pts = polylinePoints([]f32.Point{from, to}, s.Width/2)
+	s.Widths = nil
+	s.Arrow, s.Head, s.BothEnds = true, a.arrowStyle, a.arrowBoth
+	return true
+}
+
+// arrowNotch is how far into a barbed head its back is cut, as a share
+// of the distance from the tip to the line between the barbs.
+const arrowNotch = 0.6
+
+// heads returns the arrowheads of an Arrow stroke, each as a polyline
+// outline: one barb, the tip, the other barb for open heads, and closed
+// around the shape for filled ones.
+func (s *Stroke) heads() [][]f32.Point {
+	if !s.Arrow || len(s.Pts) < 2 {
+		return nil
+	}
+	var heads [][]f32.Point
+	if h := s.headAt(len(s.Pts)-1, -1); h != nil {
+		heads = append(heads, h)
+	}
+	if h := s.headAt(0, 1); s.BothEnds && h != nil {
+		heads = append(heads, h)
+	}
+	return heads
+}
+
+// headAt is the head at the point end, aimed along the path walking
+// from end in direction dir. The direction is taken over the last few
+// widths of the path rather than the last sample pair, which is too
+// short to be meaningful after interpolation.
+func (s *Stroke) headAt(end, dir int) []f32.Point {
+	tip := s.Pts[end]
+	from := s.Pts[len(s.Pts)-1-end]
+	reach := max(2*s.Width, 10)
+	for i := end + dir; i >= 0 && i < len(s.Pts); i += dir {
+		if dist(s.Pts[i], tip) >= reach {
+			from = s.Pts[i]
+			break
+		}
+	}
+	if dist(from, tip) < 1 {
+		return nil
+	}
+	left, right := arrowHead(from, tip, s.Width)
+	corners := []f32.Point{left, tip, right}
+	switch s.Head {
+	case arrowClosed:
+		corners = []f32.Point{tip, left, right, tip}
+	case arrowBarbed:
+		notch := tip.Add(left.Add(right).Mul(0.5).Sub(tip).Mul(arrowNotch))
+		corners = []f32.Point{tip, left, notch, right, tip}
+	}
+	return polylinePoints(corners, s.Width/2)
+}
+
+// withHeads returns the points of s followed by those of its heads, for
+// bounds.
+func (s *Stroke) withHeads() []f32.Point {
+	pts := s.Pts
+	for _, h := range s.heads() {
+		pts = append(pts[:len(pts):len(pts)], h...)
+	}
+	return pts
+}
+
+// drawHeads draws the arrowheads of s, filling in filled ones.
+func drawHeads(ops *op.Ops, s *Stroke) {
+	for _, h := range s.heads() {
+		if s.Head.filled() {
+			fillPolygon(ops, h, s.Col)
+		}
+		if !s.Chalk {
+			drawPolyline(ops, h, nil, s.Col, s.Width)
+		}
+	}
+}
+
+func fillPolygon(ops *op.Ops, pts []f32.Point, col color.NRGBA) {
+	fillPolygons(ops, [][]f32.Point{pts}, col)
+}
+
+// fillPolygons fills polys as one shape, by the nonzero rule.
+func fillPolygons(ops *op.Ops, polys [][]f32.Point, col color.NRGBA) {
+	var p clip.Path
+	p.Begin(ops)
+	for _, pts := range polys {
+		p.MoveTo(pts[0])
+		for _, q := range pts[1:] {
+			p.LineTo(q)
+		}
+		p.Close()
+	}
+	paint.FillShape(ops, col, clip.Outline{Path: p.End()}.Op())
+}
+
+// rasterHeads is the mask counterpart of drawHeads, into mask.
+func rasterHeads(mask *image.Alpha, s *Stroke) {
+	for _, h := range s.heads() {
+		if s.Head.filled() {
+			rasterPolygon(mask, h)
+		}
+		if !s.Chalk {
+			rasterPolyline(mask, h, nil, s.Width)
+		}
+	}
+}
+
+// rasterPolygon fills the polygon pts into mask, opaque.
+func rasterPolygon(mask *image.Alpha, pts []f32.Point) {
+	rasterPolygons(mask, [][]f32.Point{pts})
+}
+
+// rasterPolygons fills polys into mask as one shape, opaque.
+func rasterPolygons(mask *image.Alpha, polys [][]f32.Point) {
+	r := mask.Bounds()
+	z := vector.NewRasterizer(r.Dx(), r.Dy())
+	o := f32.Pt(float32(r.Min.X), float32(r.Min.Y))
+	for _, pts := range polys {
+		z.MoveTo(pts[0].X-o.X, pts[0].Y-o.Y)
+		for _, q := range pts[1:] {
+			z.LineTo(q.X-o.X, q.Y-o.Y)
+		}
+		z.ClosePath()
+	}
+	z.Draw(mask, r, image.Opaque, image.Point{})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Auto-save (-autosave) keeps a recovery file with the current strokes,
+// including the one being drawn, so a crash or kill loses at most one
+// interval of work; -restore loads it back. The file is rewritten only
+// when its contents change, and is left in place on exit, so it also
+// undoes an accidental quit. An idle overlay is not woken for it: the
+// next check is only scheduled after input or a change of the strokes.
+
+// recoveryPath returns the recovery file of overlay n (0-based).
+func recoveryPath(n int) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := "recovery.json"
+	if n > 0 {
+		name = fmt.Sprintf("recovery-%d.json", n+1)
+	}
+	return filepath.Join(dir, "screenpengo", name), nil
+}
+
+// autosave writes the recovery file if the interval has passed since the
+// last check, and otherwise, if something may have changed since that
+// check, makes sure a frame comes when it has, so the last change is
+// saved even if nothing else happens.
+func (a *Annotator) autosave(gtx layout.Context) {
+	if a.autosaveEvery <= 0 || a.recoveryFile == "" {
+		return
+	}
+	if next := a.autosavedAt.Add(a.autosaveEvery); gtx.Now.Before(next) {
+		if a.activeAt.After(a.autosavedAt) || a.cur != nil || a.strokesMark() != a.autosaveMark {
+			gtx.Execute(op.InvalidateCmd{At: next})
+		}
+		return
+	}
+	a.autosavedAt, a.autosaveMark = gtx.Now, a.strokesMark()
+	sf := a.session()
+	if a.cur != nil && len(a.cur.Pts) > 0 {
+		// The unfinished stroke is saved as if released now.
+		sf.Strokes = append(sf.Strokes, strokeToJSON(*a.cur))
+	}
+	if a.autosaved == nil && len(sf.Strokes) == 0 {
+		// Nothing drawn yet; keep a previous run's file for -restore.
+		return
+	}
+	data, err := json.Marshal(sf)
+	if err != nil || bytes.Equal(data, a.autosaved) {
+		return
+	}
+	if err := writeFileAtomic(a.recoveryFile, data); err != nil {
+		log.Printf("autosave: %v", err)
+		return
+	}
+	a.autosaved = data
+	if a.debug {
+		log.Printf("autosave: wrote %d strokes to %s", len(sf.Strokes), a.recoveryFile)
+	}
+}
+
+// restore loads the strokes of the recovery file.
+func (a *Annotator) restore() error {
+	data, err := os.ReadFile(a.recoveryFile)
+	if err != nil {
+		return err
+	}
+	var sf sessionFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return fmt.Errorf("%s: %w", a.recoveryFile, err)
+	}
+	strokes, err := sf.strokes()
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.recoveryFile, err)
+	}
+	// The window size is not known yet; fitStartup scales them.
+	a.restoreFrom, a.restoreCanvas = len(a.strokes), sf.canvas()
+	a.strokes = append(a.strokes, strokes...)
+	a.autosaved = data
+	return nil
+}
+
+// writeFileAtomic replaces path with data via a temporary file, so a crash
+// mid-write does not destroy the previous contents.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
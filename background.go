@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+	xdraw "golang.org/x/image/draw"
+)
+
+// How a loaded background (-background) is scaled to the window.
+const (
+	bgFit     = "fit"     // whole image, aspect kept, bars of -background-color
+	bgFill    = "fill"    // covers the window, aspect kept, edges cropped
+	bgStretch = "stretch" // exactly the window, aspect ignored
+)
+
+var bgFitModes = []string{bgFit, bgFill, bgStretch}
+
+// bgRect is where an image of the given size goes in a window of size
+// win under mode, centered.
+func bgRect(img, win image.Point, mode string) image.Rectangle {
+	if mode == bgStretch || img.X <= 0 || img.Y <= 0 {
+		return image.Rectangle{Max: win}
+	}
+	sx, sy := float64(win.X)/float64(img.X), float64(win.Y)/float64(img.Y)
+	s := min(sx, sy)
+	if mode == bgFill {
+		s = max(sx, sy)
+	}
+	size := image.Pt(int(float64(img.X)*s+0.5), int(float64(img.Y)*s+0.5))
+	off := win.Sub(size).Div(2)
+	return image.Rectangle{Min: off, Max: off.Add(size)}
+}
+
+// layoutBackground (re)composes a loaded background for the current window
+// size and fit mode. The result becomes a.bg, so exports and the redaction
+// pen see the background exactly as displayed. Strokes are kept on the
+// same image content: when the image moves or scales, so do they.
+func (a *Annotator) layoutBackground() {
+	if a.bgSrc == nil || a.size.X <= 0 || a.size.Y <= 0 {
+		return
+	}
+	r := bgRect(a.bgSrc.Bounds().Size(), a.size, a.bgFit)
+	if a.bg != nil && a.bgRect == r && a.bg.Bounds().Size() == a.size {
+		return
+	}
+	if !a.bgRect.Empty() && a.bgRect != r {
+		a.mapStrokes(a.bgRect, r)
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: a.size})
+	xdraw.Draw(dst, dst.Bounds(), image.NewUniform(a.bgFill), image.Point{}, xdraw.Src)
+	xdraw.BiLinear.Scale(dst, r, a.bgSrc, a.bgSrc.Bounds(), xdraw.Over, nil)
+	a.bg, a.bgRect = dst, r
+	a.bgOp = paint.NewImageOp(dst)
+}
+
+// cycleBackgroundFit switches a loaded background to the next fit mode.
+func (a *Annotator) cycleBackgroundFit() {
+	if a.bgSrc == nil {
+		a.notify("No background loaded (-background)")
+		return
+	}
+	for i, m := range bgFitModes {
+		if m == a.bgFit {
+			a.bgFit = bgFitModes[(i+1)%len(bgFitModes)]
+			break
+		}
+	}
+	a.layoutBackground()
+	a.notify("Background: %s", a.bgFit)
+}
+
+func (a *Annotator) drawBackground(gtx layout.Context) {
+	if a.bgSrc == nil || a.bg == nil {
+		return
+	}
+	a.bgOp.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+}
+
+// mapStrokes moves and scales all strokes from the rectangle from to the
+// rectangle to. Widths are left alone: a pen stays the pen it was.
+func (a *Annotator) mapStrokes(from, to image.Rectangle) {
+	sx := float32(to.Dx()) / float32(from.Dx())
+	sy := float32(to.Dy()) / float32(from.Dy())
+	m := func(p f32.Point) f32.Point {
+		return f32.Pt(float32(to.Min.X)+(p.X-float32(from.Min.X))*sx, float32(to.Min.Y)+(p.Y-float32(from.Min.Y))*sy)
+	}
+	for i := range a.strokes {
+		pts := a.strokes[i].Pts
+		for j := range pts {
+			pts[j] = m(pts[j])
+		}
+	}
+}
+
+func parseBgFit(s string) (string, error) {
+	for _, m := range bgFitModes {
+		if s == m {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode %q (want fit, fill or stretch)", s)
+}
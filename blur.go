@@ -0,0 +1,162 @@
+package main
+
+import (
+	"image"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The blur pen (X) is a redaction pen, a Pixelate stroke with Blur set,
+// that shows the background blurred under it rather than pixelated:
+// three passes of a box blur, close to a Gaussian, of a radius that grows
+// with the width, wide enough that text under a stroke of the default
+// width cannot be read. Strokes always blur the captured pixels, never
+// other blur strokes, so going over the same area again changes nothing.
+// The overlay blurs only the area of each stroke, rounded out to tiles of
+// blurTile so that a stroke being drawn is not blurred again at each
+// step, and keeps the blurred areas as long as frames draw them; a
+// recapture drops them all. Exports blur the area of each stroke from the
+// same pixels, with the same result.
+
+// blurPasses box blurs make the blur.
+const blurPasses = 3
+
+// blurTile is the grid, px, the blurred areas are rounded out to.
+const blurTile = 128
+
+// blurRadius is the box radius, px, of the blur of s.
+func blurRadius(s *Stroke) int {
+	return max(2, int(s.Width/2))
+}
+
+// blur returns a copy of the area r of src blurred with radius radius.
+// Pixels outside r but within src count, so blurring an area gives what
+// blurring all of src would there.
+func blur(src *image.RGBA, r image.Rectangle, radius int) *image.RGBA {
+	in := r.Inset(-blurPasses * radius).Intersect(src.Bounds())
+	dst := image.NewRGBA(r)
+	if in.Empty() {
+		return dst
+	}
+	w, h := in.Dx(), in.Dy()
+	buf := make([]int32, 4*w*h)
+	for y := 0; y < h; y++ {
+		i := src.PixOffset(in.Min.X, in.Min.Y+y)
+		for x := 0; x < 4*w; x++ {
+			buf[4*w*y+x] = int32(src.Pix[i+x])
+		}
+	}
+	tmp := make([]int32, len(buf))
+	for range blurPasses {
+		boxBlur(tmp, buf, w, h, 4, 4*w, radius)
+		boxBlur(buf, tmp, h, w, 4*w, 4, radius)
+	}
+	out := r.Intersect(in)
+	for y := out.Min.Y; y < out.Max.Y; y++ {
+		i := dst.PixOffset(out.Min.X, y)
+		j := 4*w*(y-in.Min.Y) + 4*(out.Min.X-in.Min.X)
+		for x := 0; x < 4*out.Dx(); x++ {
+			dst.Pix[i+x] = uint8(buf[j+x])
+		}
+	}
+	return dst
+}
+
+// boxBlur averages each of the n samples of the lines of src, step apart
+// within a line and lines stride apart, over radius samples each side,
+// into dst; the samples at the ends repeat past them. Each sample has
+// four channels.
+func boxBlur(dst, src []int32, n, lines, step, stride, radius int) {
+	div := int32(2*radius + 1)
+	for l := 0; l < lines; l++ {
+		base := l * stride
+		at := func(i int) int { return base + min(max(i, 0), n-1)*step }
+		for c := range 4 {
+			var sum int32
+			for i := -radius; i <= radius; i++ {
+				sum += src[at(i)+c]
+			}
+			for i := 0; i < n; i++ {
+				dst[base+i*step+c] = sum / div
+				sum += src[at(i+radius+1)+c] - src[at(i-radius)+c]
+			}
+		}
+	}
+}
+
+// blurKey is a blurred area of the background and the radius of its
+// blur.
+type blurKey struct {
+	r      image.Rectangle
+	radius int
+}
+
+// blurArea is a blurred area, and when a frame last drew it.
+type blurArea struct {
+	op   paint.ImageOp
+	used time.Time
+}
+
+// blurBgOp returns the area of the background around s blurred for s as
+// an image op, and where it goes. The first lookup of a frame drops the
+// areas the last frame did not draw.
+func (a *Annotator) blurBgOp(gtx layout.Context, s *Stroke) (paint.ImageOp, image.Point, bool) {
+	if a.bg == nil {
+		return paint.ImageOp{}, image.Point{}, false
+	}
+	if a.blurOf != a.bg {
+		a.blurOf, a.blurOps = a.bg, make(map[blurKey]*blurArea)
+	}
+	if gtx.Now != a.blurFrame {
+		for k, b := range a.blurOps {
+			if b.used != a.blurFrame {
+				delete(a.blurOps, k)
+			}
+		}
+		a.blurFrame = gtx.Now
+	}
+	r := strokeBounds(s)
+	r = image.Rect(
+		floorDiv(r.Min.X, blurTile)*blurTile, floorDiv(r.Min.Y, blurTile)*blurTile,
+		-floorDiv(-r.Max.X, blurTile)*blurTile, -floorDiv(-r.Max.Y, blurTile)*blurTile,
+	).Intersect(a.bg.Bounds())
+	if r.Empty() {
+		return paint.ImageOp{}, image.Point{}, false
+	}
+	k := blurKey{r: r, radius: blurRadius(s)}
+	b, ok := a.blurOps[k]
+	if !ok {
+		img := blur(a.bg, r, k.radius)
+		// Image ops start at the origin, the offset places them.
+		img.Rect = img.Rect.Sub(r.Min)
+		b = &blurArea{op: paint.NewImageOp(img)}
+		a.blurOps[k] = b
+	}
+	b.used = gtx.Now
+	return b.op, r.Min, true
+}
+
+// floorDiv is x/d rounded down, for d > 0.
+func floorDiv(x, d int) int {
+	if x < 0 {
+		return -((-x + d - 1) / d)
+	}
+	return x / d
+}
+
+// drawBlurStroke shows the blurred background through the stroke.
+func (a *Annotator) drawBlurStroke(gtx layout.Context, s *Stroke) {
+	img, at, ok := a.blurBgOp(gtx, s)
+	if !ok {
+		drawStroke(gtx.Ops, s)
+		return
+	}
+	defer clip.Outline{Path: stampsPath(gtx.Ops, s.Pts, s.Widths, s.Width)}.Op().Push(gtx.Ops).Pop()
+	defer op.Offset(at).Push(gtx.Ops).Pop()
+	img.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// Extra mouse buttons (-button-back and the like, or "buttons" in the
+// config file) each run a key chord, as if it had been pressed, so any
+// action with a key can be had one-handed while drawing: Ctrl+Shift+H
+// for the outline opacity, "." for the next color. The wheel tilting
+// sideways counts as two more buttons, repeating while held. Gio's X11
+// backend passes on the middle button and the tilt but not back and
+// forward, which only arrive on Wayland; ANNOTATOR_DEBUG logs each extra
+// button the first time it comes, to find out what a mouse has.
+//
+// The barrel button of a tablet pen comes as the secondary button, which
+// is also the right mouse button; Gio does not tell a pen from a mouse.
+// Its key runs when it is pressed and again when it is released, so held
+// while the pen touches down it is a mode for that stroke: Ctrl+X draws
+// in the previous color, K with the redaction pen, Ctrl+U hollow.
+
+// mouseButton is an extra pointer input that can be bound to a key.
+type mouseButton struct {
+	name string
+	// btn is the button, or zero for a tilt, given by dir instead.
+	btn pointer.Buttons
+	dir float32
+	// held runs the key again on release, so what it toggles lasts
+	// only while the button is held.
+	held bool
+}
+
+var mouseButtons = []mouseButton{
+	{name: "middle", btn: pointer.ButtonTertiary},
+	{name: "back", btn: pointer.ButtonQuaternary},
+	{name: "forward", btn: pointer.ButtonQuinary},
+	{name: "tilt-left", dir: -1},
+	{name: "tilt-right", dir: 1},
+	{name: "barrel", btn: pointer.ButtonSecondary, held: true},
+}
+
+// buttonFlag is the flag binding the named button.
+func buttonFlag(name string) string {
+	return "button-" + name
+}
+
+// tiltScroll lets horizontal scrolls through to the pointer handler.
+var tiltScroll = pointer.ScrollRange{Min: math.MinInt32, Max: math.MaxInt32}
+
+// parseButtons parses the -button-* chords; empty ones stay unbound.
+func parseButtons(chords map[string]string) (map[string]keyChord, error) {
+	binds := make(map[string]keyChord)
+	for name, s := range chords {
+		if s == "" {
+			continue
+		}
+		c, err := parseKeyChord(s)
+		if err != nil {
+			return nil, fmt.Errorf("-%s: %w", buttonFlag(name), err)
+		}
+		binds[name] = c
+	}
+	return binds, nil
+}
+
+// pressButtons runs the keys of the extra buttons pe pressed, and reports
+// whether that took the press: it ran the key of one, or the primary
+// button was already down, so the press must not start another stroke.
+func (a *Annotator) pressButtons(gtx layout.Context, pe pointer.Event) bool {
+	pressed := pe.Buttons &^ a.heldButtons
+	drawing := a.heldButtons.Contain(pointer.ButtonPrimary)
+	a.heldButtons = pe.Buttons
+	ran := false
+	for _, b := range mouseButtons {
+		if b.btn != 0 && pressed.Contain(b.btn) {
+			// A held button changes how the press goes on, rather
+			// than taking it.
+			ran = a.runButton(gtx, b) && !b.held || ran
+		}
+	}
+	return ran || drawing
+}
+
+// releaseButtons runs once more the keys of the held buttons among
+// released.
+func (a *Annotator) releaseButtons(gtx layout.Context, released pointer.Buttons) {
+	for _, b := range mouseButtons {
+		if b.held && released.Contain(b.btn) {
+			a.runButton(gtx, b)
+		}
+	}
+}
+
+// tiltWheel runs the key of a sideways scroll. Shift turns the plain
+// wheel sideways too; that is not a tilt.
+func (a *Annotator) tiltWheel(gtx layout.Context, pe pointer.Event) {
+	if pe.Scroll.X == 0 || pe.Modifiers.Contain(key.ModShift) {
+		return
+	}
+	for _, b := range mouseButtons {
+		if b.dir != 0 && b.dir*pe.Scroll.X > 0 {
+			a.runButton(gtx, b)
+		}
+	}
+}
+
+// runButton presses the key bound to b, if any.
+func (a *Annotator) runButton(gtx layout.Context, b mouseButton) bool {
+	c, ok := a.buttons[b.name]
+	if a.debug && !a.buttonsSeen[b.name] {
+		if a.buttonsSeen == nil {
+			a.buttonsSeen = make(map[string]bool)
+		}
+		a.buttonsSeen[b.name] = true
+		if ok {
+			log.Printf("pointer: %s button available, runs %v", b.name, c)
+		} else {
+			log.Printf("pointer: %s button available, unbound (-%s)", b.name, buttonFlag(b.name))
+		}
+	}
+	if !ok {
+		return false
+	}
+	a.handleKey(gtx, key.Event{Name: c.name, Modifiers: c.mods, State: key.Press})
+	return true
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"time"
+)
+
+// captureHideDelay gives the compositor time to repaint without the
+// overlay before the screen is read back.
+const captureHideDelay = 150 * time.Millisecond
+
+// liveHideDelay is the shorter wait of periodic captures, which keeps the
+// overlay visible most of the time at the price of an occasional capture
+// that still shows it.
+const liveHideDelay = 40 * time.Millisecond
+
+// captureResult carries a finished screen capture back to the event loop.
+type captureResult struct {
+	img *image.RGBA
+	err error
+	// Content shift since the previous capture, in follow mode.
+	shift   image.Point
+	shifted bool
+	// live is a periodic refresh (-live), which only replaces the
+	// background.
+	live bool
+	// rect is the captured area in root coordinates.
+	rect image.Rectangle
+}
+
+// requestCapture grabs the screen under the overlay in the background:
+// the overlay is made fully transparent, the screen read, and the opacity
+// restored. Without a compositor the opacity hint has no effect and the
+// overlay itself ends up in the capture. The result is picked up by
+// applyCapture on the next frame.
+func (a *Annotator) requestCapture(delay time.Duration) {
+	a.capture(delay, false)
+}
+
+func (a *Annotator) capture(delay time.Duration, live bool) {
+	if a.capturing || a.x11Display == nil || a.x11Window == 0 {
+		return
+	}
+	a.capturing = true
+	dpy, win, opacity := a.x11Display, a.x11Window, a.opacity
+	var prev *image.RGBA
+	if a.recaptureMode == recaptureFollow && !live {
+		prev = a.bg
+	}
+	if a.hidden {
+		opacity = 0
+	}
+	go func() {
+		time.Sleep(delay)
+		if err := x11SetOpacity(dpy, win, 0); err != nil {
+			a.captured <- captureResult{err: fmt.Errorf("hide overlay: %w", err)}
+			a.w.Invalidate()
+			return
+		}
+		if live {
+			time.Sleep(liveHideDelay)
+		} else {
+			time.Sleep(captureHideDelay)
+		}
+		img, err := x11CaptureScreen(dpy, win)
+		_ = x11SetOpacity(dpy, win, opacity)
+		res := captureResult{img: img, err: err, live: live}
+		if origin, oerr := x11WindowOrigin(dpy, win); err == nil && oerr == nil {
+			res.rect = img.Bounds().Add(origin)
+		}
+		if err == nil && prev != nil {
+			res.shift, res.shifted = estimateShift(prev, img)
+		}
+		a.captured <- res
+		a.w.Invalidate()
+	}()
+}
+
+// applyCapture installs a finished capture as the background. On a
+// recapture (as opposed to the one at startup) the strokes are kept,
+// cleared or moved with the content according to recaptureMode.
+func (a *Annotator) applyCapture() {
+	select {
+	case res := <-a.captured:
+		a.capturing = false
+		if res.err != nil {
+			a.notifyErr(fmt.Errorf("screen capture: %w", res.err))
+			return
+		}
+		recapture := a.bg != nil
+		a.bg, a.captureRect = res.img, res.rect
+		// The screen replaces a loaded background.
+		a.bgSrc, a.bgRect = nil, image.Rectangle{}
+		if a.debug {
+			log.Printf("captured background %v", a.bg.Bounds())
+		}
+		if !recapture || res.live {
+			return
+		}
+		switch a.recaptureMode {
+		case recaptureClear:
+			a.strokes = nil
+			a.cur = nil
+			a.dropHistory()
+		case recaptureFollow:
+			if res.shifted {
+				a.shiftStrokes(res.shift)
+				a.notify("Background recaptured, strokes moved by %d,%d", res.shift.X, res.shift.Y)
+				return
+			}
+		}
+		a.notify("Background recaptured")
+	default:
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The chalk brush draws a stroke as scattered grains of uneven opacity
+// instead of a solid band, for a friendlier hand-drawn look. The grains
+// come from a noise sequence seeded by the stroke's start time, so a
+// stroke looks the same in every frame, after a session reload and in
+// PNG exports. It is an alternate render path only: SVG exports keep the
+// clean stroke path.
+
+// chalkGrains calls grain with the square and opacity (0..1) of every
+// grain along pts, at the stamp spacing of stampPath.
+func chalkGrains(pts []f32.Point, widths []float32, width float32, seed uint64, grain func(r image.Rectangle, alpha float32)) {
+	h := seed
+	next := func() float32 {
+		// splitmix64
+		h += 0x9e3779b97f4a7c15
+		z := h
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		z ^= z >> 31
+		return float32(z>>40) / (1 << 24)
+	}
+	stampPath(pts, widths, width, func(rect image.Rectangle) {
+		r := rect.Dx() / 2
+		c := rect.Min.Add(image.Pt(r, r))
+		side := max(1, r/3)
+		for range r + 2 {
+			// Uniform in the square around the stamp, kept in its disc.
+			dx, dy := next()*2-1, next()*2-1
+			alpha := 0.4 + 0.6*next()
+			if dx*dx+dy*dy > 1 {
+				continue
+			}
+			p := c.Add(image.Pt(int(dx*float32(r)), int(dy*float32(r))))
+			grain(image.Rectangle{Min: p, Max: p.Add(image.Pt(side, side))}, alpha)
+		}
+	})
+}
+
+func (s *Stroke) chalkSeed() uint64 { return uint64(s.At.UnixMilli()) }
+
+// eachChalkGrain runs chalkGrains over the stroke and its arrowheads.
+func (s *Stroke) eachChalkGrain(grain func(r image.Rectangle, alpha float32)) {
+	chalkGrains(s.Pts, s.Widths, s.Width, s.chalkSeed(), grain)
+	for i, h := range s.heads() {
+		chalkGrains(h, nil, s.Width, s.chalkSeed()+1+uint64(i), grain)
+	}
+}
+
+func drawChalk(ops *op.Ops, s *Stroke) {
+	s.eachChalkGrain(func(r image.Rectangle, alpha float32) {
+		col := s.Col
+		col.A = uint8(float32(col.A) * alpha)
+		paint.FillShape(ops, col, clip.Rect(r).Op())
+	})
+}
+
+// chalkMask is the raster counterpart of drawChalk: the grains as a
+// coverage mask over area.
+func chalkMask(s *Stroke, area image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(area)
+	s.eachChalkGrain(func(r image.Rectangle, alpha float32) {
+		a := uint8(alpha * 0xff)
+		r = r.Intersect(area)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if mask.AlphaAt(x, y).A < a {
+					mask.SetAlpha(x, y, color.Alpha{A: a})
+				}
+			}
+		}
+	})
+	return mask
+}
+
+// toggleChalk switches the pen and arrow between the solid and the chalk
+// brush for the strokes drawn from now on.
+func (a *Annotator) toggleChalk() {
+	a.chalk = !a.chalk
+	if a.chalk {
+		a.notify("Brush: chalk")
+	} else {
+		a.notify("Brush: solid")
+	}
+}
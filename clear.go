@@ -0,0 +1,33 @@
+package main
+
+// Clearing (C) removes every stroke and nothing else: pins, the
+// background and its dim, the drawing region, the -trace layer and the
+// pen and tool stay as they are. Shift+C clears only the markup, the
+// pen, shape, arrow, measure and text strokes, and also keeps what was
+// done to the background itself (redactions and fills), so a redacted
+// screenshot stays redacted while the explanation on top of it changes
+// from step to step; step markers go with the markup. Locked strokes
+// (lock.go) stay too.
+
+// clearStrokes removes all strokes.
+func (a *Annotator) clearStrokes() {
+	a.strokes = nil
+	a.cur, a.sel = nil, -1
+	a.dropHistory()
+	a.sounds.play(cueClear)
+}
+
+// clearMarkup removes the strokes other than redactions, fills and
+// locked ones.
+func (a *Annotator) clearMarkup() {
+	kept := a.strokes[:0:0]
+	for _, s := range a.strokes {
+		if s.Pixelate || s.Fill != nil || s.Locked {
+			kept = append(kept, s)
+		}
+	}
+	a.strokes = kept
+	a.cur, a.sel = nil, -1
+	a.dropHistory()
+	a.sounds.play(cueClear)
+}
@@ -0,0 +1,46 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework AppKit
+#import <AppKit/AppKit.h>
+
+// copy_png replaces the contents of the general pasteboard with the PNG
+// data; it returns 0 on success.
+static int copy_png(const void *data, int len) {
+	@autoreleasepool {
+		NSData *d = [NSData dataWithBytes:data length:len];
+		NSPasteboard *pb = [NSPasteboard generalPasteboard];
+		[pb clearContents];
+		return [pb setData:d forType:NSPasteboardTypePNG] ? 0 : -1;
+	}
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"unsafe"
+)
+
+// Images go to the macOS general pasteboard as PNG, which Preview, Keynote
+// and browsers paste.
+
+// platformCopyImage puts img on the clipboard.
+func platformCopyImage(img image.Image) error {
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&buf, img); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if C.copy_png(unsafe.Pointer(&data[0]), C.int(len(data))) != 0 {
+		return errors.New("clipboard: NSPasteboard refused the image")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+)
+
+// Images go to the clipboard as image/png through wl-copy (Wayland) or
+// xclip (X11), which keep serving the selection after we hand it over.
+
+// imageCopiers are the commands that put a PNG from stdin on the
+// clipboard, in order of preference.
+var imageCopiers = [][]string{
+	{"wl-copy", "--type", "image/png"},
+	{"xclip", "-selection", "clipboard", "-target", "image/png", "-in"},
+}
+
+// platformCopyImage puts img on the clipboard.
+func platformCopyImage(img image.Image) error {
+	var c []string
+	for _, cc := range imageCopiers {
+		if cc[0] == "wl-copy" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		if _, err := exec.LookPath(cc[0]); err == nil {
+			c = cc
+			break
+		}
+	}
+	if c == nil {
+		return errors.New("copying images needs wl-copy or xclip")
+	}
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&buf, img); err != nil {
+		return err
+	}
+	cmd := exec.Command(c[0], c[1:]...)
+	cmd.Stdin = &buf
+	// Both fork a server for the selection and return once they have
+	// read the data.
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", c[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
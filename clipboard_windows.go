@@ -0,0 +1,122 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Images go to the Windows clipboard twice: as a CF_DIB bitmap, which
+// every program pastes, and in the registered "PNG" format, which keeps
+// the alpha for the programs that look for it (Office, GIMP, browsers).
+
+var (
+	user32   = syscall.NewLazyDLL("user32.dll")
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procOpenClipboard            = user32.NewProc("OpenClipboard")
+	procCloseClipboard           = user32.NewProc("CloseClipboard")
+	procEmptyClipboard           = user32.NewProc("EmptyClipboard")
+	procSetClipboardData         = user32.NewProc("SetClipboardData")
+	procRegisterClipboardFormatW = user32.NewProc("RegisterClipboardFormatW")
+	procGlobalAlloc              = kernel32.NewProc("GlobalAlloc")
+	procGlobalFree               = kernel32.NewProc("GlobalFree")
+	procGlobalLock               = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock             = kernel32.NewProc("GlobalUnlock")
+	procRtlMoveMemory            = kernel32.NewProc("RtlMoveMemory")
+)
+
+const (
+	cfDIB        = 8
+	gmemMoveable = 0x0002
+)
+
+// platformCopyImage puts img on the clipboard.
+func platformCopyImage(img image.Image) error {
+	var pngData bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&pngData, img); err != nil {
+		return err
+	}
+	name, err := syscall.UTF16PtrFromString("PNG")
+	if err != nil {
+		return err
+	}
+	cfPNG, _, err := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(name)))
+	if cfPNG == 0 {
+		return fmt.Errorf("clipboard: RegisterClipboardFormat: %w", err)
+	}
+	// Another program may have it open for a moment.
+	for try := 0; ; try++ {
+		if r, _, err := procOpenClipboard.Call(0); r != 0 {
+			break
+		} else if try == 10 {
+			return fmt.Errorf("clipboard: OpenClipboard: %w", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	defer procCloseClipboard.Call()
+	if r, _, err := procEmptyClipboard.Call(); r == 0 {
+		return fmt.Errorf("clipboard: EmptyClipboard: %w", err)
+	}
+	if err := setClipboard(cfDIB, dib(img)); err != nil {
+		return err
+	}
+	return setClipboard(cfPNG, pngData.Bytes())
+}
+
+// setClipboard hands data, in a global memory block, to the open
+// clipboard as the given format; the clipboard owns the block after.
+func setClipboard(format uintptr, data []byte) error {
+	h, _, err := procGlobalAlloc.Call(gmemMoveable, uintptr(len(data)))
+	if h == 0 {
+		return fmt.Errorf("clipboard: GlobalAlloc: %w", err)
+	}
+	p, _, err := procGlobalLock.Call(h)
+	if p == 0 {
+		procGlobalFree.Call(h)
+		return fmt.Errorf("clipboard: GlobalLock: %w", err)
+	}
+	procRtlMoveMemory.Call(p, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)))
+	procGlobalUnlock.Call(h)
+	if r, _, err := procSetClipboardData.Call(format, h); r == 0 {
+		procGlobalFree.Call(h)
+		return fmt.Errorf("clipboard: SetClipboardData: %w", err)
+	}
+	return nil
+}
+
+// dib encodes img as a packed device-independent bitmap: a
+// BITMAPINFOHEADER and 32-bit BGRA rows, bottom-up.
+func dib(img image.Image) []byte {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	const headerLen = 40
+	out := make([]byte, headerLen+w*h*4)
+	le := binary.LittleEndian
+	le.PutUint32(out[0:], headerLen)
+	le.PutUint32(out[4:], uint32(w))
+	le.PutUint32(out[8:], uint32(h))
+	le.PutUint16(out[12:], 1)  // planes
+	le.PutUint16(out[14:], 32) // bits per pixel
+	// BI_RGB, then the image size; resolutions and colors stay zero.
+	le.PutUint32(out[20:], uint32(w*h*4))
+	rgba := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	for y := 0; y < h; y++ {
+		row := out[headerLen+(h-1-y)*w*4:]
+		src := rgba.Pix[y*rgba.Stride:]
+		for x := 0; x < w; x++ {
+			row[4*x], row[4*x+1], row[4*x+2], row[4*x+3] = src[4*x+2], src[4*x+1], src[4*x], src[4*x+3]
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseHexColor(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want color.NRGBA
+		err  bool
+	}{
+		{in: "ff8800", want: color.NRGBA{R: 0xff, G: 0x88, A: 0xff}},
+		{in: "#ff8800", want: color.NRGBA{R: 0xff, G: 0x88, A: 0xff}},
+		{in: "FF8800", want: color.NRGBA{R: 0xff, G: 0x88, A: 0xff}},
+		// A missing alpha is opaque, an alpha of 00 transparent.
+		{in: "ff880080", want: color.NRGBA{R: 0xff, G: 0x88, A: 0x80}},
+		{in: "ff880000", want: color.NRGBA{R: 0xff, G: 0x88}},
+		{in: "00000000", want: color.NRGBA{}},
+		{in: "", err: true},
+		{in: "#", err: true},
+		{in: "fff", err: true},
+		{in: "ff88000", err: true},
+		{in: "ff8800000", err: true},
+		{in: "gg8800", err: true},
+		{in: "+f8800", err: true},
+		{in: "##ff8800", err: true},
+	} {
+		got, err := parseHexColor(tc.in)
+		if (err != nil) != tc.err {
+			t.Errorf("parseHexColor(%q) error %v, want error %v", tc.in, err, tc.err)
+			continue
+		}
+		if err == nil && got != tc.want {
+			t.Errorf("parseHexColor(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFormatHexColorRoundTrip(t *testing.T) {
+	for _, c := range []color.NRGBA{
+		{R: 0xff, G: 0x88, A: 0xff},
+		{R: 0x12, G: 0x34, B: 0x56, A: 0x78},
+		{},
+	} {
+		got, err := parseHexColor(formatHexColor(c))
+		if err != nil || got != c {
+			t.Errorf("parseHexColor(formatHexColor(%v)) = %v, %v", c, got, err)
+		}
+	}
+}
+
+// TestConfigColorSet checks that a configured transparent pen is kept
+// apart from no configured color at all.
+func TestConfigColorSet(t *testing.T) {
+	for _, tc := range []struct {
+		color  string
+		want   color.NRGBA
+		colSet bool
+	}{
+		{color: "", colSet: false},
+		{color: "#00000000", want: color.NRGBA{}, colSet: true},
+		{color: "#ff0000", want: color.NRGBA{R: 0xff, A: 0xff}, colSet: true},
+	} {
+		var o options
+		if err := (configFile{Color: tc.color}).apply(&o); err != nil {
+			t.Errorf("color %q: %v", tc.color, err)
+			continue
+		}
+		if o.colSet != tc.colSet || o.col != tc.want {
+			t.Errorf("color %q: col %v, colSet %v; want %v, %v", tc.color, o.col, o.colSet, tc.want, tc.colSet)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The command palette (Ctrl+Shift+P) lists the actions by name, narrowed
+// down by typing (letters in order, not necessarily adjacent), so they can
+// be found and run without knowing their keys. Up and Down pick, Enter or
+// a click runs and closes, Escape closes. Every action is a key, so the
+// palette runs it through handleKey just as if it had been pressed, and
+// the list below is all there is to keep in step with the key handlers.
+
+// command is an action of the palette: its name, and the key doing it.
+type command struct {
+	name  string
+	keys  string // as shown
+	chord keyChord
+}
+
+var commands = []command{
+	{"Color: red", "R", keyChord{name: "R"}},
+	{"Color: green", "G", keyChord{name: "G"}},
+	{"Color: blue", "B", keyChord{name: "B"}},
+	{"Color: yellow", "Y", keyChord{name: "Y"}},
+	{"Color: orange", "O", keyChord{name: "O"}},
+	{"Color: pink", "P", keyChord{name: "P"}},
+	{"Color: next in palette", ".", keyChord{name: "."}},
+	{"Color: previous in palette", ",", keyChord{name: ","}},
+	{"Color: enter hex code", "#", keyChord{name: "#"}},
+	{"Color: most recent custom", "Ctrl+1", keyChord{mods: key.ModShortcut, name: "1"}},
+	{"Color: swap with the previous", "Ctrl+X", keyChord{mods: key.ModShortcut, name: "X"}},
+	{"Tool: swap with the previous", "Ctrl+Shift+X", keyChord{mods: key.ModShortcut | key.ModShift, name: "X"}},
+	{"Palette: switch theme", "Ctrl+T", keyChord{mods: key.ModShortcut, name: "T"}},
+	{"Palette: edit", "Ctrl+E", keyChord{mods: key.ModShortcut, name: "E"}},
+	{"Width: thin", "1", keyChord{name: "1"}},
+	{"Width: medium", "2", keyChord{name: "2"}},
+	{"Width: thick", "3", keyChord{name: "3"}},
+	{"Width: thinner", "-", keyChord{name: "-"}},
+	{"Width: thicker", "=", keyChord{name: "="}},
+	{"Width: emphasis", "H", keyChord{name: "H"}},
+	{"Width: dynamic", "W", keyChord{name: "W"}},
+	{"Tool: blur pen", "X", keyChord{name: "X"}},
+	{"Tool: arrow", "Q", keyChord{name: "Q"}},
+	{"Tool: redaction pen", "K", keyChord{name: "K"}},
+	{"Tool: measure", "M", keyChord{name: "M"}},
+	{"Tool: step markers", "S", keyChord{name: "S"}},
+	{"Tool: fill bucket", "D", keyChord{name: "D"}},
+	{"Tool: dot", "Ctrl+.", keyChord{mods: key.ModShortcut, name: "."}},
+	{"Tool: icon stamps, next icon", "Ctrl+I", keyChord{mods: key.ModShortcut, name: "I"}},
+	{"Tool: shapes, next shape", "Ctrl+N", keyChord{mods: key.ModShortcut, name: "N"}},
+	{"Tool: eraser, whole strokes", "Shift+E", keyChord{mods: key.ModShift, name: "E"}},
+	{"Tool: text, click and type", "Shift+T", keyChord{mods: key.ModShift, name: "T"}},
+	{"Tool: laser pointer", "Shift+L", keyChord{mods: key.ModShift, name: "L"}},
+	{"Tool: highlighter", "Shift+H", keyChord{mods: key.ModShift, name: "H"}},
+	{"Tool: connector", "Ctrl+K", keyChord{mods: key.ModShortcut, name: "K"}},
+	{"Tool: lasso", "Ctrl+J", keyChord{mods: key.ModShortcut, name: "J"}},
+	{"Lines: next join style", "Ctrl+Shift+J", keyChord{mods: key.ModShortcut | key.ModShift, name: "J"}},
+	{"Lines: next cap style", "Ctrl+Shift+K", keyChord{mods: key.ModShortcut | key.ModShift, name: "K"}},
+	{"Tool: dimension line", "Ctrl+M", keyChord{mods: key.ModShortcut, name: "M"}},
+	{"Dimension: edit the label", "Ctrl+Shift+M", keyChord{mods: key.ModShortcut | key.ModShift, name: "M"}},
+	{"Tool: word highlighter (-ocr)", "Ctrl+W", keyChord{mods: key.ModShortcut, name: "W"}},
+	{"Connectors: straight or orthogonal", "Ctrl+O", keyChord{mods: key.ModShortcut, name: "O"}},
+	{"Arrowheads: next style", "<", keyChord{name: "<"}},
+	{"Arrow: turn the last stroke into one", ">", keyChord{name: ">"}},
+	{"Brush: chalk", "L", keyChord{name: "L"}},
+	{"Strokes: hollow outlines", "Ctrl+U", keyChord{mods: key.ModShortcut, name: "U"}},
+	{"Symmetry: next axis", "U", keyChord{name: "U"}},
+	{"Shapes: recognize", "N", keyChord{name: "N"}},
+	{"Shapes: fill opacity", "Ctrl+H", keyChord{mods: key.ModShortcut, name: "H"}},
+	{"Shapes: outline opacity", "Ctrl+Shift+H", keyChord{mods: key.ModShortcut | key.ModShift, name: "H"}},
+	{"Strokes: auto-contrast halo", "Ctrl+A", keyChord{mods: key.ModShortcut, name: "A"}},
+	{"Strokes: join to the previous", "J", keyChord{name: "J"}},
+	{"Strokes: merge quick restarts into the previous", "Shift+J", keyChord{mods: key.ModShift, name: "J"}},
+	{"Strokes: undo", "Ctrl+Z", keyChord{mods: key.ModShortcut, name: "Z"}},
+	{"Strokes: redo", "Ctrl+Shift+Z", keyChord{mods: key.ModShortcut | key.ModShift, name: "Z"}},
+	{"Strokes: clear all", "C", keyChord{name: "C"}},
+	{"Strokes: lock or unlock", "Ctrl+Shift+L", keyChord{mods: key.ModShortcut | key.ModShift, name: "L"}},
+	{"Strokes: clear markup, keep redactions and fills", "Shift+C", keyChord{mods: key.ModShift, name: "C"}},
+	{"Selection: next stroke", "Right", keyChord{name: key.NameRightArrow}},
+	{"Selection: previous stroke", "Left", keyChord{name: key.NameLeftArrow}},
+	{"Selection: delete", "Delete", keyChord{name: key.NameDeleteForward}},
+	{"Selection: bring to front", "PgUp", keyChord{name: key.NamePageUp}},
+	{"Selection: send to back", "PgDn", keyChord{name: key.NamePageDown}},
+	{"Selection: apply the pen width", "E", keyChord{name: "E"}},
+	{"Selection: pulse", "Ctrl+G", keyChord{mods: key.ModShortcut, name: "G"}},
+	{"Strokes: flash all", "Ctrl+Shift+G", keyChord{mods: key.ModShortcut | key.ModShift, name: "G"}},
+	{"Selection: duplicate", "Ctrl+D", keyChord{mods: key.ModShortcut, name: "D"}},
+	{"Selection: rotate clockwise", "Ctrl+]", keyChord{mods: key.ModShortcut, name: "]"}},
+	{"Selection: rotate counterclockwise", "Ctrl+[", keyChord{mods: key.ModShortcut, name: "["}},
+	{"Steps: show connectors", "Ctrl+L", keyChord{mods: key.ModShortcut, name: "L"}},
+	{"Background: dim or lighten", "A", keyChord{name: "A"}},
+	{"Background: recapture", "Ctrl+R", keyChord{mods: key.ModShortcut, name: "R"}},
+	{"Background: freeze -live", "Space", keyChord{name: key.NameSpace}},
+	{"Background: fit, fill or stretch", "Ctrl+B", keyChord{mods: key.ModShortcut, name: "B"}},
+	{"Spotlight", "F", keyChord{name: "F"}},
+	{"Reading ruler", "Shift+F", keyChord{mods: key.ModShift, name: "F"}},
+	{"Spotlight: harder edge", "{", keyChord{name: "{"}},
+	{"Spotlight: softer edge", "}", keyChord{name: "}"}},
+	{"Window: click-through", "T", keyChord{name: "T"}},
+	{"Window: more transparent", "[", keyChord{name: "["}},
+	{"Window: more opaque", "]", keyChord{name: "]"}},
+	{"Drawing region", "Z", keyChord{name: "Z"}},
+	{"Coordinates readout", "I", keyChord{name: "I"}},
+	{"Scrubber: review drawing order", "V", keyChord{name: "V"}},
+	{"Panes: switch before/after", "Ctrl+P", keyChord{mods: key.ModShortcut, name: "P"}},
+	{"Trace: flatten into the drawing", "Ctrl+F", keyChord{mods: key.ModShortcut, name: "F"}},
+	{"Replay: start", "Enter", keyChord{name: key.NameReturn}},
+	{"Template: skip the placeholder", "Tab", keyChord{name: key.NameTab}},
+	{"Template: previous placeholder", "Shift+Tab", keyChord{mods: key.ModShift, name: key.NameTab}},
+	{"Clipboard: copy as SVG", "Ctrl+C", keyChord{mods: key.ModShortcut, name: "C"}},
+	{"Clipboard: copy an earlier export", "Ctrl+Shift+V", keyChord{mods: key.ModShortcut | key.ModShift, name: "V"}},
+	{"Clipboard: paste text", "Ctrl+V", keyChord{mods: key.ModShortcut, name: "V"}},
+	{"Presets: next", "Alt+N", keyChord{mods: key.ModAlt, name: "N"}},
+	{"Presets: save the current pen", "Alt+S", keyChord{mods: key.ModAlt, name: "S"}},
+	{"Save", "Ctrl+S", keyChord{mods: key.ModShortcut, name: "S"}},
+}
+
+// maxCommandRows is how many matches the palette shows.
+const maxCommandRows = 10
+
+// openCommands opens the palette with an empty search.
+func (a *Annotator) openCommands() {
+	a.cmdOpen, a.cmdQuery, a.cmdSel = true, "", 0
+}
+
+// matchingCommands returns the commands matching the search, best first.
+func (a *Annotator) matchingCommands() []command {
+	type match struct {
+		c     command
+		score int
+	}
+	var ms []match
+	for _, c := range commands {
+		if s, ok := fuzzyScore(c.name, a.cmdQuery); ok {
+			ms = append(ms, match{c, s})
+		}
+	}
+	// Stable, so equal scores keep the order of the list.
+	slices.SortStableFunc(ms, func(x, y match) int { return y.score - x.score })
+	cs := make([]command, 0, min(len(ms), maxCommandRows))
+	for _, m := range ms[:min(len(ms), maxCommandRows)] {
+		cs = append(cs, m.c)
+	}
+	return cs
+}
+
+// fuzzyScore reports whether the letters of query appear in name in
+// order, ignoring case, and how well: runs of adjacent letters and
+// letters starting words count more.
+func fuzzyScore(name, query string) (int, bool) {
+	n, q := []rune(strings.ToLower(name)), []rune(strings.ToLower(query))
+	score, j, prev := 0, 0, -2
+	for i := 0; i < len(n) && j < len(q); i++ {
+		if n[i] != q[j] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 || !unicode.IsLetter(n[i-1]) {
+			score += 3
+		}
+		prev = i
+		j++
+	}
+	return score, j == len(q)
+}
+
+// commandEdit consumes typed text while the palette is open.
+func (a *Annotator) commandEdit(txt string) {
+	for _, r := range txt {
+		if unicode.IsPrint(r) {
+			a.cmdQuery += string(r)
+		}
+	}
+	a.cmdSel = 0
+}
+
+// commandKey handles the keys of the open palette.
+func (a *Annotator) commandKey(gtx layout.Context, ke key.Event) {
+	cs := a.matchingCommands()
+	switch ke.Name {
+	case key.NameDeleteBackward:
+		if r := []rune(a.cmdQuery); len(r) > 0 {
+			a.cmdQuery, a.cmdSel = string(r[:len(r)-1]), 0
+		}
+	case key.NameUpArrow:
+		a.cmdSel = max(a.cmdSel-1, 0)
+	case key.NameDownArrow:
+		a.cmdSel = min(a.cmdSel+1, max(len(cs)-1, 0))
+	case key.NameReturn, key.NameEnter:
+		if a.cmdSel < len(cs) {
+			a.runCommand(gtx, cs[a.cmdSel])
+		}
+	case key.NameEscape:
+		a.cmdOpen = false
+	}
+}
+
+// runCommand closes the palette and presses the key of c.
+func (a *Annotator) runCommand(gtx layout.Context, c command) {
+	a.cmdOpen = false
+	a.handleKey(gtx, key.Event{Name: c.chord.name, Modifiers: c.chord.mods, State: key.Press})
+}
+
+// drawCommands shows the search and the matches, clickable, centered
+// near the top.
+func (a *Annotator) drawCommands(gtx layout.Context) {
+	cs := a.matchingCommands()
+	for i := range cs {
+		for {
+			ev, ok := gtx.Event(pointer.Filter{Target: &a.cmdTags[i], Kinds: pointer.Press})
+			if !ok {
+				break
+			}
+			if ev.(pointer.Event).Kind == pointer.Press {
+				a.runCommand(gtx, cs[i])
+				gtx.Execute(op.InvalidateCmd{})
+				return
+			}
+		}
+	}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	dim := color.NRGBA{R: 0xa0, G: 0xa0, B: 0xa0, A: 255}
+	pos := image.Pt(gtx.Constraints.Max.X/2-gtx.Dp(180), gtx.Dp(24))
+	sz := a.drawLabel(gtx, pos, "> "+a.cmdQuery+"_", white)
+	y := pos.Y + sz.Y + gtx.Dp(4)
+	for i, c := range cs {
+		fg := dim
+		if i == a.cmdSel {
+			fg = white
+		}
+		row := a.drawLabel(gtx, image.Pt(pos.X, y), c.name+"   "+c.keys, fg)
+		area := clip.Rect(image.Rect(pos.X, y, pos.X+row.X, y+row.Y)).Push(gtx.Ops)
+		event.Op(gtx.Ops, &a.cmdTags[i])
+		pointer.CursorPointer.Add(gtx.Ops)
+		area.Pop()
+		if i == a.cmdSel {
+			r := image.Rect(pos.X, y, pos.X+row.X, y+row.Y)
+			paint.FillShape(gtx.Ops, white, clip.Stroke{Path: clip.UniformRRect(r, gtx.Dp(4)).Path(gtx.Ops), Width: float32(gtx.Dp(1))}.Op())
+		}
+		y += row.Y + gtx.Dp(2)
+	}
+	if len(cs) == 0 {
+		a.drawLabel(gtx, image.Pt(pos.X, y), "No matching commands", dim)
+	}
+}
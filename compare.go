@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Comparison mode keeps two panes, A and B, each a background capture
+// with its strokes, for before/after pictures: annotate A, switch to B
+// (which captures the screen as it is now), annotate B, and export both
+// side by side. The active pane lives in a.bg and a.strokes as usual; the
+// other one waits in a.otherPane, so nothing else has to know about panes.
+
+// compareDivider is the width and color of the bar between the panes.
+const compareDivider = 8
+
+var compareDividerCol = color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+
+type pane struct {
+	bg      *image.RGBA
+	strokes []Stroke
+}
+
+// paneName is the letter of the active pane.
+func (a *Annotator) paneName() string {
+	if a.paneB {
+		return "B"
+	}
+	return "A"
+}
+
+// switchPane swaps the active pane with the other one. The first switch to
+// B starts it with a fresh capture; only the backgrounds are kept per pane,
+// the screen below the overlay is whatever it is.
+func (a *Annotator) switchPane() {
+	cur := pane{bg: a.bg, strokes: a.strokes}
+	a.bg, a.strokes = a.otherPane.bg, a.otherPane.strokes
+	a.otherPane = cur
+	a.paneB = !a.paneB
+	a.cur, a.sel = nil, -1
+	a.dropHistory()
+	if a.bg == nil {
+		if a.bgSrc == nil {
+			a.requestCapture(0)
+		} else {
+			// layoutBackground composes the loaded one again.
+			a.bgRect = image.Rectangle{}
+		}
+	}
+	a.notify("Pane %s: %d strokes", a.paneName(), len(a.strokes))
+}
+
+// exportComparison writes panes A and B side by side as a PNG, each with
+// its own background and strokes, divided by a gray bar.
+func (a *Annotator) exportComparison(path string) error {
+	if strings.ToLower(filepath.Ext(path)) != ".png" {
+		return fmt.Errorf("compare %q: want a .png file", path)
+	}
+	panes := [2]pane{{a.bg, a.strokes}, a.otherPane}
+	if a.paneB {
+		panes[0], panes[1] = panes[1], panes[0]
+	}
+	if panes[1].bg == nil && len(panes[1].strokes) == 0 {
+		return errors.New("compare: pane B is empty (switch with Ctrl+P)")
+	}
+	// Panes are cropped (-export-crop) each to its own strokes.
+	var imgs [2]image.Image
+	for i, p := range panes {
+		strokes := a.exportStrokes(p.strokes)
+		if a.deterministic {
+			strokes = roundStrokes(strokes)
+		}
+		img := newCanvas(p.bg, a.size)
+		rasterStrokes(img, strokes)
+		a.rasterPins(img)
+		imgs[i] = a.exportOpts.finish(img, strokes)
+	}
+	ra, rb := imgs[0].Bounds(), imgs[1].Bounds()
+	h := max(ra.Dy(), rb.Dy())
+	dst := image.NewRGBA(image.Rect(0, 0, ra.Dx()+compareDivider+rb.Dx(), h))
+	draw.Draw(dst, image.Rect(ra.Dx(), 0, ra.Dx()+compareDivider, h), image.NewUniform(compareDividerCol), image.Point{}, draw.Src)
+	draw.Draw(dst, image.Rect(0, 0, ra.Dx(), ra.Dy()), imgs[0], ra.Min, draw.Src)
+	draw.Draw(dst, image.Rect(ra.Dx()+compareDivider, 0, dst.Bounds().Max.X, rb.Dy()), imgs[1], rb.Min, draw.Src)
+	img := a.exportOpts.mark(dst)
+	if err := writePNG(path, img, a.pngMetadata(time.Now())...); err != nil {
+		return err
+	}
+	a.rememberExport(path, img)
+	a.runExportHook(path)
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// configFile is the user's persistent setup, read at startup from
+// config.json next to the state file (os.UserConfigDir, which honors
+// $XDG_CONFIG_HOME on Linux), or from -config. Built-in defaults apply to
+// what it leaves out, and command-line flags override it.
+//
+//	{
+//	  "width": 4,
+//	  "color": "#ff9800",
+//	  "widths": [2, 4, 8],
+//	  "background": "dim",
+//	  "dimAlpha": 90,
+//	  "palettes": [{"name": "mine", "colors": {"red": "#e53935", "blue": "#1e88e5"}}],
+//	  "keys": {"quit": "Ctrl+Q"},
+//	  "buttons": {"back": ",", "forward": "."},
+//	  "presets": [{"name": "marker", "color": "#ffeb3b80", "width": 24, "tool": "pen"}],
+//	  "flags": {"fullscreen": "override", "recapture": "follow"}
+//	}
+type configFile struct {
+	Width      float32           `json:"width,omitempty"`      // pen width, dp
+	Color      string            `json:"color,omitempty"`      // pen color, RRGGBB[AA]; the first palette's red by default
+	Widths     []float32         `json:"widths,omitempty"`     // the widths of the keys 1, 2 and 3, dp
+	Background string            `json:"background,omitempty"` // initial backdrop: off, dim or lighten
+	DimAlpha   *int              `json:"dimAlpha,omitempty"`   // 0..255, for dim and lighten
+	Palettes   []paletteJSON     `json:"palettes,omitempty"`   // replace the built-in themes
+	Keys       map[string]string `json:"keys,omitempty"`       // action -> key chord
+	Buttons    map[string]string `json:"buttons,omitempty"`    // extra mouse button -> key chord
+	Presets    []presetJSON      `json:"presets,omitempty"`    // pen settings for Alt+1..9
+	// Flags gives defaults for command-line flags, by flag name.
+	Flags map[string]any `json:"flags,omitempty"`
+}
+
+type paletteJSON struct {
+	Name   string            `json:"name"`
+	Colors map[string]string `json:"colors"` // slot (red, green, ...) -> RRGGBB[AA]
+}
+
+// keyFlags maps the configurable key actions to the flags setting them.
+var keyFlags = map[string]string{
+	"quit": "quit-key",
+}
+
+// configPathFlag is -config, which replaces the default path.
+var configPathFlag string
+
+func configPath() (string, error) {
+	if configPathFlag != "" {
+		return configPathFlag, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "screenpengo", "config.json"), nil
+}
+
+// loadConfig reads the config file and returns it with its path; a
+// missing file gives the zero config and an empty path, unless it was
+// asked for with -config.
+func loadConfig() (configFile, string, error) {
+	var c configFile
+	p, err := configPath()
+	if err != nil {
+		return c, "", err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) && configPathFlag == "" {
+		return c, "", nil
+	}
+	if err != nil {
+		return c, "", err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, "", fmt.Errorf("%s: %w", p, err)
+	}
+	return c, p, nil
+}
+
+// saveConfig sets the top-level key of the config file to v, keeping the
+// rest of it as it is, and returns its path.
+func saveConfig(name string, v any) (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	cfg := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return "", err
+	default:
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg[name], err = json.Marshal(v); err != nil {
+		return "", err
+	}
+	if data, err = json.MarshalIndent(cfg, "", "  "); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// applyFlags sets the flags the config gives values for, except those
+// already set on the command line.
+func (c configFile) applyFlags(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	vals := make(map[string]string)
+	for name, v := range c.Flags {
+		vals[name] = fmt.Sprint(v)
+	}
+	for action, chord := range c.Keys {
+		name, ok := keyFlags[action]
+		if !ok {
+			return fmt.Errorf("keys: unknown action %q (want one of %v)", action, slices.Sorted(maps.Keys(keyFlags)))
+		}
+		vals[name] = chord
+	}
+	for button, chord := range c.Buttons {
+		if !slices.ContainsFunc(mouseButtons, func(b mouseButton) bool { return b.name == button }) {
+			return fmt.Errorf("buttons: unknown button %q (want middle, back, forward, tilt-left, tilt-right or barrel)", button)
+		}
+		vals[buttonFlag(button)] = chord
+	}
+	for name, v := range vals {
+		if set[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("flags: unknown flag %q", name)
+		}
+		if err := fs.Set(name, v); err != nil {
+			return fmt.Errorf("flags: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// apply fills in the window settings the config covers.
+func (c configFile) apply(o *options) error {
+	if c.Width != 0 {
+		if c.Width < 1 || c.Width > 100 {
+			return fmt.Errorf("width %v: want 1..100", c.Width)
+		}
+		o.widthDp = c.Width
+	}
+	if c.Color != "" {
+		col, err := parseHexColor(c.Color)
+		if err != nil {
+			return fmt.Errorf("color: %w", err)
+		}
+		o.col, o.colSet = col, true
+	}
+	if c.Widths != nil {
+		if len(c.Widths) != len(o.widthKeys) {
+			return fmt.Errorf("widths %v: want %d, for the keys 1 to %d", c.Widths, len(o.widthKeys), len(o.widthKeys))
+		}
+		for i, w := range c.Widths {
+			if w < 1 || w > 100 {
+				return fmt.Errorf("widths: %v: want 1..100", w)
+			}
+			o.widthKeys[i] = w
+		}
+	}
+	if c.DimAlpha != nil {
+		if *c.DimAlpha < 0 || *c.DimAlpha > 255 {
+			return fmt.Errorf("dimAlpha %d: want 0..255", *c.DimAlpha)
+		}
+		o.dimAlpha = uint8(*c.DimAlpha)
+	}
+	switch c.Background {
+	case "":
+	case "off":
+		o.dim = false
+	case "dim":
+		o.dim, o.dimCol = true, dimDark
+	case "lighten":
+		o.dim, o.dimCol = true, dimLight
+	default:
+		return fmt.Errorf("background %q: want off, dim or lighten", c.Background)
+	}
+	for i, pj := range c.Presets {
+		p, err := pj.preset(i)
+		if err != nil {
+			return err
+		}
+		o.presets = append(o.presets, p)
+	}
+	if len(c.Palettes) > 0 {
+		o.palettes = nil
+		for i, pj := range c.Palettes {
+			p, err := pj.palette(i)
+			if err != nil {
+				return err
+			}
+			o.palettes = append(o.palettes, p)
+		}
+	}
+	return nil
+}
+
+// palette converts the i-th configured palette. Slots it leaves out keep
+// the colors of the first built-in palette.
+func (pj paletteJSON) palette(i int) (palette, error) {
+	p := palette{name: pj.Name, colors: maps.Clone(defaultPalettes[0].colors)}
+	if p.name == "" {
+		p.name = fmt.Sprintf("palette %d", i+1)
+	}
+	for slot, s := range pj.Colors {
+		if _, ok := p.colors[slot]; !ok {
+			return palette{}, fmt.Errorf("palette %q: unknown color %q", p.name, slot)
+		}
+		c, err := parseHexColor(s)
+		if err != nil {
+			return palette{}, fmt.Errorf("palette %q: %w", p.name, err)
+		}
+		p.colors[slot] = c
+	}
+	return p, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Destructive keys (quitting and C) ask first while there are strokes to
+// lose (-confirm, on by default): a prompt covers the overlay and takes
+// all input until Enter confirms or Escape cancels. Control commands are
+// scripted and never ask.
+
+// confirmShade dims the overlay behind a prompt.
+var confirmShade = color.NRGBA{A: 0x80}
+
+// pendingAction is an action waiting for confirmation.
+type pendingAction struct {
+	prompt string
+	run    func()
+}
+
+// confirmThen runs fn, or asks first if there is something to lose.
+func (a *Annotator) confirmThen(prompt string, fn func()) {
+	if !a.confirm || len(a.strokes) == 0 {
+		fn()
+		return
+	}
+	a.pending = &pendingAction{prompt: prompt, run: fn}
+}
+
+// pendingKey handles a key press while a prompt is up.
+func (a *Annotator) pendingKey(ke key.Event) {
+	switch ke.Name {
+	case key.NameReturn, key.NameEnter:
+		p := a.pending
+		a.pending = nil
+		p.run()
+	case key.NameEscape:
+		a.pending = nil
+	}
+}
+
+func (a *Annotator) drawPending(gtx layout.Context) {
+	if a.pending == nil {
+		return
+	}
+	paint.FillShape(gtx.Ops, confirmShade, clip.Rect{Max: gtx.Constraints.Max}.Op())
+	// Lay the prompt out once to learn its size, then center it.
+	macro := op.Record(gtx.Ops)
+	sz := a.drawLabel(gtx, image.Point{}, a.pending.prompt+"  (Enter: yes, Esc: no)", color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	call := macro.Stop()
+	defer op.Offset(gtx.Constraints.Max.Sub(sz).Div(2)).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+}
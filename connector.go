@@ -0,0 +1,150 @@
+package main
+
+import (
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// The connector tool (Ctrl+K) draws flowchart connectors: from the press
+// to the release, either straight or (Ctrl+O) routed orthogonally as an
+// L or a Z of right angles, ending in an arrowhead of the current style
+// unless Shift is held at the press. An end dropped near a stroke snaps
+// to the middle of the nearest side of its box, and an orthogonal route
+// leaves and enters such sides at right angles.
+
+var toolConnector = registerTool("connector", connectorTool{})
+
+// connectorSnapDp is how close to a stroke's box an end snaps to it.
+const connectorSnapDp = 16
+
+type connectorTool struct{}
+
+func (connectorTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.connFrom = pe.Position
+	a.cur = a.newStroke(gtx, pe.Position)
+	a.cur.Widths = nil
+	if !pe.Modifiers.Contain(key.ModShift) {
+		a.cur.Arrow, a.cur.Head, a.cur.BothEnds = true, a.arrowStyle, a.arrowBoth
+	}
+}
+
+func (connectorTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur == nil {
+		return
+	}
+	snap := dpToPx(gtx, connectorSnapDp)
+	from, fromSide := a.snapConnector(a.connFrom, snap)
+	to, toSide := a.snapConnector(pe.Position, snap)
+	corners := []f32.Point{from, to}
+	if a.connectOrtho {
+		corners = orthogonalRoute(from, to, fromSide, toSide)
+	}
+	a.cur.Pts = polylinePoints(corners, a.cur.Width/2)
+}
+
+func (connectorTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur != nil && len(a.cur.Pts) > 1 {
+		a.autoHalo(a.cur)
+		a.strokes = append(a.strokes, *a.cur)
+	}
+	a.cur = nil
+}
+
+func (connectorTool) Render(a *Annotator, gtx layout.Context) {
+	if a.cur != nil {
+		a.paintStroke(gtx, a.cur)
+	}
+}
+
+// boxSide is the side of a box a connector end snapped to.
+type boxSide int
+
+const (
+	sideNone boxSide = iota
+	sideLeft
+	sideRight
+	sideTop
+	sideBottom
+)
+
+// horizontal reports whether a route leaves or enters this side along
+// the x axis.
+func (s boxSide) horizontal() bool { return s == sideLeft || s == sideRight }
+
+// snapConnector moves p to the middle of the nearest side of the box of a
+// stroke it is within d of, the closest one if there are several.
+func (a *Annotator) snapConnector(p f32.Point, d float32) (f32.Point, boxSide) {
+	best, side, bestDist := p, sideNone, d
+	for i := range a.strokes {
+		r := strokeBounds(&a.strokes[i])
+		if !p.Round().In(r.Inset(-int(d))) {
+			continue
+		}
+		minP, maxP := layout.FPt(r.Min), layout.FPt(r.Max)
+		mid := minP.Add(maxP).Mul(0.5)
+		for _, c := range []struct {
+			side boxSide
+			at   f32.Point
+			dist float32
+		}{
+			{sideLeft, f32.Pt(minP.X, mid.Y), abs32(p.X - minP.X)},
+			{sideRight, f32.Pt(maxP.X, mid.Y), abs32(p.X - maxP.X)},
+			{sideTop, f32.Pt(mid.X, minP.Y), abs32(p.Y - minP.Y)},
+			{sideBottom, f32.Pt(mid.X, maxP.Y), abs32(p.Y - maxP.Y)},
+		} {
+			if c.dist <= bestDist {
+				best, side, bestDist = c.at, c.side, c.dist
+			}
+		}
+	}
+	return best, side
+}
+
+// orthogonalRoute returns the corners of a right-angled path from from to
+// to, leaving and entering along the axes their sides call for; a free
+// end goes along the axis the ends are further apart on.
+func orthogonalRoute(from, to f32.Point, fromSide, toSide boxSide) []f32.Point {
+	d := to.Sub(from)
+	wide := abs32(d.X) >= abs32(d.Y)
+	fromH, toH := wide, wide
+	if fromSide != sideNone {
+		fromH = fromSide.horizontal()
+	}
+	if toSide != sideNone {
+		toH = toSide.horizontal()
+	}
+	switch {
+	case fromH && toH:
+		// A Z through a vertical segment halfway across.
+		mx := (from.X + to.X) / 2
+		return []f32.Point{from, f32.Pt(mx, from.Y), f32.Pt(mx, to.Y), to}
+	case !fromH && !toH:
+		my := (from.Y + to.Y) / 2
+		return []f32.Point{from, f32.Pt(from.X, my), f32.Pt(to.X, my), to}
+	case fromH:
+		// An L turning once.
+		return []f32.Point{from, f32.Pt(to.X, from.Y), to}
+	default:
+		return []f32.Point{from, f32.Pt(from.X, to.Y), to}
+	}
+}
+
+// toggleConnectorRouting switches connectors between straight and
+// orthogonal.
+func (a *Annotator) toggleConnectorRouting() {
+	a.connectOrtho = !a.connectOrtho
+	if a.connectOrtho {
+		a.notify("Connectors: orthogonal")
+	} else {
+		a.notify("Connectors: straight")
+	}
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
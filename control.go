@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// controlTimeout bounds how long a control client waits for an overlay's
+// event loop to pick up and apply a command.
+const controlTimeout = 2 * time.Second
+
+// controlCommand is one line received on the control socket, split into
+// words. The overlay's event loop applies it and sends the outcome back
+// on reply.
+type controlCommand struct {
+	args  []string
+	reply chan error
+}
+
+// controlUsage lists the commands understood on the control socket, with
+// the tools as registered.
+func controlUsage() string {
+	return "clear [markup] | color NAME|RRGGBB[AA] | width DP | tool " + strings.Join(toolNames, "|") +
+		" | export FILE.png|.svg|.json | place FILE.json | compare FILE.png | layers DIR | pin TEXT | unpin | hide | show | recapture"
+}
+
+// serveControl listens on the Unix socket at path and forwards each line
+// it receives to every overlay in targets, answering "ok" or "error: ...".
+// A stale socket left by an earlier run is replaced.
+func serveControl(path string, targets []*Annotator) error {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				log.Printf("control: %v", err)
+				return
+			}
+			go handleControlConn(conn, targets)
+		}
+	}()
+	return nil
+}
+
+func handleControlConn(conn net.Conn, targets []*Annotator) {
+	defer conn.Close()
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		args := strings.Fields(sc.Text())
+		if len(args) == 0 {
+			continue
+		}
+		var errs []error
+		for i, a := range targets {
+			args := args
+			if (args[0] == "export" || args[0] == "compare" || args[0] == "layers") && len(args) == 2 && len(targets) > 1 {
+				args = []string{args[0], monitorPath(args[1], i)}
+			}
+			errs = append(errs, a.sendControl(args))
+		}
+		if err := errors.Join(errs...); err != nil {
+			fmt.Fprintf(conn, "error: %v\n", strings.ReplaceAll(err.Error(), "\n", "; "))
+		} else {
+			fmt.Fprintln(conn, "ok")
+		}
+	}
+}
+
+// monitorPath is the file of the i-th of several overlays for path, one
+// per monitor: shot.png becomes shot-1.png, ...
+func monitorPath(path string, i int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), i+1, ext)
+}
+
+// sendControl hands args to the event loop and waits for the outcome.
+func (a *Annotator) sendControl(args []string) error {
+	c := controlCommand{args: args, reply: make(chan error, 1)}
+	select {
+	case a.control <- c:
+	default:
+		return errors.New("overlay busy")
+	}
+	a.w.Invalidate()
+	select {
+	case err := <-c.reply:
+		return err
+	case <-time.After(controlTimeout):
+		return errors.New("overlay not responding")
+	}
+}
+
+// applyControl runs the control commands queued since the last frame.
+func (a *Annotator) applyControl() {
+	for {
+		select {
+		case c := <-a.control:
+			err := a.runControl(c.args)
+			if a.debug {
+				log.Printf("control %q: %v", c.args, err)
+			}
+			c.reply <- err
+		default:
+			return
+		}
+	}
+}
+
+func (a *Annotator) runControl(args []string) error {
+	arg := func() (string, error) {
+		if len(args) != 2 {
+			return "", fmt.Errorf("%s: want one argument", args[0])
+		}
+		return args[1], nil
+	}
+	switch args[0] {
+	case "clear":
+		// "clear markup" keeps redactions and fills, like Shift+C.
+		if len(args) > 1 && args[1] == "markup" {
+			a.clearMarkup()
+		} else {
+			a.clearStrokes()
+		}
+	case "color":
+		s, err := arg()
+		if err != nil {
+			return err
+		}
+		// Named colors come from the current palette.
+		if c, ok := a.palette().colors[strings.ToLower(s)]; ok {
+			a.col = c
+			break
+		}
+		c, err := parseHexColor(s)
+		if err != nil {
+			return err
+		}
+		a.col = c
+	case "width":
+		s, err := arg()
+		if err != nil {
+			return err
+		}
+		w, err := strconv.ParseFloat(s, 32)
+		if err != nil || w < 1 || w > 100 {
+			return fmt.Errorf("width %q: want 1..100", s)
+		}
+		a.widthDp = float32(w)
+	case "tool":
+		s, err := arg()
+		if err != nil {
+			return err
+		}
+		t, err := parseTool(s)
+		if err != nil {
+			return err
+		}
+		a.setTool(t)
+	case "export":
+		path, err := arg()
+		if err != nil {
+			return err
+		}
+		if err := a.export(path); err != nil {
+			return err
+		}
+		a.sounds.play(cueExport)
+		a.endOneshot()
+	case "place":
+		path, err := arg()
+		if err != nil {
+			return err
+		}
+		return a.placeSession(path)
+	case "compare":
+		path, err := arg()
+		if err != nil {
+			return err
+		}
+		return a.exportComparison(path)
+	case "layers":
+		dir, err := arg()
+		if err != nil {
+			return err
+		}
+		if err := a.exportLayers(dir); err != nil {
+			return err
+		}
+		a.sounds.play(cueExport)
+	case "pin":
+		if len(args) < 2 {
+			return fmt.Errorf("pin: want the note text")
+		}
+		a.pins = append(a.pins, strings.Join(args[1:], " "))
+	case "unpin":
+		a.pins = nil
+	case "hide", "show":
+		return a.setHidden(args[0] == "hide")
+	case "recapture":
+		a.requestCapture(0)
+	default:
+		return fmt.Errorf("unknown command %q (want %s)", args[0], controlUsage())
+	}
+	return nil
+}
+
+// export writes the annotations to path, in the format given by its
+// extension: a PNG of the strokes over the captured background, an SVG of
+// the strokes alone, or the JSON session format. In image space all three
+// are at the resolution of the image.
+func (a *Annotator) export(path string) error {
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		strokes := a.exportStrokes(a.strokes)
+		var dst *image.RGBA
+		if a.inImageSpace() {
+			strokes, dst = a.strokesToImage(strokes), a.imageCanvas()
+		} else {
+			dst = newCanvas(a.bg, a.size)
+		}
+		if a.deterministic {
+			strokes = roundStrokes(strokes)
+		}
+		rasterStrokes(dst, strokes)
+		a.rasterPins(dst)
+		img := a.exportOpts.mark(a.exportOpts.finish(dst, strokes))
+		if err := writePNG(path, img, a.pngMetadata(time.Now())...); err != nil {
+			return err
+		}
+		a.rememberExport(path, img)
+		a.runExportHook(path)
+		return nil
+	case ".svg":
+		strokes, size := a.exportStrokes(a.strokes), a.size
+		if a.inImageSpace() {
+			strokes, size = a.strokesToImage(strokes), a.bgRes
+		}
+		data = []byte(strokesSVG(strokes, size))
+	case ".json":
+		var err error
+		if data, err = json.MarshalIndent(a.session(), "", "  "); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("export %q: want a .png, .svg or .json file", path)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// setHidden makes the overlay invisible and lets input through to the
+// windows below, or restores the opacity and click-through state the user
+// had chosen.
+func (a *Annotator) setHidden(hide bool) error {
+	if a.x11Display == nil || a.x11Window == 0 {
+		return errors.New("hide/show needs an X11 window")
+	}
+	opacity, through := a.opacity, a.clickThrough
+	if hide {
+		opacity, through = 0, true
+	}
+	if err := x11SetOpacity(a.x11Display, a.x11Window, opacity); err != nil {
+		return err
+	}
+	if err := x11SetClickThrough(a.x11Display, a.x11Window, through); err != nil {
+		return err
+	}
+	a.hidden = hide
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+
+	"gioui.org/layout"
+)
+
+// toggleCoords shows or hides the pointer coordinate readout. The
+// window's screen origin is looked up when it is shown, so the readout
+// also gives absolute screen coordinates on multi-monitor setups.
+func (a *Annotator) toggleCoords() {
+	a.showCoords = !a.showCoords
+	if !a.showCoords || a.x11Display == nil {
+		return
+	}
+	o, err := x11WindowOrigin(a.x11Display, a.x11Window)
+	if err != nil {
+		if a.debug {
+			log.Printf("x11 window origin: %v", err)
+		}
+		return
+	}
+	a.winOrigin = o
+}
+
+// drawCoords labels the pointer with its window and screen position, and
+// in image space with its position on the image.
+func (a *Annotator) drawCoords(gtx layout.Context) {
+	if !a.showCoords || !a.ptrIn {
+		return
+	}
+	x, y := int(a.ptr.X), int(a.ptr.Y)
+	txt := fmt.Sprintf("%d, %d", x, y)
+	if a.winOrigin != (image.Point{}) {
+		txt += fmt.Sprintf("  (screen %d, %d)", a.winOrigin.X+x, a.winOrigin.Y+y)
+	}
+	if a.inImageSpace() {
+		p := a.toImage(a.ptr)
+		txt += fmt.Sprintf("  (image %d, %d)", int(p.X), int(p.Y))
+	}
+	off := gtx.Dp(18)
+	a.drawLabel(gtx, image.Pt(x+off, y+off), txt, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"log"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Hiding the cursor (-hide-cursor): some compositors draw the system
+// cursor over the overlay's own, which then shows twice. With the flag the
+// system cursor is hidden while the overlay has the focus and takes input,
+// and a ring of the pen's color and width marks the pointer instead. It
+// comes back when the focus goes, in click-through mode, and on exit,
+// when the X server restores it along with the closed connection.
+//
+// Hover preview (-hover-preview) is for tablets, whose pens report where
+// they are before they touch: the ring then follows the hovering pointer
+// whether or not the cursor is hidden, with a faint dab of the pen where
+// the stroke will begin (at the end of the last one when joining), so it
+// can be placed precisely. Hovering only ever moves the pointer; ink
+// starts with the press.
+
+// updateCursor hides or shows the system cursor for the current state.
+func (a *Annotator) updateCursor() {
+	want := a.hideCursor && a.focused && !a.hidden && !a.clickThrough
+	if want == a.cursorHidden || a.x11Display == nil || a.x11Window == 0 {
+		return
+	}
+	var err error
+	if want {
+		err = x11HideCursor(a.x11Display, a.x11Window)
+	} else {
+		err = x11ShowCursor(a.x11Display, a.x11Window)
+	}
+	if err != nil {
+		log.Printf("-hide-cursor: %v; showing the system cursor", err)
+		a.hideCursor = false
+		return
+	}
+	a.cursorHidden = want
+}
+
+// drawPenCursor draws the ring that stands in for the hidden cursor, and
+// the hover preview.
+func (a *Annotator) drawPenCursor(gtx layout.Context) {
+	if !a.cursorHidden && !a.hoverPreview || !a.ptrIn || a.cur != nil {
+		return
+	}
+	if a.hoverPreview {
+		a.drawHoverDab(gtx)
+	}
+	r := max(dpToPx(gtx, a.widthDp)/2, float32(gtx.Dp(3)))
+	box := image.Rectangle{Min: a.ptr.Sub(f32.Pt(r, r)).Round(), Max: a.ptr.Add(f32.Pt(r, r)).Round()}
+	// Dark under the pen color, so the ring shows on any content.
+	for _, o := range []struct {
+		col   color.NRGBA
+		width int
+	}{
+		{color.NRGBA{A: 0xa0}, gtx.Dp(3)},
+		{a.col, gtx.Dp(1)},
+	} {
+		path := clip.Ellipse(box).Path(gtx.Ops)
+		paint.FillShape(gtx.Ops, o.col, clip.Stroke{Path: path, Width: float32(o.width)}.Op())
+	}
+}
+
+// hoverDabAlpha is how faint the hover preview's dab is, of the pen's
+// own opacity.
+const hoverDabAlpha = 0.35
+
+// drawHoverDab previews the start of a stroke of the tools that draw one.
+func (a *Annotator) drawHoverDab(gtx layout.Context) {
+	if a.tool == toolStep || a.tool == toolFill || a.tool == toolIcon || a.tool == toolEraser || a.tool == toolLaser {
+		return
+	}
+	s := a.newStroke(gtx, a.ptr)
+	at := a.ptr
+	if a.joinStrokes {
+		at = a.joinStart(at, max(float32(gtx.Dp(12)), s.Width))
+	}
+	r := max(s.Width/2, 1)
+	col := s.Col
+	col.A = uint8(float32(col.A) * hoverDabAlpha)
+	box := image.Rectangle{Min: at.Sub(f32.Pt(r, r)).Round(), Max: at.Add(f32.Pt(r, r)).Round()}
+	paint.FillShape(gtx.Ops, col, clip.Ellipse(box).Op(gtx.Ops))
+}
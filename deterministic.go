@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+
+	"gioui.org/f32"
+)
+
+// Deterministic exports (-deterministic) make the same session give a
+// byte-identical PNG every time, for pixel-diff tests in CI. PNGs are
+// always rasterized on the CPU (raster.go), never read back from the
+// GPU, so what varies between runs is only the input: hand-drawn points
+// at sub-pixel positions that differ in the last bits after a round trip
+// through normalized sessions or image space, and the creation time in
+// the PNG metadata. With the flag, exports (Ctrl+S, the export and
+// compare commands, -oneshot and -script) round every point and width to
+// whole pixels just before rasterizing and leave the time out.
+
+// roundStrokes returns copies of strokes with their points and widths
+// rounded to whole pixels.
+func roundStrokes(strokes []Stroke) []Stroke {
+	out := make([]Stroke, len(strokes))
+	for i, s := range strokes {
+		s = cloneStroke(s)
+		for j, p := range s.Pts {
+			s.Pts[j] = roundPoint(p)
+		}
+		s.Width = roundWidth(s.Width)
+		for j, w := range s.Widths {
+			s.Widths[j] = roundWidth(w)
+		}
+		out[i] = s
+	}
+	return out
+}
+
+func roundPoint(p f32.Point) f32.Point {
+	return f32.Pt(float32(math.Round(float64(p.X))), float32(math.Round(float64(p.Y))))
+}
+
+func roundWidth(w float32) float32 {
+	return max(float32(math.Round(float64(w))), 1)
+}
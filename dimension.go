@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// The dimension tool (Ctrl+M) documents sizes the way spec drawings do:
+// a line from the press to the release (Shift keeps it horizontal or
+// vertical) with perpendicular ticks at both ends and a label centered
+// on it, the length in px unless given one of its own. Ctrl+Shift+M
+// edits the label of the selected (or last) dimension line: type it,
+// Enter commits, an empty label goes back to the length, and Escape
+// cancels. Stroke.Dimension marks these strokes and Stroke.Label holds
+// the custom label.
+
+var toolDimension = registerTool("dimension", dimensionTool{})
+
+// dimensionTick is how far the end ticks reach to each side, in stroke
+// widths, beyond a minimum of dimensionTickPx.
+const (
+	dimensionTick   = 2
+	dimensionTickPx = 6
+)
+
+type dimensionTool struct{}
+
+func (dimensionTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.cur = a.newStroke(gtx, pe.Position)
+	a.cur.Widths, a.cur.Chalk = nil, false
+	a.cur.Dimension = true
+}
+
+func (dimensionTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur == nil {
+		return
+	}
+	from, to := a.cur.Pts[0], pe.Position
+	if pe.Modifiers.Contain(key.ModShift) {
+		if d := to.Sub(from); abs(d.X) >= abs(d.Y) {
+			to.Y = from.Y
+		} else {
+			to.X = from.X
+		}
+	}
+	a.cur.Pts = append(a.cur.Pts[:1], to)
+}
+
+func (dimensionTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur != nil && len(a.cur.Pts) == 2 && dist(a.cur.Pts[0], a.cur.Pts[1]) >= 1 {
+		a.autoHalo(a.cur)
+		a.strokes = append(a.strokes, *a.cur)
+	}
+	a.cur = nil
+}
+
+func (dimensionTool) Render(a *Annotator, gtx layout.Context) {
+	if a.cur != nil {
+		a.paintStroke(gtx, a.cur)
+	}
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// dimensionText is the label of a dimension line.
+func dimensionText(s *Stroke) string {
+	if s.Label != "" {
+		return s.Label
+	}
+	return fmt.Sprintf("%.0f px", dist(s.Pts[0], s.Pts[len(s.Pts)-1]))
+}
+
+// dimensionParts are the plain strokes a dimension line is drawn with:
+// the line and its two end ticks.
+func dimensionParts(s *Stroke) []Stroke {
+	if len(s.Pts) < 2 {
+		return nil
+	}
+	from, to := s.Pts[0], s.Pts[len(s.Pts)-1]
+	d := to.Sub(from)
+	l := dist(from, to)
+	if l == 0 {
+		return nil
+	}
+	reach := max(dimensionTick*s.Width, dimensionTickPx)
+	n := f32.Pt(-d.Y/l*reach, d.X/l*reach)
+	part := func(a, b f32.Point) Stroke {
+		p := *s
+		p.Dimension, p.Arrow, p.FillAlpha = false, false, 0
+		p.Pts = polylinePoints([]f32.Point{a, b}, s.Width/2)
+		return p
+	}
+	return []Stroke{
+		part(from, to),
+		part(from.Sub(n), from.Add(n)),
+		part(to.Sub(n), to.Add(n)),
+	}
+}
+
+// dimensionCenter is the middle of a dimension line, where its label
+// goes.
+func dimensionCenter(s *Stroke) f32.Point {
+	return s.Pts[0].Add(s.Pts[len(s.Pts)-1]).Mul(0.5)
+}
+
+func (a *Annotator) drawDimension(gtx layout.Context, s *Stroke) {
+	parts := dimensionParts(s)
+	for i := range parts {
+		drawStroke(gtx.Ops, &parts[i])
+	}
+	if parts == nil {
+		return
+	}
+	// Laid out first for its size, so it can be centered.
+	macro := op.Record(gtx.Ops)
+	sz := a.drawLabel(gtx, image.Point{}, dimensionText(s), color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	call := macro.Stop()
+	c := dimensionCenter(s)
+	defer op.Offset(image.Pt(int(c.X)-sz.X/2, int(c.Y)-sz.Y/2)).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+}
+
+// rasterDimension is the raster counterpart of drawDimension.
+func rasterDimension(dst *image.RGBA, s *Stroke) {
+	parts := dimensionParts(s)
+	for i := range parts {
+		rasterStroke(dst, &parts[i])
+	}
+	if parts == nil {
+		return
+	}
+	txt := dimensionText(s)
+	sz := rasterLabelSize(txt)
+	c := dimensionCenter(s)
+	rasterLabel(dst, image.Pt(int(c.X)-sz.X/2, int(c.Y)-sz.Y/2), txt)
+}
+
+// writeSVGDimension writes the lines of a dimension and its label.
+func writeSVGDimension(b *strings.Builder, s *Stroke, style string) {
+	parts := dimensionParts(s)
+	for _, p := range parts {
+		writeSVGPolyline(b, p.Pts, style)
+	}
+	if parts == nil {
+		return
+	}
+	c := dimensionCenter(s)
+	fmt.Fprintf(b, `  <text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="central" font-family="Go, sans-serif" font-size="16" fill="#%02x%02x%02x">%s</text>`+"\n",
+		c.X, c.Y, s.Col.R, s.Col.G, s.Col.B, html.EscapeString(dimensionText(s)))
+}
+
+// startLabelEntry opens the label prompt for the edit target, which has
+// to be a dimension line.
+func (a *Annotator) startLabelEntry() {
+	i := a.sel
+	if i < 0 {
+		i = len(a.strokes) - 1
+	}
+	if i < 0 || !a.strokes[i].Dimension {
+		a.notify("No dimension line to label (Ctrl+M draws them)")
+		return
+	}
+	if !a.checkUnlocked(&a.strokes[i]) {
+		return
+	}
+	a.labelEntry, a.labelIdx, a.labelAt = true, i, a.strokes[i].At
+	a.labelBuf = a.strokes[i].Label
+}
+
+// labelEdit consumes typed text while the prompt is open.
+func (a *Annotator) labelEdit(txt string) {
+	a.labelBuf += strings.Map(func(r rune) rune {
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, txt)
+}
+
+// labelKey handles the editing keys of the prompt.
+func (a *Annotator) labelKey(ke key.Event) {
+	switch ke.Name {
+	case key.NameDeleteBackward:
+		if r := []rune(a.labelBuf); len(r) > 0 {
+			a.labelBuf = string(r[:len(r)-1])
+		}
+	case key.NameReturn, key.NameEnter:
+		// Strokes may have been evicted or cleared meanwhile. The new
+		// label is an edit, for undo to give back the old one.
+		if i := a.labelIdx; i < len(a.strokes) && a.strokes[i].Dimension && a.strokes[i].At.Equal(a.labelAt) {
+			a.edit(func() bool {
+				a.strokes[i].Label = strings.TrimSpace(a.labelBuf)
+				return true
+			})
+		}
+		a.labelEntry = false
+	case key.NameEscape:
+		a.labelEntry = false
+	}
+}
+
+// drawLabelEntry shows the label being typed near the top of the window.
+func (a *Annotator) drawLabelEntry(gtx layout.Context) {
+	txt := "Label: " + a.labelBuf + "_"
+	if a.labelBuf == "" {
+		txt += "  (empty: the length)"
+	}
+	pos := image.Pt(gtx.Constraints.Max.X/2-gtx.Dp(120), gtx.Dp(24))
+	a.drawLabel(gtx, pos, txt, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	"gioui.org/layout"
+)
+
+// Dynamic width (W): strokes get thinner the faster the pointer moves,
+// like ink from a real pen. Stroke.Width is the width at rest, and stays
+// the maximum; minWidthScale of it is reached at fastSpeed.
+const (
+	minWidthScale = 0.4
+	fastSpeed     = 2.0 // dp per ms
+	// Weight of a new speed sample in the running width, so that jitter
+	// in the event timing does not show as lumps.
+	widthSmoothing = 0.3
+)
+
+// velocityWidth returns the width for the current stroke after the
+// pointer moved d px, with the event at t.
+func (a *Annotator) velocityWidth(gtx layout.Context, d float32, t time.Duration) float32 {
+	s := a.cur
+	prev := s.Widths[len(s.Widths)-1]
+	dt := t - a.dragTime
+	a.dragTime = t
+	if dt <= 0 {
+		return prev
+	}
+	speed := d / gtx.Metric.PxPerDp / float32(dt.Seconds()*1000)
+	scale := 1 - (1-minWidthScale)*min(speed/fastSpeed, 1)
+	return prev + (s.Width*scale-prev)*widthSmoothing
+}
+
+// extendWidths gives the points appended since the last call widths
+// running evenly from the last known width to w.
+func (s *Stroke) extendWidths(w float32) {
+	n := len(s.Widths)
+	prev := s.Widths[n-1]
+	added := len(s.Pts) - n
+	for i := 1; i <= added; i++ {
+		s.Widths = append(s.Widths, prev+(w-prev)*float32(i)/float32(added))
+	}
+}
+
+// widthAt is the stroke's width at point i.
+func (s *Stroke) widthAt(i int) float32 {
+	if s.Widths == nil {
+		return s.Width
+	}
+	return s.Widths[i]
+}
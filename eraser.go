@@ -0,0 +1,104 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/f32"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The eraser (Shift+E) removes whole strokes: pressing and dragging takes
+// away every stroke the pointer comes within the pen radius of, along
+// its line, or anywhere in the box of text and filled areas and shapes.
+// Locked strokes stay. A ring at the pointer shows the radius. Each drag
+// that took any is one change for undo (undo.go), which brings its
+// strokes back where they were.
+
+var toolEraser = registerTool("eraser", eraserTool{})
+
+// eraserRadius is how near the pointer a stroke has to be to go.
+func (a *Annotator) eraserRadius(gtx layout.Context) float32 {
+	return max(dpToPx(gtx, a.widthDp)/2, float32(gtx.Dp(6)))
+}
+
+// strokeHit reports whether s comes within r of p.
+func strokeHit(s *Stroke, p f32.Point, r float32) bool {
+	if len(s.Pts) == 0 {
+		return false
+	}
+	if s.Text != "" || s.Fill != nil || s.FillAlpha > 0 {
+		return image.Pt(int(p.X), int(p.Y)).In(strokeBounds(s).Inset(-int(r)))
+	}
+	pts := s.withHeads()
+	if len(pts) == 1 {
+		return dist(p, pts[0]) <= r+s.Width/2
+	}
+	for i := 1; i < len(pts); i++ {
+		if segmentDist(p, pts[i-1], pts[i]) <= r+s.Width/2 {
+			return true
+		}
+	}
+	return false
+}
+
+// eraseAt removes the strokes under p, keeping the strokes as they were
+// before the first of the drag.
+func (a *Annotator) eraseAt(gtx layout.Context, p f32.Point) {
+	e := a.erasing
+	if e == nil {
+		return
+	}
+	r := a.eraserRadius(gtx)
+	for i := len(a.strokes) - 1; i >= 0; i-- {
+		if s := &a.strokes[i]; s.Locked || !strokeHit(s, p, r) {
+			continue
+		}
+		if e.before == nil {
+			e.before = cloneStrokes(a.strokes)
+		}
+		a.strokes = append(a.strokes[:i], a.strokes[i+1:]...)
+		a.sel = -1
+	}
+}
+
+// eraserTool erases along each drag; a.erasing is the drag's change.
+type eraserTool struct{}
+
+func (eraserTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.trackUndo()
+	a.erasing = &change{}
+	a.eraseAt(gtx, pe.Position)
+}
+
+func (eraserTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.eraseAt(gtx, pe.Position)
+}
+
+func (eraserTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if e := a.erasing; e != nil && e.before != nil {
+		a.commitChange(e)
+	}
+	a.erasing = nil
+}
+
+func (eraserTool) Render(a *Annotator, gtx layout.Context) {
+	if !a.ptrIn {
+		return
+	}
+	r := a.eraserRadius(gtx)
+	box := image.Rectangle{Min: a.ptr.Sub(f32.Pt(r, r)).Round(), Max: a.ptr.Add(f32.Pt(r, r)).Round()}
+	for _, o := range []struct {
+		col   color.NRGBA
+		width int
+	}{
+		{color.NRGBA{A: 0xa0}, gtx.Dp(3)},
+		{color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, gtx.Dp(1)},
+	} {
+		path := clip.Ellipse(box).Path(gtx.Ops)
+		paint.FillShape(gtx.Ops, o.col, clip.Stroke{Path: path, Width: float32(o.width)}.Op())
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/paint"
+)
+
+// A stroke cap (-max-strokes) bounds memory and drawing time of an
+// always-on overlay: past it, the oldest strokes give way to new ones,
+// fading out over evictFade instead of vanishing. Evicted strokes are
+// gone for good; nothing brings them back, and the next autosave no
+// longer has them either.
+
+const evictFade = 400 * time.Millisecond
+
+// evicted is a stroke on its way out.
+type evicted struct {
+	s  Stroke
+	at time.Time
+}
+
+// evictStrokes drops the oldest strokes beyond the cap, keeping the
+// counts of the last frame and the selection on the same strokes.
+func (a *Annotator) evictStrokes(now time.Time) {
+	k := len(a.strokes) - a.maxStrokes
+	if a.maxStrokes <= 0 || k <= 0 {
+		return
+	}
+	for _, s := range a.strokes[:k] {
+		a.evicting = append(a.evicting, evicted{s, now})
+	}
+	a.strokes = append(a.strokes[:0:0], a.strokes[k:]...)
+	a.dropHistory()
+	if a.sel >= 0 {
+		if a.sel -= k; a.sel < 0 {
+			a.sel = -1
+		}
+	}
+	a.fadeSeen = max(a.fadeSeen-k, 0)
+	a.snapSeen = max(a.snapSeen-k, 0)
+	if a.guide != nil {
+		a.guide.seen = max(a.guide.seen-k, 0)
+	}
+	if a.debug {
+		log.Printf("-max-strokes %d: evicted %d strokes", a.maxStrokes, k)
+	}
+}
+
+// drawEvicting fades out the evicted strokes, below the others.
+func (a *Annotator) drawEvicting(gtx layout.Context) {
+	n := 0
+	for _, e := range a.evicting {
+		t := gtx.Now.Sub(e.at)
+		if t >= evictFade {
+			continue
+		}
+		a.evicting[n] = e
+		n++
+		st := paint.PushOpacity(gtx.Ops, 1-float32(t)/float32(evictFade))
+		a.paintStroke(gtx, &e.s)
+		st.Pop()
+	}
+	clear(a.evicting[n:])
+	a.evicting = a.evicting[:n]
+	if n > 0 {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// exportOptions shape the PNG exports (-export-crop, -export-margin,
+// -export-background, -export-smooth, -watermark).
+type exportOptions struct {
+	// crop cuts the image down to the strokes plus margin px around
+	// them.
+	crop   bool
+	margin int
+	// bg, if set, is put behind transparent parts, for viewers that
+	// show transparency badly.
+	bg *color.NRGBA
+	// smooth is the scale, in px, freehand strokes are smoothed at in
+	// PNG and SVG exports, 0 for none (smooth.go).
+	smooth float32
+	// watermark, if set, goes onto the finished picture (watermark.go).
+	watermark *watermark
+}
+
+// finish applies the options to a rendered export of strokes.
+func (o exportOptions) finish(img *image.RGBA, strokes []Stroke) image.Image {
+	var out image.Image = img
+	if o.crop {
+		var r image.Rectangle
+		for i := range strokes {
+			r = r.Union(strokeBounds(&strokes[i]))
+		}
+		// Nothing drawn leaves the image as it is.
+		if r = r.Inset(-o.margin).Intersect(img.Bounds()); !r.Empty() {
+			out = img.SubImage(r)
+		}
+	}
+	if o.bg != nil {
+		b := out.Bounds()
+		flat := image.NewRGBA(b)
+		draw.Draw(flat, b, image.NewUniform(*o.bg), image.Point{}, draw.Src)
+		draw.Draw(flat, b, out, b.Min, draw.Over)
+		out = flat
+	}
+	return out
+}
+
+// mark puts the watermark, if any, onto the finished picture img.
+func (o exportOptions) mark(img image.Image) image.Image {
+	if o.watermark == nil {
+		return img
+	}
+	return o.watermark.apply(img)
+}
+
+// parseExportBackground parses -export-background: "transparent" or a
+// color.
+func parseExportBackground(s string) (*color.NRGBA, error) {
+	if s == "" || s == "transparent" {
+		return nil, nil
+	}
+	c, err := parseHexColor(s)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// The export hook (-on-export, or "on-export" in the flags of the config
+// file) runs a command on every PNG written, with the file's path as its
+// last argument, e.g. an uploader that prints a URL. It runs in the
+// background; its output is logged and the first line of it shown, so a
+// link appears a moment after the export. Quitting (and -oneshot) waits
+// for commands still running, so an upload is not cut short.
+
+type exportHook struct {
+	cmd []string
+	wg  sync.WaitGroup
+}
+
+// hookResult is what a hook command printed, for the toast.
+type hookResult struct {
+	path string
+	out  string
+	err  error
+}
+
+// newExportHook parses the command line of the hook, split at spaces;
+// an empty one runs nothing.
+func newExportHook(cmdline string) *exportHook {
+	cmd := strings.Fields(cmdline)
+	if len(cmd) == 0 {
+		return nil
+	}
+	return &exportHook{cmd: cmd}
+}
+
+// run starts the hook on path and passes the result to done, on the
+// hook's goroutine, when it has finished; a nil hook does nothing.
+func (h *exportHook) run(path string, done func(hookResult)) {
+	if h == nil {
+		return
+	}
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(h.cmd[0], append(h.cmd[1:], path)...)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		out := strings.TrimSpace(stdout.String())
+		for _, l := range strings.Split(out, "\n") {
+			if l != "" {
+				log.Printf("on-export %s: %s", path, l)
+			}
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			log.Printf("on-export %s: stderr: %s", path, msg)
+			if err != nil {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+		}
+		done(hookResult{path: path, out: out, err: err})
+	}()
+}
+
+// wait blocks until the running hook commands have finished.
+func (h *exportHook) wait() {
+	if h != nil {
+		h.wg.Wait()
+	}
+}
+
+// runExportHook runs the hook on the PNG just written to path.
+func (a *Annotator) runExportHook(path string) {
+	a.hook.run(path, func(res hookResult) {
+		select {
+		case a.hookDone <- res:
+		default:
+			// Toasts of earlier runs are still pending; it is logged.
+		}
+		a.w.Invalidate()
+	})
+}
+
+// applyHooks shows the outcome of finished hook commands.
+func (a *Annotator) applyHooks() {
+	for {
+		select {
+		case res := <-a.hookDone:
+			first, _, _ := strings.Cut(res.out, "\n")
+			switch {
+			case res.err != nil:
+				a.notifyErr(fmt.Errorf("on-export %s: %w", res.path, res.err))
+			case first != "":
+				a.notify("%s", first)
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/paint"
+)
+
+// Fading in (-fade-in) ramps strokes from transparent to their opacity
+// over fadeInDuration once committed, for smoother screen recordings. It
+// is spotted in frame by the stroke list growing, so every way of adding
+// strokes takes part; what was already on screen as the stroke in
+// progress does not fade again. Only the overlay fades: exports are
+// rasterized at full opacity.
+
+const fadeInDuration = 150 * time.Millisecond
+
+// stampCommits stamps the strokes committed since the last frame.
+func (a *Annotator) stampCommits(now time.Time) {
+	if !a.fadeIn {
+		return
+	}
+	n := len(a.strokes)
+	for i := min(a.fadeSeen, n); i < n; i++ {
+		if s := &a.strokes[i]; !a.fadeDrawn || !s.At.Equal(a.fadeDrawnAt) {
+			s.committed = now
+		}
+	}
+	a.fadeSeen = n
+	a.fadeDrawn = a.cur != nil
+	if a.cur != nil {
+		a.fadeDrawnAt = a.cur.At
+	}
+}
+
+// fadeOpacity is how far s has faded in, and whether it still is.
+func (a *Annotator) fadeOpacity(s *Stroke, now time.Time) (float32, bool) {
+	if !a.fadeIn || s.committed.IsZero() {
+		return 1, false
+	}
+	t := now.Sub(s.committed)
+	if t >= fadeInDuration {
+		return 1, false
+	}
+	return max(float32(t)/float32(fadeInDuration), 0), true
+}
+
+// paintAnimated paints s at the opacity it has faded in to and its pulse
+// (pulse.go) is at, and keeps frames coming until both are done.
+func (a *Annotator) paintAnimated(gtx layout.Context, s *Stroke) {
+	fo, fading := a.fadeOpacity(s, gtx.Now)
+	po, pulsing := a.pulseOpacity(s, gtx.Now)
+	if !fading && !pulsing {
+		a.paintStroke(gtx, s)
+		return
+	}
+	defer paint.PushOpacity(gtx.Ops, fo*po).Pop()
+	a.paintStroke(gtx, s)
+	gtx.Execute(op.InvalidateCmd{})
+}
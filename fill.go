@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/paint"
+	xdraw "golang.org/x/image/draw"
+)
+
+// The fill tool floods the contiguous area of similar color under a click
+// on the background with the pen color, e.g. to blank out a uniform panel
+// in a screenshot. The result is a stroke whose Fill mask covers that
+// area, with its top-left corner at Pts[0], so it moves, exports and
+// saves like the others. Fills that would cover a large part of the
+// screen are refused: that is usually a click on a gradient or a photo.
+
+const (
+	// fillTolerance is how far (per channel, 0..255) a pixel's color
+	// may be from the clicked one to be filled.
+	fillTolerance = 24
+	// fillMaxShare caps a fill at this share of the background.
+	fillMaxShare = 0.4
+)
+
+// floodFill returns the mask of the 4-connected pixels within area whose
+// color is within tol of the one at seed, and where its top-left corner
+// goes, or false if more than limit pixels would be filled.
+func floodFill(img *image.RGBA, area image.Rectangle, seed image.Point, tol uint8, limit int) (*image.Alpha, image.Point, bool) {
+	area = area.Intersect(img.Bounds())
+	if !seed.In(area) {
+		return nil, image.Point{}, false
+	}
+	ref := img.RGBAAt(seed.X, seed.Y)
+	d := func(a, b uint8) uint8 { return max(a, b) - min(a, b) }
+	near := func(c color.RGBA) bool {
+		return d(c.R, ref.R) <= tol && d(c.G, ref.G) <= tol && d(c.B, ref.B) <= tol
+	}
+	w := area.Dx()
+	seen := make([]bool, w*area.Dy())
+	idx := func(p image.Point) int { return (p.Y-area.Min.Y)*w + p.X - area.Min.X }
+	stack := []image.Point{seed}
+	seen[idx(seed)] = true
+	var filled []image.Point
+	bounds := image.Rectangle{Min: seed, Max: seed.Add(image.Pt(1, 1))}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if filled = append(filled, p); len(filled) > limit {
+			return nil, image.Point{}, false
+		}
+		bounds = bounds.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+		for _, step := range [...]image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			q := p.Add(step)
+			if !q.In(area) || seen[idx(q)] || !near(img.RGBAAt(q.X, q.Y)) {
+				continue
+			}
+			seen[idx(q)] = true
+			stack = append(stack, q)
+		}
+	}
+	mask := image.NewAlpha(image.Rectangle{Max: bounds.Size()})
+	for _, p := range filled {
+		q := p.Sub(bounds.Min)
+		mask.Pix[q.Y*mask.Stride+q.X] = 0xff
+	}
+	return mask, bounds.Min, true
+}
+
+// fillAt fills the area of the background under p.
+func (a *Annotator) fillAt(gtx layout.Context, p f32.Point) {
+	if a.bg == nil {
+		a.notify("Fill needs a background")
+		return
+	}
+	area := a.bg.Bounds()
+	if !a.region.Empty() {
+		area = area.Intersect(a.region)
+	}
+	a.pruneFillOps()
+	limit := int(fillMaxShare * float32(a.bg.Bounds().Dx()*a.bg.Bounds().Dy()))
+	mask, at, ok := floodFill(a.bg, area, image.Pt(int(p.X), int(p.Y)), fillTolerance, limit)
+	if !ok {
+		a.notify("Area too large to fill")
+		return
+	}
+	a.strokes = append(a.strokes, Stroke{
+		Pts:   []f32.Point{layout.FPt(at)},
+		Col:   a.col,
+		Width: 1,
+		At:    gtx.Now,
+		Fill:  mask,
+	})
+}
+
+// fillBounds is where the fill s covers.
+func fillBounds(s *Stroke) image.Rectangle {
+	at := image.Pt(int(s.Pts[0].X), int(s.Pts[0].Y))
+	return s.Fill.Rect.Add(at)
+}
+
+// fillImage is the fill s in its color, with its top-left corner at the
+// origin.
+func fillImage(s *Stroke) *image.RGBA {
+	img := image.NewRGBA(s.Fill.Rect)
+	draw.DrawMask(img, img.Rect, image.NewUniform(s.Col), image.Point{}, s.Fill, image.Point{}, draw.Src)
+	return img
+}
+
+// drawFill paints a fill. The colored image is made once per mask, as
+// fills are never recolored.
+func (a *Annotator) drawFill(gtx layout.Context, s *Stroke) {
+	img, ok := a.fillOps[s.Fill]
+	if !ok {
+		if a.fillOps == nil {
+			a.fillOps = make(map[*image.Alpha]paint.ImageOp)
+		}
+		img = paint.NewImageOp(fillImage(s))
+		a.fillOps[s.Fill] = img
+	}
+	defer op.Offset(fillBounds(s).Min).Push(gtx.Ops).Pop()
+	img.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+}
+
+// pruneFillOps forgets the images of fills that are gone.
+func (a *Annotator) pruneFillOps() {
+	if len(a.fillOps) == 0 {
+		return
+	}
+	live := make(map[*image.Alpha]bool)
+	for _, strokes := range [][]Stroke{a.strokes, a.trace, a.otherPane.strokes, a.placing} {
+		for i := range strokes {
+			if strokes[i].Fill != nil {
+				live[strokes[i].Fill] = true
+			}
+		}
+	}
+	for m := range a.fillOps {
+		if !live[m] {
+			delete(a.fillOps, m)
+		}
+	}
+}
+
+func rasterFill(dst *image.RGBA, s *Stroke) {
+	r := fillBounds(s)
+	draw.DrawMask(dst, r, image.NewUniform(s.Col), image.Point{}, s.Fill, image.Point{}, draw.Over)
+}
+
+// writeSVGFill writes a fill as an embedded PNG image, the only way SVG
+// has for an arbitrary pixel area.
+func writeSVGFill(b *strings.Builder, s *Stroke) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, fillImage(s)); err != nil {
+		return
+	}
+	r := fillBounds(s)
+	fmt.Fprintf(b, `  <image x="%d" y="%d" width="%d" height="%d" href="data:image/png;base64,%s"/>`+"\n",
+		r.Min.X, r.Min.Y, r.Dx(), r.Dy(), base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// encodeFillMask and decodeFillMask convert a fill mask to and from its
+// session form, a base64 PNG.
+func encodeFillMask(m *image.Alpha) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func decodeFillMask(s string) (*image.Alpha, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("fill mask: %w", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("fill mask: %w", err)
+	}
+	b := img.Bounds()
+	m := image.NewAlpha(image.Rectangle{Max: b.Size()})
+	draw.Draw(m, m.Rect, img, b.Min, draw.Src)
+	return m, nil
+}
+
+// scaleFillMask resizes a fill mask by sx, sy, for scaleStrokes.
+func scaleFillMask(m *image.Alpha, sx, sy float32) *image.Alpha {
+	size := m.Rect.Size()
+	dst := image.NewAlpha(image.Rect(0, 0, max(1, int(float32(size.X)*sx+0.5)), max(1, int(float32(size.Y)*sy+0.5))))
+	xdraw.NearestNeighbor.Scale(dst, dst.Rect, m, m.Rect, draw.Src, nil)
+	return dst
+}
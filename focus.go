@@ -0,0 +1,31 @@
+package main
+
+import "log"
+
+// Periodic work that exists only to be seen (the -live recapture, so
+// far) pauses while nobody can be looking at the overlay: when it is
+// hidden through the control socket, or has lost the keyboard focus to
+// another window without being in click-through mode, where working in
+// the windows below with the overlay unfocused is the point. Gio sends no
+// frames to a minimized window, so that case pauses by itself. Timers
+// that change state (autosave, idle clear, following a window) go on.
+
+// setFocused records a focus change of the window.
+func (a *Annotator) setFocused(focused bool) {
+	if focused == a.focused {
+		return
+	}
+	a.focused = focused
+	if a.debug {
+		log.Printf("window focused: %v", focused)
+	}
+	if focused {
+		// Paused work resumes in the next frame.
+		a.w.Invalidate()
+	}
+}
+
+// paused reports whether visual refreshes should stop for now.
+func (a *Annotator) paused() bool {
+	return a.hidden || !a.focused && !a.clickThrough
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Following a window (-follow-window) makes the overlay cover one
+// application window instead of a monitor, and move and resize with it.
+// The overlay is an override-redirect window (as with -fullscreen
+// override), so the window manager leaves its geometry alone; the
+// followed window's geometry is polled, which needs no events from
+// another client. Strokes are in overlay pixels and so stay put relative
+// to the followed window; its content is captured again after it moved.
+
+// followInterval is how often the followed window's geometry is checked.
+const followInterval = 100 * time.Millisecond
+
+// errNotViewable is returned for a followed window that exists but is not
+// shown, e.g. minimized or on another workspace.
+var errNotViewable = errors.New("window is not viewable")
+
+// parseFollowWindow resolves -follow-window: an X11 window ID in decimal
+// or 0x hex (as xwininfo and xdotool print them), or "pointer" for the
+// window under the pointer at startup.
+func parseFollowWindow(s string) (uintptr, error) {
+	if s == "pointer" {
+		return x11WindowUnderPointer()
+	}
+	id, err := strconv.ParseUint(s, 0, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%q: want a window ID or \"pointer\"", s)
+	}
+	return uintptr(id), nil
+}
+
+// followWindow moves the overlay onto the followed window when that has
+// moved or resized since the last check.
+func (a *Annotator) followWindow(gtx layout.Context) {
+	if a.follow == 0 || a.x11Display == nil || a.x11Window == 0 {
+		return
+	}
+	next := a.followAt.Add(followInterval)
+	if gtx.Now.Before(next) {
+		gtx.Execute(op.InvalidateCmd{At: next})
+		return
+	}
+	a.followAt = gtx.Now
+	gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(followInterval)})
+	if !a.followSettled.IsZero() && !gtx.Now.Before(a.followSettled) && !a.capturing {
+		// The move is over; as with a resize, the new capture counts as
+		// the first one.
+		a.followSettled = time.Time{}
+		a.bg = nil
+		a.requestCapture(0)
+	}
+	r, err := x11FollowedGeometry(a.x11Display, a.follow)
+	switch {
+	case errors.Is(err, errNotViewable):
+		// Wait for it to come back where it was or elsewhere.
+		return
+	case err != nil:
+		log.Printf("-follow-window: %v; staying where it was", err)
+		a.notify("Followed window is gone")
+		a.follow = 0
+		return
+	}
+	if r == a.followRect {
+		return
+	}
+	moved := a.followRect != (image.Rectangle{}) && r.Size() == a.followRect.Size()
+	a.followRect = r
+	if err := x11MoveResizeWindow(a.x11Display, a.x11Window, r); err != nil {
+		log.Printf("-follow-window: %v", err)
+		return
+	}
+	a.winOrigin = r.Min
+	if a.debug {
+		log.Printf("following window 0x%x at %v", a.follow, r)
+	}
+	// A resize is handled by checkResize once the new size arrives; a
+	// move is captured again once the window stays put.
+	if moved && a.bgSrc == nil && a.bg != nil {
+		a.followSettled = gtx.Now.Add(resizeRecaptureDelay)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// The overlay draws only when something changes: input, a capture or
+// control command coming in, or a timed change that asked for a frame at
+// its moment (a toast or width hint running out, a stroke fading in or
+// pulsing, autosave and idle clear deadlines, -live and -follow-window
+// polling).
+// Nothing invalidates unconditionally, so an idle overlay draws no frames
+// at all. To catch regressions, ANNOTATOR_DEBUG logs how many frames
+// each second of activity took; a steady count with nothing happening
+// means some path keeps asking for frames.
+
+// frameStats counts the frames of the current second with any.
+type frameStats struct {
+	since time.Time
+	n     int
+}
+
+// countFrame adds a frame drawn at now, logging the count of the
+// previous second once it is over. A second without frames logs nothing,
+// so the log is quiet while idle, too.
+func (a *Annotator) countFrame(now time.Time) {
+	if !a.debug {
+		return
+	}
+	f := &a.frameStats
+	if now.Sub(f.since) >= time.Second {
+		if f.n > 0 && a.activeAt.IsZero() {
+			log.Printf("frames: %d in the second from %s, before any input", f.n, f.since.Format("15:04:05.000"))
+		} else if f.n > 0 {
+			log.Printf("frames: %d in the second from %s, last input %v before", f.n, f.since.Format("15:04:05.000"), f.since.Sub(a.activeAt).Round(time.Millisecond))
+		}
+		f.since, f.n = now, 0
+	}
+	f.n++
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"time"
+
+	"gioui.org/f32"
+)
+
+// The clear gesture (-scribble-clear) is a big, fast back-and-forth
+// scribble, for clearing with only a pen at hand. The thresholds are
+// deliberately strict, and even a match only asks: the scribble stays an
+// ordinary stroke unless the next tap confirms within clearConfirmWindow.
+const (
+	// The scribble must span this fraction of the window in both
+	// directions.
+	scribbleMinCover = 0.25
+	// Reversals along its main axis, each of at least
+	// scribbleReversalFrac of its extent on that axis.
+	scribbleMinReversals = 6
+	scribbleReversalFrac = 0.5
+	// Longest time it may take.
+	scribbleMaxDuration = 2 * time.Second
+
+	clearConfirmWindow = 3 * time.Second
+)
+
+// isClearScribble reports whether pts, drawn in d on a window of the given
+// size, is the clear gesture.
+func isClearScribble(pts []f32.Point, d time.Duration, size f32.Point) bool {
+	if len(pts) < 2*scribbleMinReversals || d > scribbleMaxDuration {
+		return false
+	}
+	minP, maxP := bounds(pts)
+	w, h := maxP.X-minP.X, maxP.Y-minP.Y
+	if w < scribbleMinCover*size.X || h < scribbleMinCover*size.Y {
+		return false
+	}
+	xs, ys := make([]float32, len(pts)), make([]float32, len(pts))
+	for i, p := range pts {
+		xs[i], ys[i] = p.X, p.Y
+	}
+	n := max(reversals(xs, scribbleReversalFrac*w), reversals(ys, scribbleReversalFrac*h))
+	return n >= scribbleMinReversals
+}
+
+// reversals counts the changes of direction in vs, ignoring wiggles
+// smaller than hyst: a turn counts once the values have come back by hyst
+// from their extreme since the previous turn.
+func reversals(vs []float32, hyst float32) int {
+	n, dir := 0, 0
+	ext := vs[0]
+	for _, v := range vs[1:] {
+		switch dir {
+		case 0:
+			if v-ext >= hyst {
+				dir, ext = 1, v
+			} else if ext-v >= hyst {
+				dir, ext = -1, v
+			}
+		case 1:
+			if v > ext {
+				ext = v
+			} else if ext-v >= hyst {
+				n++
+				dir, ext = -1, v
+			}
+		case -1:
+			if v < ext {
+				ext = v
+			} else if v-ext >= hyst {
+				n++
+				dir, ext = 1, v
+			}
+		}
+	}
+	return n
+}
+
+// checkClearScribble asks for confirmation after a stroke that looks like
+// the clear gesture.
+func (a *Annotator) checkClearScribble(s *Stroke, now time.Time) {
+	if !a.scribbleClear || s.Measure || s.Step > 0 {
+		return
+	}
+	size := f32.Pt(float32(a.size.X), float32(a.size.Y))
+	if !isClearScribble(s.Pts, now.Sub(s.At), size) {
+		return
+	}
+	a.clearPromptAt = now
+	a.notify("Clear everything? Tap to confirm")
+}
+
+// confirmClear handles a tap while the clear prompt is up: it clears and
+// reports true, meaning the tap is used up. After the prompt has expired
+// nothing happens and the tap draws as usual.
+func (a *Annotator) confirmClear(now time.Time) bool {
+	if a.clearPromptAt.IsZero() {
+		return false
+	}
+	at := a.clearPromptAt
+	a.clearPromptAt = time.Time{}
+	if now.Sub(at) > clearConfirmWindow {
+		return false
+	}
+	a.strokes = nil
+	a.cur = nil
+	a.dropHistory()
+	a.notify("Cleared")
+	a.sounds.play(cueClear)
+	return true
+}
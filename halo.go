@@ -0,0 +1,115 @@
+package main
+
+import (
+	"image"
+	"image/color"
+)
+
+// Auto-contrast (Ctrl+A) gives every new line, arrow and shape a thin
+// halo, an outline in black or white, whichever is further from the
+// average brightness of the captured background along it, so the
+// annotation stands out over any content in any color. The halo is
+// picked once, when the stroke is committed, and kept with it, so
+// exports and sessions show the same one; it is drawn as a wider copy of
+// the stroke underneath.
+
+var (
+	haloDark  = color.NRGBA{A: 0xff}
+	haloLight = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+// toggleAutoContrast switches halos for new strokes on or off.
+func (a *Annotator) toggleAutoContrast() {
+	a.autoContrast = !a.autoContrast
+	if a.autoContrast {
+		a.notify("Auto-contrast halo: on")
+	} else {
+		a.notify("Auto-contrast halo: off")
+	}
+}
+
+// autoHalo picks the halo of a stroke being committed, if auto-contrast
+// is on. Text, markers, fills, redactions and highlighter strokes have
+// none.
+func (a *Annotator) autoHalo(s *Stroke) {
+	if !a.autoContrast || len(s.Pts) == 0 || s.Text != "" || s.Step > 0 || s.Fill != nil || s.Pixelate || s.Measure || s.Highlight {
+		return
+	}
+	s.Halo = haloDark
+	if !a.brightUnder(s) {
+		s.Halo = haloLight
+	}
+	// As see-through as the stroke, so highlighters stay highlighters.
+	s.Halo.A = s.Col.A
+}
+
+// brightUnder reports whether the background along s is light on
+// average; without one, the stroke's own color stands in for it, so
+// the halo sets the color off.
+func (a *Annotator) brightUnder(s *Stroke) bool {
+	bg := a.bg
+	if bg == nil {
+		return !isBright(s.Col)
+	}
+	var sum, n int
+	b := bg.Bounds()
+	for _, p := range s.Pts {
+		pt := image.Pt(int(p.X), int(p.Y))
+		if !pt.In(b) {
+			continue
+		}
+		c := bg.RGBAAt(pt.X, pt.Y)
+		sum += 299*int(c.R) + 587*int(c.G) + 114*int(c.B)
+		n++
+	}
+	if n == 0 {
+		return !isBright(s.Col)
+	}
+	return sum/n > 128_000
+}
+
+// isBright reports whether c is a light color.
+func isBright(c color.NRGBA) bool {
+	return 299*int(c.R)+587*int(c.G)+114*int(c.B) > 128_000
+}
+
+// haloWidth is how far the halo of s reaches out on each side.
+func haloWidth(s *Stroke) float32 {
+	return max(1.5, s.Width*0.15)
+}
+
+// haloStroke is the halo of s as a stroke of its own: s widened by
+// haloWidth on both sides, in the halo color, without a fill.
+func haloStroke(s *Stroke) (Stroke, bool) {
+	if s.Halo.A == 0 {
+		return Stroke{}, false
+	}
+	w := 2 * haloWidth(s)
+	h := *s
+	if s.Hollow {
+		// Around the boundary that is drawn.
+		h = hollowStroke(s)
+	}
+	h.Col, h.Halo, h.FillAlpha, h.Chalk = s.Halo, color.NRGBA{}, 0, false
+	h.Width += w
+	if h.Widths != nil {
+		h.Widths = make([]float32, len(s.Widths))
+		for i, v := range s.Widths {
+			h.Widths[i] = v + w
+		}
+	}
+	return h, true
+}
+
+// withHalos returns strokes with each halo as a stroke just below its
+// own, for exports that draw strokes one by one.
+func withHalos(strokes []Stroke) []Stroke {
+	out := make([]Stroke, 0, len(strokes))
+	for i := range strokes {
+		if h, ok := haloStroke(&strokes[i]); ok {
+			out = append(out, h)
+		}
+		out = append(out, strokes[i])
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"slices"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Resize handles: the selection box of a stroke with a shape to scale
+// (anything but text, step markers and fills) has handles on its corners
+// and edges, and dragging one scales the stroke's points so that the box
+// follows the pointer on that side, the opposite side staying put. The
+// pen width is kept, as when redrawing the shape larger. Each drag that
+// resized the stroke is a change for undo.
+
+// boxHandle is a handle by the sides it moves: -1 left/top, 1 right/bottom,
+// 0 neither.
+type boxHandle struct{ x, y int }
+
+var boxHandles = []boxHandle{
+	{-1, -1}, {0, -1}, {1, -1},
+	{-1, 0}, {1, 0},
+	{-1, 1}, {0, 1}, {1, 1},
+}
+
+// handleDrag is a handle being dragged.
+type handleDrag struct {
+	h        boxHandle
+	from     f32.Point // where the drag started
+	min, max f32.Point // bounds of the points then
+	orig     []f32.Point
+	change   *change // the strokes before the drag
+}
+
+// resizable reports whether s can be resized with the handles.
+func resizable(s *Stroke) bool {
+	return s.Text == "" && s.Step == 0 && s.Fill == nil && len(s.Pts) > 1
+}
+
+// handleRects returns the squares of the handles of the selected stroke,
+// on the box drawSelection draws, or nil.
+func (a *Annotator) handleRects(gtx layout.Context) []image.Rectangle {
+	s := a.selected()
+	if s == nil || !resizable(s) || s.Locked {
+		return nil
+	}
+	box := strokeBounds(s).Inset(-gtx.Dp(4))
+	half := gtx.Dp(4)
+	rects := make([]image.Rectangle, len(boxHandles))
+	for i, h := range boxHandles {
+		c := image.Pt(
+			box.Min.X+(h.x+1)*box.Dx()/2,
+			box.Min.Y+(h.y+1)*box.Dy()/2,
+		)
+		rects[i] = image.Rectangle{Min: c, Max: c}.Inset(-half)
+	}
+	return rects
+}
+
+// startHandleDrag starts dragging the handle at p, if there is one.
+func (a *Annotator) startHandleDrag(gtx layout.Context, p f32.Point) bool {
+	// A little more than the square, for fingers and pens.
+	slop := gtx.Dp(3)
+	for i, r := range a.handleRects(gtx) {
+		if !p.Round().In(r.Inset(-slop)) {
+			continue
+		}
+		s := a.selected()
+		minP, maxP := bounds(s.Pts)
+		a.handleDrag = &handleDrag{h: boxHandles[i], from: p, min: minP, max: maxP, orig: append([]f32.Point(nil), s.Pts...), change: a.beginChange()}
+		return true
+	}
+	return false
+}
+
+// dragHandle scales the selected stroke for the handle dragged to p.
+func (a *Annotator) dragHandle(p f32.Point) {
+	d, s := a.handleDrag, a.selected()
+	if s == nil || len(s.Pts) != len(d.orig) {
+		a.handleDrag = nil
+		return
+	}
+	delta := p.Sub(d.from)
+	minP, maxP := d.min, d.max
+	switch d.h.x {
+	case -1:
+		minP.X += delta.X
+	case 1:
+		maxP.X += delta.X
+	}
+	switch d.h.y {
+	case -1:
+		minP.Y += delta.Y
+	case 1:
+		maxP.Y += delta.Y
+	}
+	// A flat shape (a horizontal or vertical line) cannot be stretched
+	// across; it keeps that coordinate.
+	scale := func(v, oldMin, oldMax, newMin, newMax float32) float32 {
+		if oldMax-oldMin < 1 {
+			return v
+		}
+		return newMin + (v-oldMin)*(newMax-newMin)/(oldMax-oldMin)
+	}
+	for i, q := range d.orig {
+		s.Pts[i] = f32.Pt(
+			scale(q.X, d.min.X, d.max.X, minP.X, maxP.X),
+			scale(q.Y, d.min.Y, d.max.Y, minP.Y, maxP.Y),
+		)
+	}
+}
+
+// finishHandleDrag ends the drag of a handle, making the resize, if it
+// came to one, a step for undo.
+func (a *Annotator) finishHandleDrag() {
+	d := a.handleDrag
+	a.handleDrag = nil
+	if s := a.selected(); s != nil && !slices.Equal(s.Pts, d.orig) {
+		a.commitChange(d.change)
+	}
+}
+
+// drawHandles draws the handles of the selection as small squares, white
+// with a black edge like the box.
+func (a *Annotator) drawHandles(gtx layout.Context) {
+	for _, r := range a.handleRects(gtx) {
+		paint.FillShape(gtx.Ops, color.NRGBA{A: 0xff}, clip.Rect(r.Inset(-gtx.Dp(1))).Op())
+		paint.FillShape(gtx.Ops, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, clip.Rect(r).Op())
+	}
+}
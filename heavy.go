@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// Every frame draws every stroke from its points, so a long session gets
+// slower as it grows. Past -warn-strokes strokes or -warn-points points
+// in all, a small amber note in the top-right corner says so, and what
+// to do about it: clear (C, or Shift+C to keep redactions), or cap the
+// strokes with -max-strokes. A click on the note dismisses it until the
+// session drops below both thresholds again. The totals are counted
+// again only when the strokes change (strokesMark, undo.go).
+
+var heavyCol = color.NRGBA{R: 0xff, G: 0xc0, B: 0x40, A: 0xff}
+
+// heavyWarning is the state of the note.
+type heavyWarning struct {
+	maxStrokes, maxPoints int // 0 for no limit
+	// The totals, and the strokes they were counted for.
+	strokes, points int
+	seen            strokesMark
+	over, dismissed bool
+}
+
+// checkHeavy counts the totals again if the strokes changed.
+func (a *Annotator) checkHeavy() {
+	h := &a.heavy
+	if h.maxStrokes <= 0 && h.maxPoints <= 0 {
+		return
+	}
+	m := a.strokesMark()
+	if m == h.seen {
+		return
+	}
+	h.seen = m
+	h.strokes, h.points = m.n, 0
+	for i := range a.strokes {
+		h.points += len(a.strokes[i].Pts)
+	}
+	over := h.maxStrokes > 0 && h.strokes > h.maxStrokes || h.maxPoints > 0 && h.points > h.maxPoints
+	if over && !h.over && a.debug {
+		log.Printf("heavy session: %d strokes, %d points (-warn-strokes %d, -warn-points %d)", h.strokes, h.points, h.maxStrokes, h.maxPoints)
+	}
+	if !over {
+		h.dismissed = false
+	}
+	h.over = over
+}
+
+// drawHeavy shows the note, clickable to dismiss it.
+func (a *Annotator) drawHeavy(gtx layout.Context) {
+	h := &a.heavy
+	for {
+		ev, ok := gtx.Event(pointer.Filter{Target: h, Kinds: pointer.Press})
+		if !ok {
+			break
+		}
+		if ev.(pointer.Event).Kind == pointer.Press {
+			h.dismissed = true
+		}
+	}
+	if !h.over || h.dismissed {
+		return
+	}
+	txt := fmt.Sprintf("%d strokes, %d points: may get slow. C clears, -max-strokes caps", h.strokes, h.points)
+	macro := op.Record(gtx.Ops)
+	sz := a.drawLabel(gtx, image.Point{}, txt, heavyCol)
+	call := macro.Stop()
+	m := gtx.Dp(16)
+	r := image.Rectangle{Max: sz}.Add(image.Pt(gtx.Constraints.Max.X-m-sz.X, m))
+	defer op.Offset(r.Min).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+	area := clip.Rect{Max: sz}.Push(gtx.Ops)
+	event.Op(gtx.Ops, h)
+	pointer.CursorPointer.Add(gtx.Ops)
+	area.Pop()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"strings"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Precise color entry: '#' opens a prompt, hex digits arrive as edit
+// events (the same text-input path an IME uses), Enter commits and
+// Escape cancels. While the prompt is open, shortcut keys are ignored.
+// Committed colors join the recent-colors row shown under the prompt.
+
+func (a *Annotator) startHexEntry() {
+	a.hexEntry = true
+	a.hexBuf = ""
+}
+
+// hexEdit consumes typed text while the prompt is open.
+func (a *Annotator) hexEdit(txt string) {
+	for _, r := range txt {
+		if isHexDigit(r) && len(a.hexBuf) < 6 {
+			a.hexBuf += strings.ToUpper(string(r))
+		}
+	}
+}
+
+// hexKey handles the editing keys of the prompt.
+func (a *Annotator) hexKey(ke key.Event) {
+	switch ke.Name {
+	case key.NameDeleteBackward:
+		if n := len(a.hexBuf); n > 0 {
+			a.hexBuf = a.hexBuf[:n-1]
+		}
+	case key.NameReturn, key.NameEnter:
+		c, err := parseHexColor(a.hexBuf)
+		if err != nil {
+			if a.debug {
+				log.Printf("hex color: %v", err)
+			}
+			return
+		}
+		a.useCustomColor(c)
+		a.hexEntry = false
+	case key.NameEscape:
+		a.hexEntry = false
+	}
+}
+
+// drawHexEntry shows the typed code and a swatch of the color it will
+// commit to, centered near the top of the window.
+func (a *Annotator) drawHexEntry(gtx layout.Context) {
+	txt := "#" + a.hexBuf + strings.Repeat("_", 6-len(a.hexBuf))
+	pos := image.Pt(gtx.Constraints.Max.X/2-gtx.Dp(60), gtx.Dp(24))
+	sz := a.drawLabel(gtx, pos, txt, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+
+	sw := image.Rectangle{Min: image.Pt(pos.X+sz.X+gtx.Dp(6), pos.Y), Max: image.Pt(pos.X+sz.X+gtx.Dp(6)+sz.Y, pos.Y+sz.Y)}
+	rr := clip.UniformRRect(sw, gtx.Dp(4))
+	if c, err := parseHexColor(a.hexBuf); err == nil {
+		paint.FillShape(gtx.Ops, c, rr.Op(gtx.Ops))
+	} else {
+		paint.FillShape(gtx.Ops, labelBg, rr.Op(gtx.Ops))
+	}
+	paint.FillShape(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+		clip.Stroke{Path: rr.Path(gtx.Ops), Width: float32(gtx.Dp(1))}.Op())
+
+	// Recently used custom colors, clickable, below the prompt.
+	a.drawRecentColors(gtx, image.Pt(pos.X, pos.Y+sz.Y+gtx.Dp(6)), sz.Y)
+}
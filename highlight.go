@@ -0,0 +1,144 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"golang.org/x/image/vector"
+)
+
+// The highlighter (Shift+H) is a marker for going over text: Highlight
+// strokes in the pen color at highlightAlpha, unless the color is
+// translucent already, drawn with a flat nib held upright, Width tall and
+// a quarter of that thick, so lines along text come out as an even band
+// with square ends. Each segment sweeps the nib into a convex polygon,
+// and all of a stroke's polygons fill as one shape, so a stroke is the
+// same tint where it goes over itself; only separate strokes stack. The
+// polygons are what every renderer draws: on screen, in PNGs and as one
+// SVG path alike. Yellow (Y) and green (G) are the usual colors.
+
+// highlightAlpha is the opacity of highlighter strokes in an opaque
+// color, the same as of word highlights (ocr.go).
+const highlightAlpha = 0x60
+
+// highlightColor is the color of a highlighter stroke in the pen color
+// col.
+func highlightColor(col color.NRGBA) color.NRGBA {
+	if col.A == 0xff {
+		col.A = highlightAlpha
+	}
+	return col
+}
+
+// highlightNib is the half size of the nib for strokes of width w.
+func highlightNib(w float32) f32.Point {
+	return f32.Pt(max(1, w/8), max(1, w/2))
+}
+
+// highlightPolygons are the areas the nib of s sweeps, one per segment,
+// or the nib itself for a single point, all wound the same way.
+func highlightPolygons(s *Stroke) [][]f32.Point {
+	h := highlightNib(s.Width)
+	corners := func(p f32.Point) []f32.Point {
+		return []f32.Point{
+			p.Add(f32.Pt(-h.X, -h.Y)), p.Add(f32.Pt(h.X, -h.Y)),
+			p.Add(f32.Pt(h.X, h.Y)), p.Add(f32.Pt(-h.X, h.Y)),
+		}
+	}
+	if len(s.Pts) == 1 {
+		return [][]f32.Point{corners(s.Pts[0])}
+	}
+	var polys [][]f32.Point
+	for i := 1; i < len(s.Pts); i++ {
+		polys = append(polys, convexHull(append(corners(s.Pts[i-1]), corners(s.Pts[i])...)))
+	}
+	return polys
+}
+
+// convexHull returns the convex hull of pts, by the monotone chain, in
+// one winding direction whatever the order of pts.
+func convexHull(pts []f32.Point) []f32.Point {
+	slices.SortFunc(pts, func(p, q f32.Point) int {
+		if p.X != q.X {
+			return cmp.Compare(p.X, q.X)
+		}
+		return cmp.Compare(p.Y, q.Y)
+	})
+	cross := func(o, p, q f32.Point) float32 {
+		return (p.X-o.X)*(q.Y-o.Y) - (p.Y-o.Y)*(q.X-o.X)
+	}
+	hull := make([]f32.Point, 0, 2*len(pts))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range pts {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		// The last point is the first of the other chain.
+		hull = hull[:len(hull)-1]
+		slices.Reverse(pts)
+	}
+	return hull
+}
+
+// drawHighlight draws a highlighter stroke.
+func drawHighlight(ops *op.Ops, s *Stroke) {
+	var p clip.Path
+	p.Begin(ops)
+	for _, poly := range highlightPolygons(s) {
+		p.MoveTo(poly[0])
+		for _, q := range poly[1:] {
+			p.LineTo(q)
+		}
+		p.Close()
+	}
+	paint.FillShape(ops, s.Col, clip.Outline{Path: p.End()}.Op())
+}
+
+// rasterHighlight is the raster counterpart of drawHighlight, onto dst
+// within area.
+func rasterHighlight(dst *image.RGBA, area image.Rectangle, s *Stroke) {
+	mask := image.NewAlpha(area)
+	z := vector.NewRasterizer(area.Dx(), area.Dy())
+	o := f32.Pt(float32(area.Min.X), float32(area.Min.Y))
+	for _, poly := range highlightPolygons(s) {
+		z.MoveTo(poly[0].X-o.X, poly[0].Y-o.Y)
+		for _, q := range poly[1:] {
+			z.LineTo(q.X-o.X, q.Y-o.Y)
+		}
+		z.ClosePath()
+	}
+	z.Draw(mask, area, image.Opaque, image.Point{})
+	draw.DrawMask(dst, area, image.NewUniform(s.Col), area.Min, mask, area.Min, draw.Over)
+}
+
+// writeSVGHighlight writes a highlighter stroke as one filled path.
+func writeSVGHighlight(b *strings.Builder, s *Stroke) {
+	b.WriteString(`  <path d="`)
+	for i, poly := range highlightPolygons(s) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		for j, p := range poly {
+			cmd := "L"
+			if j == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(b, "%s%.1f,%.1f ", cmd, p.X, p.Y)
+		}
+		b.WriteByte('Z')
+	}
+	fmt.Fprintf(b, `" fill="#%02x%02x%02x" fill-opacity="%.3f" stroke="none"/>`+"\n",
+		s.Col.R, s.Col.G, s.Col.B, float32(s.Col.A)/255)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"path/filepath"
+
+	"gioui.org/f32"
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	xdraw "golang.org/x/image/draw"
+)
+
+// The export history (Ctrl+Shift+V) keeps the last few PNG exports of
+// the session, so that an earlier variant can be copied to the clipboard
+// again without drawing it again: a panel shows them as thumbnails and a
+// click copies one as an image. Gio's clipboard only carries text, so
+// the copy goes through platformCopyImage (clipboard_*.go). The history
+// lives in memory only, as encoded PNGs (for a screenshot, a few MB each)
+// and small thumbnails, at most maxExportHistory of them, and is gone
+// when the overlay quits.
+
+const (
+	maxExportHistory = 6
+	// exportThumbDp is the width of the thumbnails in the panel.
+	exportThumbDp = 160
+	// exportThumbPx is the width they are scaled to once, when the
+	// export is remembered.
+	exportThumbPx = 320
+)
+
+// exportedImage is one export in the history.
+type exportedImage struct {
+	name  string // base name of the file
+	png   []byte
+	thumb paint.ImageOp
+	size  image.Point // of the thumbnail
+}
+
+// rememberExport adds the PNG img, just written to path, to the history.
+func (a *Annotator) rememberExport(path string, img image.Image) {
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, img, nil); err != nil {
+		log.Printf("export history: %v", err)
+		return
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return
+	}
+	w := min(b.Dx(), exportThumbPx)
+	thumb := image.NewRGBA(image.Rect(0, 0, w, max(b.Dy()*w/b.Dx(), 1)))
+	xdraw.ApproxBiLinear.Scale(thumb, thumb.Bounds(), img, b, xdraw.Src, nil)
+	e := exportedImage{name: filepath.Base(path), png: buf.Bytes(), thumb: paint.NewImageOp(thumb), size: thumb.Bounds().Size()}
+	a.exportHistory = append([]exportedImage{e}, a.exportHistory...)
+	if len(a.exportHistory) > maxExportHistory {
+		a.exportHistory = a.exportHistory[:maxExportHistory]
+	}
+}
+
+// toggleHistory shows or hides the export history panel.
+func (a *Annotator) toggleHistory() {
+	if !a.historyOpen && len(a.exportHistory) == 0 {
+		a.notify("No PNG exported yet")
+		return
+	}
+	a.historyOpen = !a.historyOpen
+}
+
+// copyExport copies the i-th export of the history.
+func (a *Annotator) copyExport(i int) {
+	if i < 0 || i >= len(a.exportHistory) {
+		return
+	}
+	e := a.exportHistory[i]
+	img, err := png.Decode(bytes.NewReader(e.png))
+	if err == nil {
+		err = platformCopyImage(img)
+	}
+	if err != nil {
+		a.notifyErr(err)
+		return
+	}
+	a.notify("Copied %s", e.name)
+	a.sounds.play(cueExport)
+}
+
+// drawHistory shows the export history as a row of clickable
+// thumbnails, newest first, centered near the top of the window.
+func (a *Annotator) drawHistory(gtx layout.Context) {
+	for i := range a.exportHistory {
+		for {
+			ev, ok := gtx.Event(pointer.Filter{Target: &a.historyTags[i], Kinds: pointer.Press})
+			if !ok {
+				break
+			}
+			if ev.(pointer.Event).Kind == pointer.Press {
+				a.copyExport(i)
+				a.historyOpen = false
+				gtx.Execute(op.InvalidateCmd{})
+			}
+		}
+	}
+	if !a.historyOpen {
+		return
+	}
+	w, gap := gtx.Dp(exportThumbDp), gtx.Dp(8)
+	h := 0
+	for _, e := range a.exportHistory {
+		h = max(h, e.size.Y*w/e.size.X)
+	}
+	n := len(a.exportHistory)
+	panel := image.Rect(0, 0, n*w+(n+1)*gap, h+2*gap)
+	panel = panel.Add(image.Pt((gtx.Constraints.Max.X-panel.Dx())/2, gtx.Dp(24)))
+	paint.FillShape(gtx.Ops, labelBg, clip.UniformRRect(panel, gtx.Dp(6)).Op(gtx.Ops))
+	for i, e := range a.exportHistory {
+		r := image.Rect(0, 0, w, e.size.Y*w/e.size.X).Add(panel.Min.Add(image.Pt(gap+i*(w+gap), gap)))
+		area := clip.Rect(r).Push(gtx.Ops)
+		event.Op(gtx.Ops, &a.historyTags[i])
+		pointer.CursorPointer.Add(gtx.Ops)
+		area.Pop()
+		k := float32(w) / float32(e.size.X)
+		off := op.Offset(r.Min).Push(gtx.Ops)
+		scale := op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(k, k))).Push(gtx.Ops)
+		e.thumb.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		scale.Pop()
+		off.Pop()
+		paint.FillShape(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+			clip.Stroke{Path: clip.Rect(r).Path(), Width: float32(gtx.Dp(1))}.Op())
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+
+	"gioui.org/f32"
+)
+
+// Hollow strokes (Ctrl+U) draw only the boundary of the thick line, so
+// what they circle shows through the middle. The boundary is traced as
+// one closed path, down one side at the stroke's half width less half the
+// edge, around the round end cap and back up the other side, and that
+// path is what each renderer draws, with the edge width, in place of the
+// points: on screen, in PNGs and as an SVG polyline alike. Arrowheads and
+// shape fills stay as they are. Where a line turns more tightly than its
+// half width the inner side crosses itself, which leaves a small loop
+// inside the bend.
+
+// hollowEdge is the width of the boundary line of a hollow s.
+func hollowEdge(s *Stroke) float32 {
+	return max(2, s.Width/6)
+}
+
+// hollowStroke is what stands in for s when it is hollow: its boundary
+// as a closed stroke of the edge width, without a fill or heads.
+func hollowStroke(s *Stroke) Stroke {
+	edge := hollowEdge(s)
+	h := *s
+	h.Hollow, h.FillAlpha, h.Arrow, h.Widths, h.Shape, h.Join, h.Cap = false, 0, false, nil, "", joinRound, capRound
+	h.Width = edge
+	h.Pts = hollowOutline(s.Pts, func(i int) float32 {
+		return max(edge/2, s.widthAt(i)/2-edge/2)
+	}, edge/2)
+	return h
+}
+
+// hollowOutline traces around pts at radius(i) from point i, with points
+// about spacing apart on the caps.
+func hollowOutline(pts []f32.Point, radius func(i int) float32, spacing float32) []f32.Point {
+	n := len(pts)
+	if n == 0 {
+		return nil
+	}
+	// Normals (to the left of the direction of drawing) at every point,
+	// from the neighbors; repeated points take those of the points
+	// before them, or at the start after them.
+	normals := make([]f32.Point, n)
+	first := -1
+	for i := range pts {
+		d := pts[min(i+1, n-1)].Sub(pts[max(i-1, 0)])
+		if l := float32(math.Hypot(float64(d.X), float64(d.Y))); l > 0 {
+			normals[i] = f32.Pt(d.Y/l, -d.X/l)
+			if first < 0 {
+				first = i
+			}
+		} else if i > 0 {
+			normals[i] = normals[i-1]
+		}
+	}
+	if first < 0 {
+		first, normals[0] = 0, f32.Pt(0, -1)
+	}
+	for i := range normals {
+		if i < first || normals[i] == (f32.Point{}) {
+			normals[i] = normals[first]
+		}
+	}
+	out := make([]f32.Point, 0, 2*n+32)
+	// arc adds the points of the arc around point i from its normal nrm
+	// through the angle sweep, between its ends.
+	arc := func(i int, nrm f32.Point, sweep float64) {
+		r := radius(i)
+		steps := max(int(math.Ceil(float64(r)*math.Abs(sweep)/float64(spacing))), 2)
+		a0 := math.Atan2(float64(nrm.Y), float64(nrm.X))
+		for k := 1; k < steps; k++ {
+			a := a0 + sweep*float64(k)/float64(steps)
+			out = append(out, pts[i].Add(f32.Pt(float32(math.Cos(a)), float32(math.Sin(a))).Mul(r)))
+		}
+	}
+	if n == 1 {
+		// A dot is a ring.
+		out = append(out, pts[0].Add(normals[0].Mul(radius(0))))
+		arc(0, normals[0], 2*math.Pi)
+		return append(out, out[0])
+	}
+	for i := range pts {
+		out = append(out, pts[i].Add(normals[i].Mul(radius(i))))
+	}
+	// Screen y points down, so turning from the left normal through the
+	// direction of drawing to the right one is a positive angle.
+	arc(n-1, normals[n-1], math.Pi)
+	for i := n - 1; i >= 0; i-- {
+		out = append(out, pts[i].Sub(normals[i].Mul(radius(i))))
+	}
+	arc(0, normals[0].Mul(-1), math.Pi)
+	return append(out, out[0])
+}
+
+// toggleHollow switches new pen and arrow strokes between filled and
+// hollow.
+func (a *Annotator) toggleHollow() {
+	a.hollow = !a.hollow
+	if a.hollow {
+		a.notify("Strokes: hollow")
+	} else {
+		a.notify("Strokes: filled")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// Icon stamps (Ctrl+I) are Strokes with Icon set: a check, cross, star or
+// warning sign of size Width centered on the single point Pts[0], in the
+// pen color, one per click. Ctrl+I again, while the tool is on, goes on
+// to the next icon. The icons are a few filled polygons each, so the
+// screen, PNG and SVG draw them from the same points.
+
+var toolIcon = registerTool("icon", iconTool{})
+
+// iconNames are the icons in the order Ctrl+I goes through them.
+var iconNames = []string{"check", "cross", "star", "warning"}
+
+// iconPart is one polygon of an icon, in a box from -0.5 to 0.5 around
+// its center; mark parts are drawn in black or white, whichever stands
+// out on the pen color, as the numbers of step markers are.
+type iconPart struct {
+	pts  []f32.Point
+	mark bool
+}
+
+var iconShapes = map[string][]iconPart{
+	"check": {{pts: []f32.Point{
+		{X: -0.45, Y: 0.02}, {X: -0.33, Y: -0.1}, {X: -0.1, Y: 0.13},
+		{X: 0.33, Y: -0.35}, {X: 0.45, Y: -0.23}, {X: -0.1, Y: 0.37},
+	}}},
+	"cross": {{pts: rotated(plusPoints(0.6, 0.1), math.Pi/4)}},
+	"star":  {{pts: starPoints(5, 0.5, 0.2)}},
+	"warning": {
+		{pts: []f32.Point{{X: 0, Y: -0.46}, {X: 0.5, Y: 0.42}, {X: -0.5, Y: 0.42}}},
+		{pts: []f32.Point{{X: -0.05, Y: -0.18}, {X: 0.05, Y: -0.18}, {X: 0.035, Y: 0.16}, {X: -0.035, Y: 0.16}}, mark: true},
+		{pts: []f32.Point{{X: -0.05, Y: 0.22}, {X: 0.05, Y: 0.22}, {X: 0.05, Y: 0.32}, {X: -0.05, Y: 0.32}}, mark: true},
+	},
+}
+
+// plusPoints is a plus sign with arms l long from the center and t thick
+// on either side.
+func plusPoints(l, t float32) []f32.Point {
+	return []f32.Point{
+		{X: -t, Y: -l}, {X: t, Y: -l}, {X: t, Y: -t}, {X: l, Y: -t},
+		{X: l, Y: t}, {X: t, Y: t}, {X: t, Y: l}, {X: -t, Y: l},
+		{X: -t, Y: t}, {X: -l, Y: t}, {X: -l, Y: -t}, {X: -t, Y: -t},
+	}
+}
+
+// starPoints is a star of n points, its tips at radius outer (the first
+// one up) and the notches between them at inner.
+func starPoints(n int, outer, inner float32) []f32.Point {
+	pts := make([]f32.Point, 2*n)
+	for i := range pts {
+		r := outer
+		if i%2 == 1 {
+			r = inner
+		}
+		a := -math.Pi/2 + math.Pi*float64(i)/float64(n)
+		pts[i] = f32.Pt(r*float32(math.Cos(a)), r*float32(math.Sin(a)))
+	}
+	return pts
+}
+
+func rotated(pts []f32.Point, angle float64) []f32.Point {
+	sin, cos := math.Sincos(angle)
+	out := make([]f32.Point, len(pts))
+	for i, p := range pts {
+		out[i] = f32.Pt(p.X*float32(cos)-p.Y*float32(sin), p.X*float32(sin)+p.Y*float32(cos))
+	}
+	return out
+}
+
+// iconPolygons calls fn with every polygon of the icon s in window px,
+// and its color.
+func iconPolygons(s *Stroke, fn func(pts []f32.Point, col color.NRGBA)) {
+	c := s.Pts[0]
+	for _, part := range iconShapes[s.Icon] {
+		pts := make([]f32.Point, len(part.pts))
+		for i, p := range part.pts {
+			pts[i] = c.Add(p.Mul(s.Width))
+		}
+		col := s.Col
+		if part.mark {
+			col = stepTextColor(s.Col)
+			col.A = s.Col.A
+		}
+		fn(pts, col)
+	}
+}
+
+// placeIcon adds the current icon at p.
+func (a *Annotator) placeIcon(gtx layout.Context, p f32.Point) {
+	a.strokes = append(a.strokes, Stroke{
+		Pts:   []f32.Point{p},
+		Col:   a.col,
+		Width: dpToPx(gtx, stepDiameter(a.widthDp)),
+		At:    gtx.Now,
+		Icon:  iconNames[a.iconIdx],
+	})
+}
+
+// iconKey turns the icon tool on, or while it is on goes to the next
+// icon.
+func (a *Annotator) iconKey() {
+	if a.tool == toolIcon {
+		a.iconIdx = (a.iconIdx + 1) % len(iconNames)
+	}
+	a.tool = toolIcon
+	a.notify("Icon: %s", iconNames[a.iconIdx])
+}
+
+func (a *Annotator) drawIcon(gtx layout.Context, s *Stroke) {
+	iconPolygons(s, func(pts []f32.Point, col color.NRGBA) {
+		fillPolygon(gtx.Ops, pts, col)
+	})
+}
+
+// rasterIcon draws an icon onto dst the way drawIcon does on screen.
+func rasterIcon(dst *image.RGBA, s *Stroke) {
+	r := strokeBounds(s).Inset(-1).Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+	iconPolygons(s, func(pts []f32.Point, col color.NRGBA) {
+		mask := image.NewAlpha(r)
+		rasterPolygon(mask, pts)
+		draw.DrawMask(dst, r, image.NewUniform(col), image.Point{}, mask, r.Min, draw.Over)
+	})
+}
+
+// writeSVGIcon writes an icon as its filled polygons.
+func writeSVGIcon(b *strings.Builder, s *Stroke) {
+	iconPolygons(s, func(pts []f32.Point, col color.NRGBA) {
+		b.WriteString(`  <polygon points="`)
+		for j, p := range pts {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(b, "%.1f,%.1f", p.X, p.Y)
+		}
+		fmt.Fprintf(b, `" fill="#%02x%02x%02x" fill-opacity="%.3f"/>`+"\n", col.R, col.G, col.B, float32(col.A)/255)
+	})
+}
+
+// iconTool places the current icon per click.
+type iconTool struct{}
+
+func (iconTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.placeIcon(gtx, pe.Position)
+}
+
+func (iconTool) Drag(*Annotator, layout.Context, pointer.Event)    {}
+func (iconTool) Release(*Annotator, layout.Context, pointer.Event) {}
+func (iconTool) Render(*Annotator, layout.Context)                 {}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Idle clear (-idle-clear) wipes the strokes once nobody has used the
+// overlay for a while, so a shared or demo machine does not keep the last
+// visitor's annotations on screen. Unlike quitting, the overlay stays up
+// for the next one. Pointer and key input over the overlay count as use.
+
+// idleClear clears the strokes when the interval has passed since the last
+// input, and otherwise makes sure a frame comes when it will have.
+func (a *Annotator) idleClear(gtx layout.Context) {
+	if a.idleClearAfter <= 0 {
+		return
+	}
+	if a.activeAt.IsZero() {
+		a.activeAt = gtx.Now
+	}
+	next := a.activeAt.Add(a.idleClearAfter)
+	if gtx.Now.Before(next) {
+		if len(a.strokes) > 0 {
+			gtx.Execute(op.InvalidateCmd{At: next})
+		}
+		return
+	}
+	if len(a.strokes) == 0 || a.cur != nil || a.placing != nil {
+		return
+	}
+	log.Printf("idle for %v; cleared %d strokes", a.idleClearAfter, len(a.strokes))
+	a.strokes = nil
+	a.sel = -1
+	a.dropHistory()
+	a.activeAt = gtx.Now
+}
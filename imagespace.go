@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"gioui.org/f32"
+	xdraw "golang.org/x/image/draw"
+)
+
+// Image space (-background-resolution) is for annotating a screenshot
+// whose pixels are not the window's, such as one taken on a HiDPI screen
+// at another scale: the declared resolution is the image's own pixel
+// grid, and the coordinate readout, exports and sessions use it instead
+// of window px. Strokes are still drawn and kept in window px; they are
+// mapped through where the image is shown (bgRect) on the way out, and
+// sessions recorded in image space are mapped back on the way in, so the
+// annotations land on the same image pixels whatever the display scale
+// and fit mode.
+
+// parseResolution parses a WxH size, such as 3840x2160.
+func parseResolution(s string) (image.Point, error) {
+	var p image.Point
+	if _, err := fmt.Sscanf(s, "%dx%d", &p.X, &p.Y); err != nil || p.X <= 0 || p.Y <= 0 {
+		return image.Point{}, fmt.Errorf("bad resolution %q (want WxH, e.g. 3840x2160)", s)
+	}
+	return p, nil
+}
+
+// inImageSpace reports whether coordinates go out in image px.
+func (a *Annotator) inImageSpace() bool {
+	return a.bgRes != (image.Point{}) && a.bgSrc != nil && !a.bgRect.Empty()
+}
+
+// imageScale is how many image px one window px is, per axis.
+func (a *Annotator) imageScale() f32.Point {
+	return f32.Pt(float32(a.bgRes.X)/float32(a.bgRect.Dx()), float32(a.bgRes.Y)/float32(a.bgRect.Dy()))
+}
+
+// toImage maps a window point into image px.
+func (a *Annotator) toImage(p f32.Point) f32.Point {
+	s := a.imageScale()
+	p = p.Sub(f32.Pt(float32(a.bgRect.Min.X), float32(a.bgRect.Min.Y)))
+	return f32.Pt(p.X*s.X, p.Y*s.Y)
+}
+
+// fromImage maps a point in image px into the window.
+func (a *Annotator) fromImage(p f32.Point) f32.Point {
+	s := a.imageScale()
+	return f32.Pt(p.X/s.X+float32(a.bgRect.Min.X), p.Y/s.Y+float32(a.bgRect.Min.Y))
+}
+
+// strokesToImage returns copies of strokes in image px; widths scale by
+// the smaller factor, as in scaleStrokes.
+func (a *Annotator) strokesToImage(strokes []Stroke) []Stroke {
+	s := a.imageScale()
+	out := make([]Stroke, len(strokes))
+	for i, st := range strokes {
+		st = cloneStroke(st)
+		mapImageStroke(&st, a.toImage, s)
+		out[i] = st
+	}
+	return out
+}
+
+// fitStrokes moves strokes recorded on canvas into the window: through
+// the image when they were recorded in its space, else by scaleStrokes.
+func (a *Annotator) fitStrokes(strokes []Stroke, canvas image.Point) bool {
+	if !a.inImageSpace() || canvas != a.bgRes {
+		return scaleStrokes(strokes, canvas, a.size)
+	}
+	s := a.imageScale()
+	for i := range strokes {
+		mapImageStroke(&strokes[i], a.fromImage, f32.Pt(1/s.X, 1/s.Y))
+	}
+	return true
+}
+
+// mapImageStroke maps the points of st with m, scaling widths and fill
+// masks by s.
+func mapImageStroke(st *Stroke, m func(f32.Point) f32.Point, s f32.Point) {
+	k := min(s.X, s.Y)
+	for j, p := range st.Pts {
+		st.Pts[j] = m(p)
+	}
+	st.Width *= k
+	for j := range st.Widths {
+		st.Widths[j] *= k
+	}
+	if st.Fill != nil {
+		st.Fill = scaleFillMask(st.Fill, s.X, s.Y)
+	}
+}
+
+// imageCanvas is the background at its declared resolution, for exports.
+func (a *Annotator) imageCanvas() *image.RGBA {
+	dst := image.NewRGBA(image.Rectangle{Max: a.bgRes})
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), a.bgSrc, a.bgSrc.Bounds(), xdraw.Src, nil)
+	return dst
+}
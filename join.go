@@ -0,0 +1,23 @@
+package main
+
+import (
+	"gioui.org/f32"
+)
+
+// joinStart returns where a new freehand stroke pressed at p should begin:
+// exactly at the end of the previous stroke if p is within radius of it,
+// so that multi-part drawings connect without a visible gap.
+func (a *Annotator) joinStart(p f32.Point, radius float32) f32.Point {
+	if len(a.strokes) == 0 {
+		return p
+	}
+	last := &a.strokes[len(a.strokes)-1]
+	if last.Text != "" || last.Fill != nil || len(last.Pts) == 0 {
+		return p
+	}
+	end := last.Pts[len(last.Pts)-1]
+	if dist(p, end) > radius {
+		return p
+	}
+	return end
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// labelBg is the backing box behind on-screen labels, so they stay
+// readable over any desktop content.
+var labelBg = color.NRGBA{A: 0xb0}
+
+// theme lazily builds the text theme; shaping the Go fonts is only paid
+// for once something actually needs to show text.
+func (a *Annotator) theme() *material.Theme {
+	if a.th == nil {
+		a.th = material.NewTheme()
+		a.th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+	}
+	return a.th
+}
+
+// drawLabel draws txt on a translucent box with its top-left corner at
+// pos (window px) and returns the size of the box.
+func (a *Annotator) drawLabel(gtx layout.Context, pos image.Point, txt string, fg color.NRGBA) image.Point {
+	lbl := material.Label(a.theme(), unit.Sp(16), txt)
+	lbl.Color = fg
+
+	gtx.Constraints.Min = image.Point{}
+	macro := op.Record(gtx.Ops)
+	dims := lbl.Layout(gtx)
+	call := macro.Stop()
+
+	pad := gtx.Dp(6)
+	box := image.Rect(0, 0, dims.Size.X+2*pad, dims.Size.Y+2*pad)
+	defer op.Offset(pos).Push(gtx.Ops).Pop()
+	paint.FillShape(gtx.Ops, labelBg, clip.UniformRRect(box, gtx.Dp(4)).Op(gtx.Ops))
+	off := op.Offset(image.Pt(pad, pad)).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	off.Pop()
+	return box.Max
+}
+
+// rasterLabelSize is the size of the box rasterLabel draws for txt.
+func rasterLabelSize(txt string) image.Point {
+	face, err := goFace(16)
+	if err != nil {
+		return image.Point{}
+	}
+	defer face.Close()
+	const pad = 6
+	return image.Pt(font.MeasureString(face, txt).Ceil()+2*pad, face.Metrics().Height.Ceil()+2*pad)
+}
+
+// rasterLabel draws txt onto dst the way drawLabel does on screen: white
+// on a translucent box with its top-left corner at pos. It returns the
+// size of the box.
+func rasterLabel(dst *image.RGBA, pos image.Point, txt string) image.Point {
+	face, err := goFace(16)
+	if err != nil {
+		return image.Point{}
+	}
+	defer face.Close()
+	const pad = 6
+	m := face.Metrics()
+	w := font.MeasureString(face, txt).Ceil()
+	box := image.Rect(0, 0, w+2*pad, m.Height.Ceil()+2*pad).Add(pos)
+	draw.Draw(dst, box, image.NewUniform(labelBg), image.Point{}, draw.Over)
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+		Dot:  fixed.P(box.Min.X+pad, box.Min.Y+pad).Add(fixed.Point26_6{Y: m.Ascent}),
+	}
+	d.DrawString(txt)
+	return box.Size()
+}
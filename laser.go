@@ -0,0 +1,136 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The laser pointer (Shift+L) draws nothing that stays: the pointer is a
+// bright dot in the pen color, and where it went, moving or dragging, a
+// trail that thins and fades out over laserFade. Dimming (A) around it
+// makes it a spotlight that follows the hand. The trail keeps only the
+// points of the last laserFade, so it stays short however long the
+// pointer moves.
+//
+// The trail is smoothed by the speed of the pointer (-laser-smooth),
+// apart from the drawing's own smoothing: each point goes from the last
+// one a fraction d/(d+scale) of the way to the pointer, d being how far
+// the pointer is, so the few pixels of a hand's tremor are mostly taken
+// out while a sweep across the screen is followed almost at once. When
+// the pointer stops, the dot catches up with it frame by frame.
+
+var toolLaser = registerTool("laser", laserTool{})
+
+// laserFade is how long a point of the trail takes to fade out.
+const laserFade = time.Second
+
+// laserPoint is a point of the trail and when the pointer was there.
+type laserPoint struct {
+	p  f32.Point
+	at time.Time
+}
+
+// pushLaser adds the pointer at p to the trail, smoothed, dropping the
+// points that have faded.
+func (a *Annotator) pushLaser(gtx layout.Context, p f32.Point) {
+	a.pruneLaser(gtx.Now)
+	if n := len(a.laser); n > 0 {
+		p = laserSmooth(a.laser[n-1].p, p, dpToPx(gtx, a.laserSmoothDp))
+	}
+	a.laser = append(a.laser, laserPoint{p: p, at: gtx.Now})
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// laserSmooth returns the point that follows from last toward p at the
+// smoothing scale: the further p is, the closer it is followed.
+func laserSmooth(last, p f32.Point, scale float32) f32.Point {
+	d := dist(last, p)
+	if scale <= 0 || d == 0 {
+		return p
+	}
+	return last.Add(p.Sub(last).Mul(d / (d + scale)))
+}
+
+// laserDot is where the dot is drawn: the head of the trail, or the
+// pointer once the trail has faded.
+func (a *Annotator) laserDot() f32.Point {
+	if n := len(a.laser); n > 0 {
+		return a.laser[n-1].p
+	}
+	return a.ptr
+}
+
+// pruneLaser drops the points older than laserFade.
+func (a *Annotator) pruneLaser(now time.Time) {
+	i := 0
+	for i < len(a.laser) && now.Sub(a.laser[i].at) >= laserFade {
+		i++
+	}
+	if i == len(a.laser) {
+		a.laser = a.laser[:0]
+		return
+	}
+	a.laser = a.laser[:copy(a.laser, a.laser[i:])]
+}
+
+// laserTool points rather than draws; handlePointer passes it the moves
+// too.
+type laserTool struct{}
+
+func (laserTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.pushLaser(gtx, pe.Position)
+}
+
+func (laserTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.pushLaser(gtx, pe.Position)
+}
+
+func (laserTool) Release(*Annotator, layout.Context, pointer.Event) {}
+
+// Render draws the trail, each segment as faint and thin as its older
+// end is old, and the dot at the pointer.
+func (laserTool) Render(a *Annotator, gtx layout.Context) {
+	a.pruneLaser(gtx.Now)
+	r := max(dpToPx(gtx, a.widthDp), float32(gtx.Dp(4)))
+	for i := 1; i < len(a.laser); i++ {
+		f := 1 - float32(gtx.Now.Sub(a.laser[i-1].at))/float32(laserFade)
+		col := a.col
+		col.A = uint8(float32(col.A) * f)
+		var path clip.Path
+		path.Begin(gtx.Ops)
+		path.MoveTo(a.laser[i-1].p)
+		path.LineTo(a.laser[i].p)
+		paint.FillShape(gtx.Ops, col, clip.Stroke{Path: path.End(), Width: r * f}.Op())
+	}
+	if len(a.laser) > 0 {
+		// Until the trail has faded.
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	if !a.ptrIn {
+		return
+	}
+	if dist(a.laserDot(), a.ptr) > 0.5 {
+		// Catch up with a pointer that stopped.
+		a.pushLaser(gtx, a.ptr)
+	}
+	dot := a.laserDot()
+	for _, o := range []struct {
+		col color.NRGBA
+		r   float32
+	}{
+		{color.NRGBA{R: a.col.R, G: a.col.G, B: a.col.B, A: 0x60}, r * 1.5},
+		{color.NRGBA{R: a.col.R, G: a.col.G, B: a.col.B, A: 0xff}, r * 0.75},
+		{color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, r * 0.3},
+	} {
+		box := image.Rectangle{Min: dot.Sub(f32.Pt(o.r, o.r)).Round(), Max: dot.Add(f32.Pt(o.r, o.r)).Round()}
+		paint.FillShape(gtx.Ops, o.col, clip.Ellipse(box).Op(gtx.Ops))
+	}
+}
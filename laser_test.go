@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"gioui.org/f32"
+)
+
+// TestLaserSmooth checks that small moves are damped more than large
+// ones, and that no scale leaves the pointer as it is.
+func TestLaserSmooth(t *testing.T) {
+	last := f32.Pt(0, 0)
+	for _, tc := range []struct {
+		p     f32.Point
+		scale float32
+		want  f32.Point
+	}{
+		{p: f32.Pt(2, 0), scale: 6, want: f32.Pt(0.5, 0)},
+		{p: f32.Pt(0, 60), scale: 6, want: f32.Pt(0, 600.0/11)},
+		{p: f32.Pt(3, 4), scale: 0, want: f32.Pt(3, 4)},
+		{p: f32.Pt(0, 0), scale: 6, want: f32.Pt(0, 0)},
+	} {
+		if got := laserSmooth(last, tc.p, tc.scale); dist(got, tc.want) > 1e-4 {
+			t.Errorf("laserSmooth(%v, %v, %g) = %v, want %v", last, tc.p, tc.scale, got, tc.want)
+		}
+	}
+}
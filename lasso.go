@@ -0,0 +1,56 @@
+package main
+
+import (
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// The lasso tool (Ctrl+J) circles and shades irregular areas, which the
+// rectangles and ellipses of shape recognition (N) cannot: the freehand
+// loop is closed back to its start on release and filled in the pen
+// color, at the shape fill opacity (Ctrl+H) or 25% while that is none;
+// Shift at the press leaves it unfilled. Like recognized shapes, the
+// closed loop itself is what makes a stroke fillable (Stroke.FillAlpha).
+
+var toolLasso = registerTool("lasso", lassoTool{})
+
+type lassoTool struct{}
+
+func (lassoTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.cur = a.newStroke(gtx, pe.Position)
+	a.cur.Widths = nil
+	a.cur.Col.A = uint8(int(a.cur.Col.A) * int(a.shapeOutline) / 0xff)
+	if !pe.Modifiers.Contain(key.ModShift) {
+		// Set from the start, so the fill shows while drawing.
+		a.cur.FillAlpha = a.shapeFill
+		if a.cur.FillAlpha == 0 {
+			a.cur.FillAlpha = shapeFillSteps[1]
+		}
+	}
+	a.dragTime = pe.Time
+	a.predictor = predictor{}
+	a.predictor.add(pe.Position, pe.Time, gtx.Now)
+}
+
+func (lassoTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	penTool{}.Drag(a, gtx, pe)
+}
+
+func (lassoTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	s := a.cur
+	a.cur = nil
+	if s == nil || len(s.Pts) < 3 || pathLength(s.Pts) < 3*s.Width {
+		// Too small to enclose anything.
+		return
+	}
+	appendInterpolated(&s.Pts, s.Pts[len(s.Pts)-1], s.Pts[0], s.Width/2)
+	a.autoHalo(s)
+	a.strokes = append(a.strokes, *s)
+}
+
+func (lassoTool) Render(a *Annotator, gtx layout.Context) {
+	if a.cur != nil {
+		a.paintStroke(gtx, a.predicted(gtx))
+	}
+}
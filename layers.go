@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Layer export writes every stroke to a transparent PNG of its own, all
+// of the canvas size so they line up when stacked in a design tool, with
+// an index.json listing them bottom to top. The step connectors form one
+// layer below the strokes, and the background and the pinned notes get
+// layers of their own when they would be in a PNG export.
+
+// layerIndex is the index.json of a layer export.
+type layerIndex struct {
+	Width      int         `json:"width"`
+	Height     int         `json:"height"`
+	Background string      `json:"background,omitempty"`
+	Layers     []layerJSON `json:"layers"`
+}
+
+type layerJSON struct {
+	File string `json:"file"`
+	// Stroke is the 1-based stroke number, 0 for the layers that are
+	// not a single stroke.
+	Stroke int    `json:"stroke,omitempty"`
+	Kind   string `json:"kind"`
+	// Bounds is the painted area, as x0, y0, x1, y1 in canvas pixels.
+	Bounds [4]int `json:"bounds"`
+}
+
+// exportLayers writes the layers and their index into dir, creating it.
+func (a *Annotator) exportLayers(dir string) error {
+	if len(a.strokes) == 0 {
+		return fmt.Errorf("layers: nothing drawn")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	canvas := image.Rectangle{Max: a.size}
+	if a.bg != nil {
+		canvas = image.Rectangle{Max: a.bg.Bounds().Size()}
+	}
+	idx := layerIndex{Width: canvas.Dx(), Height: canvas.Dy()}
+	meta := a.pngMetadata(time.Now())
+	write := func(name string, img *image.RGBA) error {
+		return writePNG(filepath.Join(dir, name), img, meta...)
+	}
+	// Pixelate strokes show the background through them, as in a PNG
+	// export without the strokes below.
+	under := newCanvas(a.bg, a.size)
+	if a.bg != nil {
+		idx.Background = "background.png"
+		if err := write(idx.Background, under); err != nil {
+			return err
+		}
+	}
+	add := func(name string, stroke int, kind string, draw func(img *image.RGBA)) error {
+		img := image.NewRGBA(canvas)
+		draw(img)
+		r := opaqueBounds(img)
+		idx.Layers = append(idx.Layers, layerJSON{File: name, Stroke: stroke, Kind: kind, Bounds: [4]int{r.Min.X, r.Min.Y, r.Max.X, r.Max.Y}})
+		return write(name, img)
+	}
+	if a.connectSteps {
+		if conn := stepConnectors(a.strokes, nil); len(conn) > 0 {
+			if err := add("connectors.png", 0, "connectors", func(img *image.RGBA) { rasterStrokes(img, conn) }); err != nil {
+				return err
+			}
+		}
+	}
+	strokes := smoothStrokes(a.strokes, a.exportOpts.smooth)
+	for i := range strokes {
+		s := &strokes[i]
+		kind := strokeKind(s)
+		name := fmt.Sprintf("%03d-%s.png", i+1, strings.ReplaceAll(kind, " ", "-"))
+		err := add(name, i+1, kind, func(img *image.RGBA) {
+			if s.Pixelate {
+				rasterStrokeOver(img, under, s)
+			} else {
+				rasterStrokes(img, strokes[i:i+1])
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if a.pinExport && len(a.pins) > 0 {
+		if err := add("pins.png", 0, "pins", a.rasterPins); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "index.json"), data, 0o644)
+}
+
+// opaqueBounds is the smallest rectangle holding the painted pixels of
+// img.
+func opaqueBounds(img *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[(y-b.Min.Y)*img.Stride:]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if row[(x-b.Min.X)*4+3] != 0 {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"math"
+	"slices"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+)
+
+// Line styles: the corners (joins) of new lines can be round, mitered or
+// beveled (Ctrl+Shift+J, -join) and their ends (caps) round, square or
+// flat (Ctrl+Shift+K, -cap). Round suits handwriting; mitered corners and
+// flat or square ends suit technical lines. Each stroke keeps the style
+// it was drawn with (Stroke.Join, Stroke.Cap), in sessions and in SVG as
+// stroke-linejoin and stroke-linecap, so exports match the screen.
+//
+// clip.Stroke only strokes round, so a line of another style is filled
+// as polygons instead, on screen and in PNGs alike: a quad per segment,
+// square ends lengthened by half the width, and at each corner a disc, a
+// miter or a bevel on the outer side. Such lines follow their points
+// straight rather than along the curve of smoothPath. Mitered corners
+// sharper than miterLimit are beveled, as SVG does by default.
+// Dynamic-width, chalk, hollow and highlighter strokes, arrowheads and
+// the exact rectangles and ellipses (shape.go) keep their own look.
+
+// lineJoin is the shape of the corners of a line.
+type lineJoin uint8
+
+const (
+	joinRound lineJoin = iota
+	joinMiter
+	joinBevel
+)
+
+var lineJoinNames = [...]string{
+	joinRound: "round",
+	joinMiter: "miter",
+	joinBevel: "bevel",
+}
+
+func (j lineJoin) String() string { return lineJoinNames[j] }
+
+// lineCap is the shape of the ends of a line.
+type lineCap uint8
+
+const (
+	capRound lineCap = iota
+	capSquare
+	capFlat
+)
+
+var lineCapNames = [...]string{
+	capRound:  "round",
+	capSquare: "square",
+	capFlat:   "flat",
+}
+
+func (c lineCap) String() string { return lineCapNames[c] }
+
+// svg is the stroke-linecap value of c; SVG calls flat butt.
+func (c lineCap) svg() string {
+	if c == capFlat {
+		return "butt"
+	}
+	return c.String()
+}
+
+// parseLineJoin is the inverse of String; "" is round.
+func parseLineJoin(name string) (lineJoin, bool) {
+	if name == "" {
+		return joinRound, true
+	}
+	for j, n := range lineJoinNames {
+		if n == name {
+			return lineJoin(j), true
+		}
+	}
+	return 0, false
+}
+
+// parseLineCap is the inverse of String, also taking SVG's butt; "" is
+// round.
+func parseLineCap(name string) (lineCap, bool) {
+	switch name {
+	case "":
+		return capRound, true
+	case "butt":
+		return capFlat, true
+	}
+	for c, n := range lineCapNames {
+		if n == name {
+			return lineCap(c), true
+		}
+	}
+	return 0, false
+}
+
+// miterLimit is the longest a miter may be, in widths, as SVG's default
+// stroke-miterlimit.
+const miterLimit = 4
+
+// cycleJoin steps the joins of new lines through round, miter and bevel.
+func (a *Annotator) cycleJoin() {
+	a.penJoin = (a.penJoin + 1) % lineJoin(len(lineJoinNames))
+	a.notify("Line joins: %v", a.penJoin)
+}
+
+// cycleCap steps the caps of new lines through round, square and flat.
+func (a *Annotator) cycleCap() {
+	a.penCap = (a.penCap + 1) % lineCap(len(lineCapNames))
+	a.notify("Line caps: %v", a.penCap)
+}
+
+// styledLine reports whether s is a line drawn as the polygons of
+// linePolygons rather than stroked round.
+func (s *Stroke) styledLine() bool {
+	return (s.Join != joinRound || s.Cap != capRound) && s.Widths == nil && !s.Chalk && len(s.Pts) > 0
+}
+
+// drawStyledLine draws a line of s's join and cap style.
+func drawStyledLine(ops *op.Ops, s *Stroke) {
+	fillPolygons(ops, linePolygons(s.Pts, max(2, s.Width), s.Join, s.Cap), s.Col)
+}
+
+// linePolygons covers the line along pts of the given width, joins and
+// caps, as polygons all wound the same way, for filling by the nonzero
+// rule. A line that ends where it starts is closed, joined there rather
+// than capped.
+func linePolygons(pts []f32.Point, width float32, join lineJoin, cp lineCap) [][]f32.Point {
+	hw := width / 2
+	// Repeated points have no direction.
+	pts = slices.CompactFunc(slices.Clone(pts), func(p, q f32.Point) bool { return dist(p, q) < 0.01 })
+	if len(pts) == 1 {
+		p := pts[0]
+		if cp == capRound {
+			return [][]f32.Point{shapePolygon("ellipse", p.Sub(f32.Pt(hw, hw)), p.Add(f32.Pt(hw, hw)))}
+		}
+		// A dot with flat ends would not show; it is a square.
+		return [][]f32.Point{shapePolygon("rectangle", p.Sub(f32.Pt(hw, hw)), p.Add(f32.Pt(hw, hw)))}
+	}
+	closed := len(pts) > 3 && pts[0] == pts[len(pts)-1]
+	unit := func(d f32.Point) f32.Point {
+		return d.Mul(1 / float32(math.Hypot(float64(d.X), float64(d.Y))))
+	}
+	var polys [][]f32.Point
+	add := func(poly ...f32.Point) {
+		polys = append(polys, woundClockwise(poly))
+	}
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		u := unit(b.Sub(a))
+		n := f32.Pt(-u.Y, u.X).Mul(hw)
+		if !closed && cp == capSquare {
+			if i == 1 {
+				a = a.Sub(u.Mul(hw))
+			}
+			if i == len(pts)-1 {
+				b = b.Add(u.Mul(hw))
+			}
+		}
+		add(a.Add(n), b.Add(n), b.Sub(n), a.Sub(n))
+	}
+	if !closed && cp == capRound {
+		for _, p := range []f32.Point{pts[0], pts[len(pts)-1]} {
+			add(shapePolygon("ellipse", p.Sub(f32.Pt(hw, hw)), p.Add(f32.Pt(hw, hw)))...)
+		}
+	}
+	// The corners, each between the segment into a point and the one out.
+	corner := func(prev, p, next f32.Point) {
+		u0, u1 := unit(p.Sub(prev)), unit(next.Sub(p))
+		if abs32(u0.X*u1.Y-u0.Y*u1.X) < 1e-3 && u0.X*u1.X+u0.Y*u1.Y > 0 {
+			// Straight on, near enough.
+			return
+		}
+		if join == joinRound {
+			add(shapePolygon("ellipse", p.Sub(f32.Pt(hw, hw)), p.Add(f32.Pt(hw, hw)))...)
+			return
+		}
+		n0, n1 := f32.Pt(-u0.Y, u0.X).Mul(hw), f32.Pt(-u1.Y, u1.X).Mul(hw)
+		if n0.X*u1.X+n0.Y*u1.Y > 0 {
+			// The line turns toward n0: the outer side is the other.
+			n0, n1 = n0.Mul(-1), n1.Mul(-1)
+		}
+		sum := n0.Add(n1)
+		l2 := sum.X*sum.X + sum.Y*sum.Y
+		if join == joinMiter && l2 > 0 && 2*hw/float32(math.Sqrt(float64(l2))) <= miterLimit {
+			add(p, p.Add(n0), p.Add(sum.Mul(2*hw*hw/l2)), p.Add(n1))
+			return
+		}
+		add(p, p.Add(n0), p.Add(n1))
+	}
+	for i := 1; i < len(pts)-1; i++ {
+		corner(pts[i-1], pts[i], pts[i+1])
+	}
+	if closed {
+		corner(pts[len(pts)-2], pts[0], pts[1])
+	}
+	return polys
+}
+
+// woundClockwise returns poly, reversed if it is wound counterclockwise
+// on screen, so that the polygons of a line add up under nonzero filling
+// rather than cancel out.
+func woundClockwise(poly []f32.Point) []f32.Point {
+	var area float32
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	if area < 0 {
+		slices.Reverse(poly)
+	}
+	return poly
+}
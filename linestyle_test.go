@@ -0,0 +1,63 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"gioui.org/f32"
+)
+
+// TestRasterLineStyles checks the ends and corners of an L-shaped line,
+// from (10,30) to (30,30) up to (30,10), 8px wide.
+func TestRasterLineStyles(t *testing.T) {
+	pts := []f32.Point{{X: 10, Y: 30}, {X: 30, Y: 30}, {X: 30, Y: 10}}
+	for _, tc := range []struct {
+		name    string
+		join    lineJoin
+		cp      lineCap
+		in, out []image.Point // pixels covered, and not
+	}{
+		// The end goes half the width past the last point, the corner's
+		// outer square is filled.
+		{name: "miter square", join: joinMiter, cp: capSquare, in: []image.Point{{7, 30}, {33, 33}, {30, 7}}, out: []image.Point{{4, 30}, {36, 36}}},
+		{name: "bevel flat", join: joinBevel, cp: capFlat, in: []image.Point{{11, 30}, {31, 31}}, out: []image.Point{{8, 30}, {33, 33}, {30, 8}}},
+		{name: "round flat", join: joinRound, cp: capFlat, in: []image.Point{{11, 30}, {32, 32}}, out: []image.Point{{8, 30}, {34, 34}}},
+	} {
+		s := Stroke{Pts: pts, Col: color.NRGBA{R: 0xff, A: 0xff}, Width: 8, Join: tc.join, Cap: tc.cp}
+		dst := image.NewRGBA(image.Rect(0, 0, 48, 48))
+		rasterStrokes(dst, []Stroke{s})
+		for _, p := range tc.in {
+			if a := dst.RGBAAt(p.X, p.Y).A; a < 0x80 {
+				t.Errorf("%s: %v not covered (alpha %#x)", tc.name, p, a)
+			}
+		}
+		for _, p := range tc.out {
+			if a := dst.RGBAAt(p.X, p.Y).A; a != 0 {
+				t.Errorf("%s: %v covered (alpha %#x)", tc.name, p, a)
+			}
+		}
+	}
+}
+
+// TestLineStyleRoundTrip checks that sessions and SVG keep the caps and
+// joins of a stroke.
+func TestLineStyleRoundTrip(t *testing.T) {
+	s := Stroke{Pts: []f32.Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}}, Col: color.NRGBA{A: 0xff}, Width: 4, Join: joinBevel, Cap: capFlat}
+	got, err := strokeToJSON(s).stroke()
+	if err != nil || got.Join != s.Join || got.Cap != s.Cap {
+		t.Errorf("session: got %v %v, %v; want %v %v", got.Join, got.Cap, err, s.Join, s.Cap)
+	}
+	svg := strokesSVG([]Stroke{s}, image.Pt(20, 20))
+	if !strings.Contains(svg, `stroke-linecap="butt" stroke-linejoin="bevel"`) {
+		t.Errorf("svg: no butt caps and bevel joins in %s", svg)
+	}
+	loaded, err := parseSVG(strings.NewReader(svg), "export")
+	if err != nil || len(loaded) != 1 || loaded[0].Join != s.Join || loaded[0].Cap != s.Cap {
+		t.Errorf("svg: loaded %+v, %v", loaded, err)
+	}
+	if _, err := (strokeJSON{Points: [][2]float32{{0, 0}}, Color: "000000", Width: 1, Cap: "pointy"}).stroke(); err == nil {
+		t.Error("unknown cap: no error")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Live mode (-live) recaptures the background periodically, so that the
+// redaction pen and exports follow a playing video or a changing window
+// while strokes stay where they are on the screen. Every refresh hides
+// the overlay for a moment and re-pixelates the whole capture, which is
+// why the rate is kept low and capped at maxLiveFPS, and why it pauses
+// while the overlay is out of sight (focus.go).
+
+const maxLiveFPS = 5
+
+// liveInterval converts -live frames per second to the refresh interval,
+// 0 for off.
+func liveInterval(fps float64) time.Duration {
+	if fps <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / min(fps, maxLiveFPS))
+}
+
+// liveRefresh starts the next periodic capture when it is due, and makes
+// sure a frame comes then.
+func (a *Annotator) liveRefresh(gtx layout.Context) {
+	if a.liveEvery <= 0 || a.bgSrc != nil || a.frozen || a.paused() {
+		return
+	}
+	next := a.liveAt.Add(a.liveEvery)
+	if !a.capturing && !gtx.Now.Before(next) {
+		a.liveAt = gtx.Now
+		a.capture(0, true)
+		next = gtx.Now.Add(a.liveEvery)
+	}
+	gtx.Execute(op.InvalidateCmd{At: next})
+}
+
+// toggleFreeze stops the live background at the current moment, e.g. a
+// popup that is about to go away, to annotate it at leisure; the capture
+// taken now (or the one in flight) is the last until it is toggled again.
+func (a *Annotator) toggleFreeze() {
+	if a.liveEvery <= 0 || a.bgSrc != nil {
+		a.notify("Freeze needs a -live background")
+		return
+	}
+	a.frozen = !a.frozen
+	if !a.frozen {
+		a.notify("Background: live")
+		return
+	}
+	a.capture(0, true)
+	a.notify("Background: frozen")
+}
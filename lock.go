@@ -0,0 +1,62 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Locking (Ctrl+Shift+L) protects finished parts of a drawing while more
+// is added: a locked stroke cannot be deleted, resized with the handles,
+// given another width or relabeled, and clearing the markup (Shift+C)
+// keeps it. Clearing everything (C) still removes it, as it removes
+// redactions. It can still be selected, pulsed and reordered; the
+// selection box then carries a small padlock. Stroke.Locked is kept in
+// sessions.
+
+// toggleLock locks or unlocks the selected stroke, or the last one. The
+// key runs it as an edit, for undo to lock or unlock it again.
+func (a *Annotator) toggleLock() bool {
+	s := a.editTarget()
+	if s == nil {
+		a.notify("Nothing to lock")
+		return false
+	}
+	s.Locked = !s.Locked
+	if s.Locked {
+		a.notify("Locked %s", strokeKind(s))
+	} else {
+		a.notify("Unlocked %s", strokeKind(s))
+	}
+	return true
+}
+
+// checkUnlocked reports whether s may be edited, and says why not.
+func (a *Annotator) checkUnlocked(s *Stroke) bool {
+	if s.Locked {
+		a.notify("Locked; Ctrl+Shift+L unlocks it")
+		return false
+	}
+	return true
+}
+
+// drawLock draws a padlock just outside the top-right corner of box.
+func drawLock(gtx layout.Context, box image.Rectangle) {
+	u := gtx.Dp(3)
+	body := image.Rect(box.Max.X+u, box.Min.Y-3*u, box.Max.X+5*u, box.Min.Y)
+	shackle := image.Rect(body.Min.X+u/2, body.Min.Y-2*u, body.Max.X-u/2, body.Min.Y+2*u)
+	for _, o := range []struct {
+		col   color.NRGBA
+		width int
+	}{
+		{color.NRGBA{A: 0xff}, gtx.Dp(3)},
+		{color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, gtx.Dp(1)},
+	} {
+		paint.FillShape(gtx.Ops, o.col, clip.Stroke{Path: clip.Ellipse(shackle).Path(gtx.Ops), Width: float32(o.width)}.Op())
+	}
+	paint.FillShape(gtx.Ops, color.NRGBA{A: 0xff}, clip.Rect(body.Inset(-gtx.Dp(1))).Op())
+	paint.FillShape(gtx.Ops, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, clip.Rect(body).Op())
+}
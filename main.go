@@ -1,126 +1,1153 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"image"
 	"image/color"
+	"io"
 	"log"
 	"math"
 	"os"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
 	"gioui.org/app"
 	"gioui.org/f32"
+	"gioui.org/io/clipboard"
 	"gioui.org/io/event"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
+	"gioui.org/io/system"
+	"gioui.org/io/transfer"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
+	"gioui.org/widget/material"
 )
 
 type Stroke struct {
 	Pts   []f32.Point
 	Col   color.NRGBA
-	Width float32 // px
+	Width float32   // px
+	At    time.Time // when drawing started
+	// Arrow adds an arrowhead at the last point, aimed along the end
+	// of the (possibly curved) path; Head is its shape, and BothEnds
+	// adds another at the first point.
+	Arrow    bool
+	Head     arrowStyle
+	BothEnds bool
+	// Widths, if set, holds the width at each point (px), for strokes
+	// drawn with dynamic width; Width is then their maximum.
+	Widths []float32
+	// Pixelate shows the background pixelated under the stroke instead
+	// of Col, for redacting (see pixelate.go); with Blur, blurred
+	// (blur.go).
+	Pixelate bool
+	Blur     bool
+	// Measure makes this a two-point line labeled with its length.
+	Measure bool
+	// Text makes this a text annotation (see text.go); Width is then
+	// the font size.
+	Text string
+	// Step, if positive, makes this a numbered step marker (see
+	// steps.go); Width is then its diameter.
+	Step int
+	// Icon, if set, makes this an icon stamp (see icon.go) centered on
+	// the single point; Width is then its size.
+	Icon string
+	// Dimension makes this a two-point dimension line with end ticks,
+	// labeled with Label or else its length (dimension.go).
+	Dimension bool
+	Label     string
+	// Chalk draws the stroke with the grainy chalk brush (chalk.go).
+	Chalk bool
+	// Hollow draws only the boundary of the stroke (hollow.go).
+	Hollow bool
+	// Highlight draws the stroke with the flat highlighter nib
+	// (highlight.go).
+	Highlight bool
+	// Shape, if set, makes this a "rectangle" or an "ellipse" in the box
+	// of the points, drawn exactly rather than along them; the points
+	// trace its outline for what works on points (shape.go).
+	Shape string
+	// Fill, if set, makes this a filled area (see fill.go) with its
+	// top-left corner at the single point. Masks are never modified.
+	Fill *image.Alpha
+	// FillAlpha, if set, fills the closed path in Col at this opacity,
+	// under the outline (shapefill.go).
+	FillAlpha uint8
+	// Halo, if set, outlines the stroke in this color (halo.go).
+	Halo color.NRGBA
+	// Locked protects the stroke from edits (lock.go).
+	Locked bool
+	// Join and Cap are the shapes of the corners and ends of the line
+	// (linestyle.go).
+	Join lineJoin
+	Cap  lineCap
+
+	// committed is when the stroke was committed, for -fade-in
+	// (fade.go); zero for no fade.
+	committed time.Time
+	// pulsed is when the stroke started pulsing (pulse.go), if it is.
+	pulsed time.Time
+}
+
+// Emphasis overlays: darken for light content, lighten for dark content,
+// at the default strength; dimColor gives them the overlay's own.
+var (
+	dimDark  = color.NRGBA{A: 120}
+	dimLight = color.NRGBA{R: 255, G: 255, B: 255, A: 120}
+)
+
+// dimColor is the overlay c at the strength of this overlay's.
+func (a *Annotator) dimColor(c color.NRGBA) color.NRGBA {
+	c.A = a.dimAlpha
+	return c
 }
 
 type Annotator struct {
 	keyTag struct{}
 	ptrTag struct{}
 
+	// trace is the -trace guide layer (trace.go).
+	trace []Stroke
+	// Canvases the -trace layer and the -restore strokes (from index
+	// restoreFrom on) were recorded on, for fitStartup.
+	traceCanvas   image.Point
+	restoreCanvas image.Point
+	restoreFrom   int
+	// replay is the -replay session, until it has been played (replay.go).
+	replay *replay
+	// -template being filled in, if any (template.go).
+	guide *guide
+	// Write sessions in normalized coordinates (-session-coords).
+	normalizedSessions bool
+
+	// strokes changes only in whole user actions: a drag builds cur,
+	// which is appended once on release, and keys or commands edit,
+	// move or remove a stroke at a time or clear them all. Undo works
+	// at those points rather than per pointer event, so that one
+	// gesture stays one step.
 	strokes []Stroke
 	cur     *Stroke
+	// What undo took back, the last undone last, the strokes as of the
+	// last undo or redo, and the changes of existing strokes (undo.go)
+	// with the generation of the strokes and the last one handed out.
+	redone    []undoStep
+	undoMark  strokesMark
+	changes   []*change
+	gen, gens int
+	// The drag of the eraser in progress (eraser.go).
+	erasing *change
+	// The laser pointer's trail, oldest first, and how much it is
+	// smoothed (-laser-smooth; laser.go).
+	laser         []laserPoint
+	laserSmoothDp float32
 
 	col       color.NRGBA
 	widthDp   float32
+	widthKeys [3]float32 // of the keys 1, 2 and 3
 	dim       bool
+	dimCol    color.NRGBA // darkening or lightening overlay
+	dimAlpha  uint8       // strength of both
 	debug     bool
 	lastLogAt time.Time
 
-	x11Ready bool
+	// rawPoints stores pointer samples as-is instead of densifying
+	// them with appendInterpolated.
+	rawPoints bool
+	// fullscreen is the -fullscreen mode.
+	fullscreen string
+
+	// Last known pointer position (window px), tracked for overlays
+	// that follow the cursor.
+	ptr   f32.Point
+	ptrIn bool
+
+	showCoords bool
+	winOrigin  image.Point // window's top-left in screen coordinates
+
+	spotlight     bool
+	spotRadiusDp  float32
+	spotFalloffDp float32
+	// Reading ruler (ruler.go): the center and height of its band, and
+	// whether Ctrl+drag is moving it.
+	ruler         bool
+	rulerY        float32
+	rulerHeightDp float32
+	rulerDrag     bool
+
+	recognize bool
+	tool      tool
+	// Vary the width with the drawing speed (dynwidth.go); dragTime is
+	// the time of the last pointer event of the current stroke.
+	dynWidth bool
+	dragTime time.Duration
+	// Start strokes at the previous stroke's end when close to it; Shift
+	// at press inverts this for one stroke.
+	joinStrokes bool
+	// Continue the previous pen stroke when pressed again soon and near
+	// its end (merge.go): the thresholds, the continuation in progress,
+	// and the stroke count and time of the last pen release.
+	merge       bool
+	mergeGap    time.Duration
+	mergeDistDp float32
+	merging     bool
+	mergeLen    int
+	mergeAt     time.Duration
+	// Draw arrows between consecutive step markers.
+	connectSteps bool
+	// Mirror pen strokes across a center axis (symmetry.go).
+	symmetry symmetry
+	// Draw pen and arrow strokes with the chalk brush (chalk.go), or
+	// hollow (hollow.go).
+	chalk  bool
+	hollow bool
+	// Joins and caps of new lines (linestyle.go).
+	penJoin lineJoin
+	penCap  lineCap
+	// Heads of new arrows (arrow.go).
+	arrowStyle arrowStyle
+	arrowBoth  bool
+
+	sel int // index of the selected stroke, -1 for none
+	// handleDrag is the resize handle being dragged (handles.go).
+	handleDrag *handleDrag
+
+	// Strokes being placed (place.go), and the point of them that
+	// follows the pointer.
+	placing     []Stroke
+	placeAnchor f32.Point
+
+	// Drawing region (region.go); regionPick has the next drag set it
+	// and regionSizing is that drag, started at regionFrom.
+	region       image.Rectangle
+	regionPick   bool
+	regionSizing bool
+	regionFrom   f32.Point
+
+	scrubTag  struct{}
+	scrubber  bool
+	scrubbing bool
+	scrubPos  float32 // 0..1 along the recorded time range
+
+	hexEntry bool
+	hexBuf   string
+
+	// Ctrl+Shift+M label prompt: the dimension line it edits, as index
+	// and start time, and the text so far (dimension.go).
+	labelEntry bool
+	labelIdx   int
+	labelAt    time.Time
+	labelBuf   string
+
+	// Text being typed with the text tool, not yet a stroke (typing.go).
+	typing *Stroke
+
+	// The command palette (commands.go): open, the search, the picked
+	// match, and the click targets of the matches.
+	cmdOpen  bool
+	cmdQuery string
+	cmdSel   int
+	cmdTags  [maxCommandRows]bool
+
+	// The palette editor (paletteedit.go): open, the picked slot, and
+	// whether there are changes to save.
+	paletteEdit  bool
+	paletteSlot  int
+	paletteDirty bool
+
+	// Held-key acceleration for the nudge keys.
+	nudgeKey     key.Name
+	nudgeAt      time.Time
+	nudgeRepeats int
+
+	palettes   []palette
+	paletteIdx int
+	recent     []color.NRGBA // custom colors, most recent first
+	recentTags [maxRecentColors]bool
+	// The pen color as of the last frame and the one before it, for
+	// Ctrl+X (palette.go).
+	lastCol color.NRGBA
+	prevCol color.NRGBA
+	// The tool as of the last frame and the one before it, for
+	// Ctrl+Shift+X (tool.go).
+	lastTool tool
+	prevTool tool
+
+	th    *material.Theme
+	toast toast
+	// Emphasis (H): the doubled width in use and the width it doubled;
+	// emphasisDp is 0 when emphasis is off.
+	emphasisDp  float32
+	baseWidthDp float32
+	// Pen width readout; hintWidthDp is the width it last announced.
+	widthHint   toast
+	hintWidthDp float32
+
+	// Window size in px as of the last frame.
+	size image.Point
+
+	// Loaded background (-background), shown scaled by bgFit into bgRect
+	// with bgFill around it, and the image op of its composition.
+	bgSrc  image.Image
+	bgFit  string
+	bgFill color.NRGBA
+	bgRect image.Rectangle
+	bgOp   paint.ImageOp
+	// bgRes is the declared pixel size of the image for image space
+	// (imagespace.go), zero without -background-resolution.
+	bgRes image.Point
+
+	// Screen contents under the overlay, captured once it is placed and
+	// again on request; or the composed loaded background.
+	bg            *image.RGBA
+	captured      chan captureResult
+	capturing     bool
+	recaptureMode string
+	// Periodic recapture (live.go): the interval, and when the last
+	// one started.
+	liveEvery time.Duration
+	liveAt    time.Time
+	// frozen stops it at the last capture, which stays in bg.
+	frozen bool
+
+	// Before/after comparison (compare.go): the inactive pane, and
+	// whether B is the active one.
+	otherPane pane
+	paneB     bool
+
+	// Quitting (-quit-key, -quit-confirm).
+	quitKey      keyChord
+	quitConfirm  bool
+	quitPromptAt time.Time
+
+	// Keys of the extra mouse buttons, the ones held as of the last
+	// press or release, and those logged for debugging (buttons.go).
+	buttons     map[string]keyChord
+	heldButtons pointer.Buttons
+	buttonsSeen map[string]bool
+
+	// Confirmation of destructive keys (confirm.go).
+	confirm bool
+	pending *pendingAction
+
+	// Clear gesture (gesture.go) and when it last asked to confirm.
+	scribbleClear bool
+	clearPromptAt time.Time
+
+	// Auto-save (autosave.go): the recovery file, how often it is
+	// checked, when it last was, the strokes then, and what it last got.
+	recoveryFile  string
+	autosaveEvery time.Duration
+	autosavedAt   time.Time
+	autosaveMark  strokesMark
+	autosaved     []byte
+
+	// Idle clear (idle.go): after how long without input, and when the
+	// last input came.
+	idleClearAfter time.Duration
+	activeAt       time.Time
+
+	// Followed window (follow.go), its last rectangle, when it was last
+	// checked, and when it will have stayed put long enough after a move
+	// to be captured again.
+	follow        uintptr
+	followRect    image.Rectangle
+	followAt      time.Time
+	followSettled time.Time
+
+	// Sound cues (sound.go), nil without -sounds, and the pen color
+	// last cued.
+	sounds  *soundPlayer
+	cuedCol color.NRGBA
+
+	// exit quits the program.
+	exit func()
+
+	// Pixelated copy of bg for the redaction pen, and the bg it was
+	// made from.
+	pixelOp paint.ImageOp
+	pixelOf *image.RGBA
+	// Blurred areas of bg for the blur pen, the bg they were made from
+	// and the frame that last looked one up (blur.go).
+	blurOps   map[blurKey]*blurArea
+	blurOf    *image.RGBA
+	blurFrame time.Time
+	// fillOps caches the images of fills (fill.go).
+	fillOps map[*image.Alpha]paint.ImageOp
+
+	exportOpts exportOptions
+
+	// Pinned notes (pins.go), and whether exports show them.
+	pins      []string
+	pinExport bool
+
+	// Commands from the -control socket, applied on the next frame.
+	control chan controlCommand
+
+	w *app.Window
+	// monitor is the screen area this window belongs to with
+	// -all-monitors or -mirror; nil means the monitor under the pointer.
+	monitor *image.Rectangle
+
+	// mirror is this overlay's audience window (-mirror, see
+	// mirror.go); feed is set instead on the mirror itself.
+	mirror *Annotator
+	feed   *mirrorFeed
+
+	x11Ready        bool
 	x11OverlayTried bool
-	opacity uint32 // 0..0xFFFFFFFF
-	clickThrough bool
+	opacity         uint32 // 0..0xFFFFFFFF
+	clickThrough    bool
+	hidden          bool // hidden via the control socket
+	focused         bool // has the keyboard focus (focus.go)
+	// -hide-cursor, and whether the cursor is hidden now (cursor.go).
+	hideCursor   bool
+	cursorHidden bool
+	// -hover-preview: the ring and a dab of the pen follow the hovering
+	// pointer (cursor.go).
+	hoverPreview bool
+	// Connector tool (connector.go): the press, and the routing.
+	connFrom     f32.Point
+	connectOrtho bool
+	// -png-metadata, and the screen area of the capture (pngmeta.go).
+	pngMeta     bool
+	captureRect image.Rectangle
+	// -deterministic rounds exported strokes (deterministic.go).
+	deterministic bool
+	// Pen strokes shorter than this are discarded (-min-stroke).
+	minStrokeDp float32
+	// Opacities of the fill and the outline of new shapes (shapefill.go).
+	shapeFill    uint8
+	shapeOutline uint8
+	// -predict, and the samples it extrapolates from (predict.go).
+	predict   bool
+	predictor predictor
+
+	// Auto-contrast halos for new strokes (halo.go).
+	autoContrast bool
+
+	// -max-strokes, and the strokes evicted and fading out (evict.go).
+	maxStrokes int
+	evicting   []evicted
+	// -warn-strokes and -warn-points, and the note past them (heavy.go).
+	heavy heavyWarning
+
+	// Pulses of Ctrl+G, and when Ctrl+Shift+G flashed all strokes
+	// (pulse.go).
+	pulseCount  int
+	pulsePeriod time.Duration
+	flashed     time.Time
+
+	// -ocr, the words of the background read in ocrImg, the run in
+	// progress and its result, and the word tool's marks (ocr.go).
+	ocrBin        string
+	ocrImg        *image.RGBA
+	ocrWords      []ocrWord
+	ocrBusy       bool
+	ocrDone       chan ocrResult
+	wordFrom      int
+	wordUnderline bool
+	wordPreview   []Stroke
+
+	// -pixel-snap, and the strokes already looked at (pixelsnap.go).
+	pixelSnap bool
+	snapSeen  int
+
+	// Recent PNG exports, newest first, and their panel (history.go).
+	exportHistory []exportedImage
+	historyOpen   bool
+	historyTags   [maxExportHistory]bool
+
+	// -on-export, shared by the overlays, and the outcomes of its runs
+	// (exporthook.go).
+	hook     *exportHook
+	hookDone chan hookResult
+
+	// Frames drawn, logged for debugging (frames.go).
+	frameStats frameStats
+	// F12 shows the point data of strokes, with ANNOTATOR_DEBUG (wireframe.go).
+	wireframe bool
+
+	// Icon placed by the icon tool, into iconNames (icon.go).
+	iconIdx int
+	// Shape drawn by the shape tool, into shapeNames, the press it is
+	// drawn from, and the unit direction of the last line or arrow it
+	// drew, zero before the first (shape.go); and whether the ends of the
+	// line being drawn snapped onto strokes (snap.go).
+	shapeIdx         int
+	shapeFrom        f32.Point
+	lineDir          f32.Point
+	snapFrom, snapTo bool
+
+	// Pen presets, and the one last picked, or -1 (presets.go).
+	presets   []preset
+	presetIdx int
+
+	// -fade-in, and how many strokes there were and whether one was in
+	// progress (started when) as of the last frame (fade.go).
+	fadeIn      bool
+	fadeSeen    int
+	fadeDrawn   bool
+	fadeDrawnAt time.Time
+	// Ctrl+S saves here; -oneshot quits after exporting (oneshot.go).
+	savePath   string
+	oneshot    bool
 	x11Display unsafe.Pointer
-	x11Window uintptr
+	x11Window  uintptr
 }
 
 func main() {
+	logFile := flag.String("logfile", "", "append log output to this file instead of stderr")
+	rawPoints := flag.Bool("raw-points", false, "store raw pointer samples without interpolation")
+	recapture := flag.String("recapture", recaptureKeep, "strokes on background recapture: keep (in place), clear, or follow (move with the content)")
+	recaptureClearFlag := flag.Bool("recapture-clear", false, "shorthand for -recapture clear")
+	scriptPath := flag.String("script", "", "render this JSON annotation script headlessly and exit")
+	outPath := flag.String("out", "", "output PNG for -script (overrides the script's \"out\"); otherwise where Ctrl+S saves (.png, .svg or .json; default a timestamped PNG in $SCREENPENGO_OUT or the current directory)")
+	oneshot := flag.Bool("oneshot", false, "quit after the first export (Ctrl+S or the control socket): capture, draw, save, done")
+	output := flag.String("output", "", "Wayland output to open the overlay on, e.g. DP-1 (Sway, Hyprland); the compositor's choice, usually the focused output, without it")
+	allMonitors := flag.Bool("all-monitors", false, "open an independent overlay on every monitor (X11)")
+	followFlag := flag.String("follow-window", "", "cover this X11 window instead of a monitor and move and resize with it: an ID (xwininfo, xdotool) or \"pointer\" for the window under the pointer")
+	mirrorMon := flag.Int("mirror", 0, "also show the strokes, read-only, on this monitor (1-based, X11) for an audience")
+	fullscreen := flag.String("fullscreen", fullscreenBoth, "how to cover the screen: gio, netwm, both or override (X11 override-redirect)")
+	buttonChords := make(map[string]*string)
+	for _, b := range mouseButtons {
+		usage := fmt.Sprintf("key the %s mouse button presses, e.g. . for the next color", b.name)
+		if b.held {
+			usage = fmt.Sprintf("key the %s button of a pen (or the right mouse button) presses, and presses again on release, for a mode while held, e.g. Ctrl+X for the previous color", b.name)
+		}
+		buttonChords[b.name] = flag.String(buttonFlag(b.name), "", usage)
+	}
+	startTool := flag.String("tool", "pen", fmt.Sprintf("tool to start with: %s", strings.Join(toolNames, ", ")))
+	quitKey := flag.String("quit-key", "Escape", "key that quits, e.g. Ctrl+Q; a bare Escape then only cancels")
+	quitConfirm := flag.Bool("quit-confirm", false, "require pressing the quit key twice")
+	confirm := flag.Bool("confirm", true, "ask before quitting or clearing by key while there are strokes (-confirm=false for instant)")
+	scribbleClear := flag.Bool("scribble-clear", false, "a big fast back-and-forth scribble offers to clear (confirmed with a tap)")
+	dump := flag.Bool("dump", false, "on exit, write the strokes to stdout as JSON (one session per overlay per line)")
+	soundsFlag := flag.Bool("sounds", false, "play short sound cues on color changes, clearing and exports (needs paplay, pw-play or aplay)")
+	deterministic := flag.Bool("deterministic", false, "make exports byte-identical for the same strokes: round positions to whole pixels and leave the time out of PNG metadata, for pixel-diff tests")
+	pngMeta := flag.Bool("png-metadata", true, "put the time, version, captured screen area and followed window title into exported PNGs (false for clean files)")
+	minStroke := flag.Float64("min-stroke", 0, "discard pen strokes shorter than this many dp on release, e.g. 3 against stray clicks (Ctrl+. places dots on purpose)")
+	pulseCount := flag.Int("pulse-count", 3, "how many times Ctrl+G blinks the selected stroke")
+	pulsePeriod := flag.Duration("pulse-period", 400*time.Millisecond, "how long one Ctrl+G blink takes")
+	fadeIn := flag.Bool("fade-in", false, fmt.Sprintf("fade newly committed strokes in over %v instead of popping up, for smoother recordings", fadeInDuration))
+	predict := flag.Bool("predict", false, "draw the stroke in progress one pointer sample ahead, to hide some of the input lag")
+	hoverPreview := flag.Bool("hover-preview", false, "show a ring and a faint dab of the pen where a stroke would start while a tablet pen hovers")
+	hideCursor := flag.Bool("hide-cursor", false, "hide the system cursor over the focused overlay and mark the pointer with a pen-sized ring (for compositors that show two cursors)")
+	merge := flag.Bool("merge", false, "continue the previous pen stroke when the pen comes down again within -merge-gap and -merge-dist of its end, mending lines broken by a skipping tablet pen (Shift+J toggles)")
+	mergeGap := flag.Duration("merge-gap", 150*time.Millisecond, "how soon after a release -merge continues the stroke")
+	mergeDist := flag.Float64("merge-dist", 16, "how near the end of the previous stroke, in dp, -merge continues it")
+	joinName := flag.String("join", "round", "corners of new lines: round, miter or bevel (Ctrl+Shift+J cycles)")
+	capName := flag.String("cap", "round", "ends of new lines: round, square or flat (Ctrl+Shift+K cycles)")
+	laserSmooth := flag.Float64("laser-smooth", 6, "smooth the laser pointer (Shift+L) at this scale in dp: moves of about this much per event or less are damped, so the dot glides rather than jitters, faster ones follow closely (0 for off)")
+	rulerHeight := flag.Float64("ruler-height", 40, "initial height, in dp, of the reading ruler band (Shift+F)")
+	pixelSnap := flag.Bool("pixel-snap", false, fmt.Sprintf("snap the points of strokes up to %dpx wide to the pixel grid, for crisp thin lines", pixelSnapMax))
+	onExport := flag.String("on-export", "", "run this command, with the path appended, after each PNG export, e.g. an uploader printing a URL (logged and shown)")
+	ocrBin := flag.String("ocr", "", "read the words of the background with this tesseract binary, e.g. tesseract, for the word tool (Ctrl+W)")
+	warnStrokes := flag.Int("warn-strokes", 3000, "note in a corner that the overlay may get slow past this many strokes (0 for never)")
+	warnPoints := flag.Int("warn-points", 300000, "note in a corner that the overlay may get slow past this many points in all strokes (0 for never)")
+	maxStrokes := flag.Int("max-strokes", 0, "keep at most this many strokes, the oldest fading out as new ones come, for always-on overlays (0 for no limit)")
+	idleClearAfter := flag.Duration("idle-clear", 0, "clear the strokes after this long without input, e.g. 5m for a kiosk (0 disables)")
+	autosaveEvery := flag.Duration("autosave", 5*time.Second, "how often to save the strokes to a recovery file (0 disables)")
+	restore := flag.Bool("restore", false, "start with the strokes from the recovery file")
+	svgPath := flag.String("load-svg", "", "start with the strokes of this SVG (lines, polylines, straight paths, rectangles and ellipses, e.g. an edited Ctrl+C export)")
+	sessionCoords := flag.String("session-coords", sessionPixels, "coordinates of saved sessions (.json export, autosave, -dump): px, or normalized 0..1 of the canvas")
+	tracePath := flag.String("trace", "", "show this session file faintly under the strokes as a guide (not exported until flattened with Ctrl+F)")
+	templatePath := flag.String("template", "", "guide the annotation through the placeholders of this template file, one prompt at a time")
+	replayPath := flag.String("replay", "", "draw this session file again stroke by stroke, in its recorded rhythm, when Enter is pressed")
+	replaySpeed := flag.Float64("replay-speed", 1, "how many times faster than recorded -replay draws")
+	live := flag.Float64("live", 0, fmt.Sprintf("recapture the background this many times a second (up to %d), to annotate video; costs CPU and flickers the overlay", maxLiveFPS))
+	dim := flag.Bool("dim", false, "start with the background dimmed, as after pressing A")
+	dimLevel := flag.Float64("dim-level", 0, "strength of dimming and lightening, 0..1 (default 0.47, or dimAlpha from the config)")
+	opacity := flag.Float64("opacity", 0, "whole-window opacity 0.1..1 through the compositor (default 0.3, 1 with -background)")
+	paletteFile := flag.String("palette", "", "GIMP .gpl or Paint.NET .txt palette for the color keys (R G B Y O P in order), or the built-in colorblind-safe okabe-ito")
+	bgPath := flag.String("background", "", "annotate this image instead of the screen")
+	bgFitMode := flag.String("background-fit", bgFit, "how -background is scaled: fit (letterbox), fill (crop) or stretch")
+	bgRes := flag.String("background-resolution", "", "native WxH pixel size of -background: the readout, exports and sessions use its pixels instead of the window's")
+	bgColor := flag.String("background-color", "000000", "color around a letterboxed -background (RRGGBB)")
+	exportCrop := flag.Bool("export-crop", false, "crop PNG exports to the strokes (plus -export-margin)")
+	exportMargin := flag.Int("export-margin", 16, "margin in px around the strokes for -export-crop")
+	exportSmooth := flag.Float64("export-smooth", 0, "smooth the hand tremor out of freehand strokes in PNG and SVG exports at this scale in px, e.g. 4; the overlay and sessions keep the points as drawn (0 for off)")
+	watermarkText := flag.String("watermark", "", "put this text onto every PNG export, e.g. a confidentiality label")
+	watermarkImg := flag.String("watermark-image", "", "put this image (PNG or JPEG, e.g. a logo) onto every PNG export, before the -watermark text")
+	watermarkCorner := flag.String("watermark-corner", "bottom-right", fmt.Sprintf("where the watermark goes: %s", strings.Join(watermarkCorners, ", ")))
+	watermarkSize := flag.Float64("watermark-size", 20, "size of the -watermark text in px")
+	watermarkOpacity := flag.Float64("watermark-opacity", 0.6, "opacity of the watermark, up to 1")
+	exportBg := flag.String("export-background", "transparent", "color behind transparent parts of PNG exports (RRGGBB), or transparent")
+	var pins pinFlag
+	flag.Var(&pins, "pin", "pinned note in the corner of the screen, e.g. \"REC\" (repeatable)")
+	pinExport := flag.Bool("pin-export", false, "include pinned notes in PNG exports")
+	controlPath := flag.String("control", "", "accept control commands on this Unix socket")
+	flag.StringVar(&configPathFlag, "config", "", "read the config from this file instead of screenpengo/config.json in the user config directory")
+	penColor := flag.String("color", "", "pen color to start with (RRGGBB[AA]), instead of the config's or the palette's red")
+	penWidth := flag.Float64("width", 0, "pen width to start with, dp (1..100), instead of the config's or 6")
+	flag.Parse()
+
+	// The config provides defaults for unset flags, so it is read before
+	// any flag is looked at.
+	cfg, cfgPath, cfgErr := loadConfig()
+	if cfgErr == nil {
+		cfgErr = cfg.applyFlags(flag.CommandLine)
+	}
+
 	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatalf("open log file: %v", err)
+		}
+		log.SetOutput(f)
+	}
 	debug := os.Getenv("ANNOTATOR_DEBUG") == "1" || os.Getenv("ANNOTATOR_DEBUG") == "true"
 	log.Printf("starting gio-screenpen (go=%s os=%s debug=%v)", runtime.Version(), runtime.GOOS+"/"+runtime.GOARCH, debug)
+	if cfgErr != nil {
+		log.Fatalf("config: %v", cfgErr)
+	}
+	if debug {
+		if cfgPath != "" {
+			log.Printf("config: loaded %s", cfgPath)
+		} else if p, err := configPath(); err == nil {
+			log.Printf("config: %s not found, using defaults", p)
+		}
+	}
+
+	if *scriptPath != "" {
+		if err := runScript(*scriptPath, *outPath, *deterministic); err != nil {
+			log.Fatalf("script: %v", err)
+		}
+		return
+	}
 
+	switch *fullscreen {
+	case fullscreenGio, fullscreenNetWM, fullscreenBoth, fullscreenOverride:
+	default:
+		log.Fatalf("-fullscreen: unknown mode %q", *fullscreen)
+	}
+	if *recaptureClearFlag {
+		*recapture = recaptureClear
+	}
+	switch *recapture {
+	case recaptureKeep, recaptureClear, recaptureFollow:
+	default:
+		log.Fatalf("-recapture: unknown mode %q", *recapture)
+	}
+	var follow uintptr
+	if *followFlag != "" {
+		if *allMonitors {
+			log.Fatalf("-follow-window: not with -all-monitors")
+		}
+		var err error
+		if follow, err = parseFollowWindow(*followFlag); err != nil {
+			log.Fatalf("-follow-window: %v", err)
+		}
+		if *fullscreen != fullscreenOverride {
+			// Only an override-redirect window can be put anywhere.
+			log.Printf("-follow-window: using -fullscreen override")
+			*fullscreen = fullscreenOverride
+		}
+	}
+	penJoin, ok := parseLineJoin(*joinName)
+	if !ok {
+		log.Fatalf("-join: unknown %q, want round, miter or bevel", *joinName)
+	}
+	penCap, ok := parseLineCap(*capName)
+	if !ok {
+		log.Fatalf("-cap: unknown %q, want round, square or flat", *capName)
+	}
+	switch *sessionCoords {
+	case sessionPixels, sessionNormalized:
+	default:
+		log.Fatalf("-session-coords: unknown %q, want px or normalized", *sessionCoords)
+	}
+	quit, err := parseKeyChord(*quitKey)
+	if err != nil {
+		log.Fatalf("-quit-key: %v", err)
+	}
+	firstTool, err := parseTool(*startTool)
+	if err != nil {
+		log.Fatalf("-tool: %v", err)
+	}
+	chords := make(map[string]string)
+	for name, s := range buttonChords {
+		chords[name] = *s
+	}
+	buttons, err := parseButtons(chords)
+	if err != nil {
+		log.Fatal(err)
+	}
+	o := options{
+		debug: debug, rawPoints: *rawPoints, recapture: *recapture, fullscreen: *fullscreen, quitKey: quit, quitConfirm: *quitConfirm,
+		scribbleClear: *scribbleClear, confirm: *confirm, follow: follow, buttons: buttons, tool: firstTool,
+		widthDp: 6, widthKeys: [3]float32{3, 6, 12}, dimCol: dimDark, dimAlpha: dimDark.A, palettes: defaultPalettes,
+	}
+	if err := cfg.apply(&o); err != nil {
+		log.Fatalf("config %s: %v", cfgPath, err)
+	}
+	if *penColor != "" {
+		if o.col, err = parseHexColor(*penColor); err != nil {
+			log.Fatalf("-color: %v", err)
+		}
+		o.colSet = true
+	}
+	if *penWidth != 0 {
+		if *penWidth < 1 || *penWidth > 100 {
+			log.Fatalf("-width %v: want 1..100", *penWidth)
+		}
+		o.widthDp = float32(*penWidth)
+	}
+	// 0, no dimming at all, is a level of its own, not the flag left out.
+	dimLevelSet := false
+	flag.Visit(func(f *flag.Flag) { dimLevelSet = dimLevelSet || f.Name == "dim-level" })
+	if dimLevelSet {
+		if *dimLevel < 0 || *dimLevel > 1 {
+			log.Fatalf("-dim-level %v: want 0..1", *dimLevel)
+		}
+		o.dimAlpha = uint8(*dimLevel*255 + 0.5)
+	}
+	if *dim {
+		o.dim, o.dimCol = true, dimDark
+	}
+	if *opacity != 0 {
+		o.opacity = windowOpacity(*opacity)
+	}
+	if *paletteFile != "" {
+		p, ok := namedPalette(*paletteFile)
+		if !ok {
+			if p, err = loadPaletteFile(*paletteFile); err != nil {
+				log.Fatalf("-palette: %v", err)
+			}
+			if debug {
+				log.Printf("palette %q from %s", p.name, *paletteFile)
+			}
+		}
+		// Ahead of the others, which Ctrl+T still reaches.
+		p.oneOff = true
+		o.palettes = append([]palette{p}, o.palettes...)
+	}
+	if *bgPath != "" {
+		if o.bgSrc, err = loadImage(*bgPath); err != nil {
+			log.Fatalf("-background: %v", err)
+		}
+		if o.bgFit, err = parseBgFit(*bgFitMode); err != nil {
+			log.Fatalf("-background-fit: %v", err)
+		}
+		if o.bgFill, err = parseHexColor(*bgColor); err != nil {
+			log.Fatalf("-background-color: %v", err)
+		}
+		if *bgRes != "" {
+			if o.bgRes, err = parseResolution(*bgRes); err != nil {
+				log.Fatalf("-background-resolution: %v", err)
+			}
+		}
+	}
+	o.pins, o.pinExport = pins, *pinExport
+	if o.live = liveInterval(*live); o.live > 0 {
+		log.Printf("-live: recapturing every %v; each capture briefly hides the overlay and uses CPU, lower the rate if it stutters", o.live)
+	}
+	o.export = exportOptions{crop: *exportCrop, margin: max(*exportMargin, 0), smooth: float32(max(*exportSmooth, 0))}
+	if o.export.bg, err = parseExportBackground(*exportBg); err != nil {
+		log.Fatalf("-export-background: %v", err)
+	}
+	if o.export.watermark, err = parseWatermark(*watermarkText, *watermarkImg, *watermarkCorner, *watermarkSize, *watermarkOpacity); err != nil {
+		log.Fatalf("-watermark: %v", err)
+	}
+	var (
+		trace       []Stroke
+		traceCanvas image.Point
+	)
+	if *tracePath != "" {
+		if trace, traceCanvas, err = loadSession(*tracePath); err != nil {
+			log.Fatalf("-trace: %v", err)
+		}
+	}
+	var tmpl *guide
+	if *templatePath != "" {
+		if tmpl, err = loadTemplate(*templatePath); err != nil {
+			log.Fatalf("-template: %v", err)
+		}
+	}
+	var rep *replay
+	if *replayPath != "" {
+		if *replaySpeed <= 0 {
+			log.Fatalf("-replay-speed %v: want more than 0", *replaySpeed)
+		}
+		if rep, err = loadReplay(*replayPath, *replaySpeed); err != nil {
+			log.Fatalf("-replay: %v", err)
+		}
+	}
+	var loaded []Stroke
+	if *svgPath != "" {
+		if loaded, err = loadSVG(*svgPath); err != nil {
+			log.Fatalf("-load-svg: %v", err)
+		}
+	}
+	switch {
+	case *output != "" && !onWayland():
+		log.Printf("-output: not a Wayland session; the overlay opens on the monitor under the pointer")
+	case *output != "":
+		if err := focusWaylandOutput(*output); err != nil {
+			log.Printf("-output: %v; the overlay opens where the compositor puts it", err)
+		} else if debug {
+			log.Printf("-output: focused %s", *output)
+		}
+	}
+	var mons []image.Rectangle
+	if *allMonitors {
+		var err error
+		if mons, err = x11Monitors(); err != nil {
+			log.Printf("-all-monitors: %v; using a single window", err)
+		} else if debug {
+			log.Printf("monitors: %v", mons)
+		}
+	}
+	var overlays []*Annotator
+	if len(mons) == 0 {
+		overlays = append(overlays, newAnnotator(new(app.Window), o))
+	}
+	for _, m := range mons {
+		a := newAnnotator(new(app.Window), o)
+		a.monitor = &m
+		overlays = append(overlays, a)
+	}
+	// The mirror is not one of the overlays: it has no strokes, keys or
+	// files of its own.
+	var mirror *Annotator
+	if *mirrorMon != 0 {
+		if *allMonitors {
+			log.Fatalf("-mirror: not with -all-monitors")
+		}
+		mons, err := x11Monitors()
+		if err != nil {
+			log.Fatalf("-mirror: %v", err)
+		}
+		if *mirrorMon < 1 || *mirrorMon > len(mons) {
+			log.Fatalf("-mirror %d: want 1..%d", *mirrorMon, len(mons))
+		}
+		mirror = newAnnotator(new(app.Window), o)
+		mirror.monitor = &mons[*mirrorMon-1]
+		mirror.feed = new(mirrorFeed)
+		mirror.clickThrough = true
+		overlays[0].mirror = mirror
+	}
+	if *controlPath != "" {
+		if err := serveControl(*controlPath, overlays); err != nil {
+			log.Fatalf("-control: %v", err)
+		}
+	}
+	// Quitting closes every window, so the strokes are final (and no
+	// longer touched by an event loop) when they are dumped.
+	closeAll := func() {
+		for _, a := range overlays {
+			a.w.Perform(system.ActionClose)
+		}
+		if mirror != nil {
+			mirror.w.Perform(system.ActionClose)
+		}
+	}
+	var sounds *soundPlayer
+	if *soundsFlag {
+		sounds = newSoundPlayer()
+	}
+	hook := newExportHook(*onExport)
+	var wg sync.WaitGroup
+	for i, a := range overlays {
+		a.exit = closeAll
+		a.sounds, a.cuedCol = sounds, a.col
+		a.hook, a.hookDone = hook, make(chan hookResult, 4)
+		// Each overlay gets its own copies, as they are edited in place.
+		for _, s := range trace {
+			a.trace = append(a.trace, cloneStroke(s))
+		}
+		a.traceCanvas = traceCanvas
+		if rep != nil {
+			a.replay = rep.clone()
+		}
+		if tmpl != nil {
+			a.guide = tmpl.clone()
+		}
+		a.normalizedSessions = *sessionCoords == sessionNormalized
+		for _, s := range loaded {
+			a.strokes = append(a.strokes, cloneStroke(s))
+		}
+		a.idleClearAfter = *idleClearAfter
+		a.maxStrokes = *maxStrokes
+		a.heavy.maxStrokes, a.heavy.maxPoints = *warnStrokes, *warnPoints
+		a.ocrBin, a.ocrDone = *ocrBin, make(chan ocrResult, 1)
+		a.hideCursor = *hideCursor
+		a.hoverPreview = *hoverPreview
+		a.predict = *predict
+		a.fadeIn = *fadeIn
+		a.pixelSnap = *pixelSnap
+		a.rulerHeightDp = float32(*rulerHeight)
+		a.laserSmoothDp = float32(max(*laserSmooth, 0))
+		a.penJoin, a.penCap = penJoin, penCap
+		a.merge, a.mergeGap, a.mergeDistDp = *merge, *mergeGap, float32(*mergeDist)
+		a.pulseCount, a.pulsePeriod = *pulseCount, *pulsePeriod
+		a.minStrokeDp = float32(*minStroke)
+		a.pngMeta = *pngMeta
+		a.deterministic = *deterministic
+		a.oneshot, a.savePath = *oneshot, *outPath
+		if *outPath != "" && len(overlays) > 1 {
+			a.savePath = monitorPath(*outPath, i)
+		}
+		a.autosaveEvery = *autosaveEvery
+		if a.recoveryFile, err = recoveryPath(i); err != nil {
+			log.Printf("autosave: %v", err)
+		} else if *restore {
+			if err := a.restore(); err != nil {
+				log.Printf("-restore: %v", err)
+			} else {
+				log.Printf("restored %d strokes from %s", len(a.strokes), a.recoveryFile)
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWindow(a)
+		}()
+	}
+	if mirror != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWindow(mirror)
+		}()
+	}
 	go func() {
-		w := new(app.Window)
-		w.Option(
-			app.Title("gio-screenpen"),
-			app.Decorated(false),
-			app.Fullscreen.Option(),
-		)
-
-		a := &Annotator{
-			opacity: 0x50000000, // ~30%
-			col:     color.NRGBA{R: 255, A: 255}, // red default
-			widthDp: 6,
-			debug:   debug,
-		}
-
-		var ops op.Ops
-		for {
-			switch e := w.Event().(type) {
-			case app.DestroyEvent:
-				log.Printf("destroy: %v", e.Err)
-				return
-			case app.X11ViewEvent:
-				if !a.x11Ready && e.Valid() {
-					if err := x11MoveWindowToPointer(e.Display, e.Window); err != nil {
-						if a.debug {
-							log.Printf("x11 move-to-pointer failed: %v", err)
-						}
-					} else if a.debug {
-						log.Printf("x11 moved window to pointer monitor (win=0x%x)", e.Window)
-					}
-					a.x11Ready = true
-				}
-				a.tryEnableOverlay(e)
-			case app.FrameEvent:
-				gtx := app.NewContext(&ops, e)
-				a.frame(gtx)
-				e.Frame(gtx.Ops)
+		wg.Wait()
+		hook.wait()
+		sounds.remove()
+		if *dump {
+			if err := dumpSessions(os.Stdout, overlays); err != nil {
+				log.Printf("-dump: %v", err)
+				os.Exit(1)
 			}
 		}
+		os.Exit(0)
 	}()
 	app.Main()
 }
 
+// Ways of covering the monitor (-fullscreen). Window managers differ in
+// which of them they honor without borders or uncovered panels.
+const (
+	fullscreenGio      = "gio"      // Gio's fullscreen window option only
+	fullscreenNetWM    = "netwm"    // only the _NET_WM_STATE_FULLSCREEN hint
+	fullscreenBoth     = "both"     // both of the above
+	fullscreenOverride = "override" // bypass the WM: override-redirect at monitor size
+)
 
-func (a *Annotator) tryEnableOverlay(e app.X11ViewEvent) {
-	if a.x11OverlayTried {
-		return
+// windowOpacity converts an opacity in 0.1..1 (clamped) to the
+// _NET_WM_WINDOW_OPACITY scale.
+func windowOpacity(f float64) uint32 {
+	f = min(max(f, 0.1), 1)
+	return uint32(f * 0xffffffff)
+}
+
+// options are the command-line settings every overlay window starts with.
+type options struct {
+	debug       bool
+	rawPoints   bool
+	recapture   string
+	fullscreen  string
+	quitKey     keyChord
+	quitConfirm bool
+	buttons     map[string]keyChord // extra mouse button -> key
+	// scribbleClear enables the clear gesture.
+	scribbleClear bool
+	confirm       bool
+
+	// Startup tool (-tool), pen and backdrop, from the config file;
+	// without colSet the pen is the first palette's red.
+	tool      tool
+	col       color.NRGBA
+	colSet    bool
+	widthDp   float32
+	widthKeys [3]float32 // of the keys 1, 2 and 3
+	dim       bool
+	dimCol    color.NRGBA
+	dimAlpha  uint8
+	palettes  []palette
+	presets   []preset
+
+	opacity uint32 // _NET_WM_WINDOW_OPACITY; 0 for the default
+	export  exportOptions
+
+	pins      []string
+	pinExport bool
+
+	live   time.Duration // -live refresh interval, 0 for off
+	follow uintptr       // -follow-window, 0 for none
+
+	// Loaded background and how to fit it.
+	bgSrc  image.Image
+	bgFit  string
+	bgFill color.NRGBA
+	bgRes  image.Point
+}
+
+func newAnnotator(w *app.Window, o options) *Annotator {
+	w.Option(
+		app.Title("gio-screenpen"),
+		app.Decorated(false),
+	)
+	if o.fullscreen == fullscreenGio || o.fullscreen == fullscreenBoth {
+		w.Option(app.Fullscreen.Option())
 	}
-	a.x11OverlayTried = true
-	a.x11Display = e.Display
-	a.x11Window = e.Window
-	if err := x11EnableOverlayHints(e.Display, e.Window); err != nil {
-		if a.debug {
-			log.Printf("x11 overlay hints failed: %v", err)
+	col := o.col
+	if !o.colSet {
+		col = o.palettes[0].colors["red"]
+	}
+	a := &Annotator{
+		opacity:      0x50000000, // ~30%
+		col:          col,
+		palettes:     o.palettes,
+		presets:      o.presets,
+		presetIdx:    -1,
+		widthDp:      o.widthDp,
+		widthKeys:    o.widthKeys,
+		hintWidthDp:  o.widthDp,
+		sel:          -1,
+		shapeOutline: 0xff,
+		connectSteps: true,
+		dim:          o.dim,
+		dimCol:       o.dimCol,
+		dimAlpha:     o.dimAlpha,
+		debug:        o.debug,
+
+		scribbleClear: o.scribbleClear,
+		confirm:       o.confirm,
+		quitKey:       o.quitKey,
+		quitConfirm:   o.quitConfirm,
+
+		rawPoints:  o.rawPoints,
+		fullscreen: o.fullscreen,
+		buttons:    o.buttons,
+		tool:       o.tool,
+
+		captured:      make(chan captureResult, 1),
+		control:       make(chan controlCommand, 8),
+		recaptureMode: o.recapture,
+		liveEvery:     o.live,
+		follow:        o.follow,
+		w:             w,
+
+		exportOpts: o.export,
+		pins:       slices.Clone(o.pins),
+		pinExport:  o.pinExport,
+
+		bgSrc:  o.bgSrc,
+		bgFit:  o.bgFit,
+		bgFill: o.bgFill,
+		bgRes:  o.bgRes,
+
+		spotRadiusDp:  120,
+		spotFalloffDp: 40,
+	}
+	a.dimCol = a.dimColor(a.dimCol)
+	switch {
+	case o.opacity != 0:
+		a.opacity = o.opacity
+	case a.bgSrc != nil:
+		// An image, not the desktop, is being annotated.
+		a.opacity = 0xffffffff
+	}
+	a.loadRecentColors()
+	return a
+}
+
+// runWindow is the event loop of one overlay window; it returns when the
+// window is closed.
+func runWindow(a *Annotator) {
+	w := a.w
+	var ops op.Ops
+	for {
+		switch e := w.Event().(type) {
+		case app.DestroyEvent:
+			log.Printf("destroy: %v", e.Err)
+			return
+		case app.ConfigEvent:
+			a.setFocused(e.Config.Focused)
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, e)
+			if a.feed != nil {
+				a.mirrorFrame(gtx)
+			} else {
+				a.frame(gtx)
+			}
+			e.Frame(gtx.Ops)
+		default:
+			a.viewEvent(e)
 		}
-		return
 	}
-	if a.opacity == 0 {
-		a.opacity = 0x50000000 // ~30% opacity
+}
+
+// coverMonitor makes the window an override-redirect one covering its
+// target monitor, or the followed window.
+func (a *Annotator) coverMonitor(display unsafe.Pointer, window uintptr) {
+	r, err := a.targetMonitor(display)
+	if a.follow != 0 {
+		r, err = x11FollowedGeometry(display, a.follow)
+		if errors.Is(err, errNotViewable) {
+			err = nil
+		}
+		a.followRect = r
+	}
+	if err == nil {
+		err = x11CoverOverrideRedirect(display, window, r)
+	}
+	if err != nil {
+		log.Printf("x11 override-redirect placement failed: %v", err)
+	} else if a.debug {
+		log.Printf("x11 override-redirect window covers %v (win=0x%x)", r, window)
+	}
+}
+
+// targetMonitor is the monitor this window should cover: the assigned one
+// with -all-monitors, else the one under the pointer.
+func (a *Annotator) targetMonitor(display unsafe.Pointer) (image.Rectangle, error) {
+	if a.monitor != nil {
+		return *a.monitor, nil
+	}
+	mons, err := x11Monitors()
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	p, err := x11PointerPosition(display)
+	if err != nil {
+		return mons[0], nil
 	}
-	_ = x11SetOpacity(e.Display, e.Window, a.opacity)
-	_ = x11SetClickThrough(e.Display, e.Window, a.clickThrough)
-	if a.debug {
-		log.Printf("x11 overlay enabled (opacity=0x%08x clickThrough=%v)", a.opacity, a.clickThrough)
+	for _, m := range mons {
+		if p.In(m) {
+			return m, nil
+		}
 	}
+	return mons[0], nil
 }
 
 func (a *Annotator) frame(gtx layout.Context) {
+	a.countFrame(gtx.Now)
+	prev := a.size
+	a.size = gtx.Constraints.Max
+	a.checkResize(prev)
+	a.followWindow(gtx)
+	a.applyCapture()
+	a.liveRefresh(gtx)
+	a.layoutBackground()
+	a.applyOCR()
+	a.refreshOCR()
+	a.applyHooks()
+	a.applyControl()
+	a.updateCursor()
+
 	// Pointer events should be scoped to the window rect.
 	area := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
 	event.Op(gtx.Ops, &a.ptrTag)
@@ -133,27 +1160,96 @@ func (a *Annotator) frame(gtx layout.Context) {
 
 	a.handlePointer(gtx)
 	a.handleKeys(gtx)
+	a.handleScrubber(gtx)
+	a.cueColorChange()
 
 	// Background.
 	paint.FillShape(gtx.Ops, color.NRGBA{A: 0}, clip.Rect{Max: gtx.Constraints.Max}.Op())
-	if a.dim {
-		paint.FillShape(gtx.Ops, color.NRGBA{A: 120}, clip.Rect{Max: gtx.Constraints.Max}.Op())
+	a.drawBackground(gtx)
+	if a.spotlight {
+		a.drawSpotlight(gtx)
+	} else if a.ruler {
+		a.drawRuler(gtx)
+	} else if a.dim {
+		paint.FillShape(gtx.Ops, a.dimCol, clip.Rect{Max: gtx.Constraints.Max}.Op())
 	}
 
-	// Draw strokes.
+	// Draw strokes, step connectors below the markers, within the
+	// drawing region if there is one.
+	strokeArea := clip.Rect{Max: gtx.Constraints.Max}
+	if !a.region.Empty() {
+		strokeArea = clip.Rect(a.region)
+	}
+	strokeClip := strokeArea.Push(gtx.Ops)
+	a.drawTrace(gtx)
+	if a.connectSteps {
+		for _, c := range stepConnectors(a.strokes, a.scrubVisible) {
+			drawStroke(gtx.Ops, &c)
+		}
+	}
+	a.stampCommits(gtx.Now)
+	a.snapCommits()
+	a.followGuide()
+	a.evictStrokes(gtx.Now)
+	a.drawEvicting(gtx)
+	flash, flashing := a.flashLevel(gtx.Now)
 	for i := range a.strokes {
-		drawStroke(gtx.Ops, &a.strokes[i])
+		if a.scrubVisible(&a.strokes[i]) {
+			if g, ok := flashGlow(&a.strokes[i], flash); ok {
+				drawStroke(gtx.Ops, &g)
+			}
+			a.paintAnimated(gtx, &a.strokes[i])
+		}
+	}
+	if flashing {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	a.drawReplay(gtx)
+	a.activeTool().Render(a, gtx)
+	a.drawTyping(gtx)
+	a.drawGhost(gtx)
+	strokeClip.Pop()
+	a.drawRegion(gtx)
+	a.drawSelection(gtx)
+
+	a.drawScrubber(gtx)
+	a.drawCoords(gtx)
+	a.drawGuide(gtx)
+	a.drawWidthHint(gtx)
+
+	if a.hexEntry {
+		a.drawHexEntry(gtx)
+	}
+	if a.paletteEdit {
+		a.drawPaletteEdit(gtx)
 	}
-	if a.cur != nil {
-		drawStroke(gtx.Ops, a.cur)
+	if a.cmdOpen {
+		a.drawCommands(gtx)
 	}
+	if a.labelEntry {
+		a.drawLabelEntry(gtx)
+	}
+	a.drawHistory(gtx)
+	a.checkHeavy()
+	a.drawHeavy(gtx)
+	a.drawToast(gtx)
+	a.drawPins(gtx)
+	a.drawPenCursor(gtx)
+	a.drawPending(gtx)
+	a.idleClear(gtx)
+	a.autosave(gtx)
+	a.trackColor()
+	a.trackTool()
+	a.trackUndo()
+	a.publishMirror()
 }
 
 func (a *Annotator) handlePointer(gtx layout.Context) {
 	for {
 		ev, ok := gtx.Event(pointer.Filter{
-			Target: &a.ptrTag,
-			Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+			Target:  &a.ptrTag,
+			Kinds:   pointer.Move | pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel | pointer.Leave | pointer.Scroll,
+			ScrollX: tiltScroll,
 		})
 		if !ok {
 			break
@@ -163,31 +1259,91 @@ func (a *Annotator) handlePointer(gtx layout.Context) {
 			log.Printf("pointer: kind=%v pos=(%.1f,%.1f) buttons=%v", pe.Kind, pe.Position.X, pe.Position.Y, pe.Buttons)
 			a.lastLogAt = time.Now()
 		}
+		a.ptr = pe.Position
+		a.ptrIn = pe.Kind != pointer.Leave && pe.Kind != pointer.Cancel
+		a.activeAt = gtx.Now
 		switch pe.Kind {
+		case pointer.Move, pointer.Leave:
+			if a.spotlight || a.showCoords || a.placing != nil || a.cursorHidden || a.hoverPreview || a.tool == toolEraser {
+				gtx.Execute(op.InvalidateCmd{})
+			}
+			if a.tool == toolLaser && pe.Kind == pointer.Move {
+				a.pushLaser(gtx, pe.Position)
+			}
+		case pointer.Scroll:
+			a.tiltWheel(gtx, pe)
+			continue
 		case pointer.Press:
-			if pe.Buttons&pointer.ButtonPrimary == 0 {
+			if a.pressButtons(gtx, pe) {
 				continue
 			}
-			a.cur = &Stroke{Col: a.col, Width: dpToPx(gtx, a.widthDp)}
-			a.cur.Pts = append(a.cur.Pts, pe.Position)
+			if pe.Buttons&pointer.ButtonPrimary == 0 || a.pending != nil {
+				continue
+			}
+			if a.confirmClear(gtx.Now) {
+				continue
+			}
+			if a.placing != nil {
+				a.finishPlacing()
+				continue
+			}
+			if a.regionPick {
+				a.regionFrom, a.regionSizing = pe.Position, true
+				continue
+			}
+			if a.ruler && pe.Modifiers.Contain(key.ModShortcut) {
+				a.rulerY, a.rulerDrag = pe.Position.Y, true
+				continue
+			}
+			if a.startHandleDrag(gtx, pe.Position) {
+				continue
+			}
+			if !a.inRegion(pe.Position) {
+				continue
+			}
+			a.activeTool().Press(a, gtx, pe)
 		case pointer.Drag:
-			if a.cur == nil {
+			if a.regionSizing {
+				gtx.Execute(op.InvalidateCmd{})
+				continue
+			}
+			if a.rulerDrag {
+				a.rulerY = pe.Position.Y
 				continue
 			}
-			// Interpolate points so the line looks continuous (not dotted).
-			last := a.cur.Pts[len(a.cur.Pts)-1]
-			appendInterpolated(&a.cur.Pts, last, pe.Position, a.cur.Width/2)
+			pe.Position = a.clampToRegion(pe.Position)
+			if a.handleDrag != nil {
+				a.dragHandle(pe.Position)
+				gtx.Execute(op.InvalidateCmd{})
+				continue
+			}
+			a.activeTool().Drag(a, gtx, pe)
 		case pointer.Release, pointer.Cancel:
-			if a.cur != nil {
-				a.strokes = append(a.strokes, *a.cur)
-				a.cur = nil
+			released := a.heldButtons &^ pe.Buttons
+			a.heldButtons = pe.Buttons
+			switch {
+			case !released.Contain(pointer.ButtonPrimary) && pe.Kind == pointer.Release:
+				// Another button; the drag goes on.
+			case a.regionSizing:
+				a.finishRegion()
+			case a.rulerDrag:
+				a.rulerDrag = false
+			case a.handleDrag != nil:
+				a.finishHandleDrag()
+			default:
+				a.activeTool().Release(a, gtx, pe)
 			}
+			// After the tool, which a held button may switch back.
+			a.releaseButtons(gtx, released)
 		}
 	}
 
-	// Keep animating while drawing.
-	if a.cur != nil {
-		gtx.Execute(op.InvalidateCmd{})
+	// Input brings the frames while drawing; only a prediction needs
+	// one more, to drop it when it runs out.
+	if a.cur != nil && a.predict {
+		if at, ok := a.predictor.expiry(); ok {
+			gtx.Execute(op.InvalidateCmd{At: at})
+		}
 	}
 }
 
@@ -198,13 +1354,46 @@ func (a *Annotator) handleKeys(gtx layout.Context) {
 		if !ok {
 			break
 		}
-		if fe, ok := ev.(key.FocusEvent); ok && a.debug {
-			log.Printf("key focus: %v", fe.Focus)
+		switch ev := ev.(type) {
+		case key.FocusEvent:
+			if a.debug {
+				log.Printf("key focus: %v", ev.Focus)
+			}
+		case key.EditEvent:
+			if a.hexEntry {
+				a.hexEdit(ev.Text)
+				gtx.Execute(op.InvalidateCmd{})
+			}
+			if a.cmdOpen {
+				a.commandEdit(ev.Text)
+				gtx.Execute(op.InvalidateCmd{})
+			}
+			if a.labelEntry {
+				a.labelEdit(ev.Text)
+				gtx.Execute(op.InvalidateCmd{})
+			}
+			if a.typing != nil {
+				a.typingEdit(ev.Text)
+				gtx.Execute(op.InvalidateCmd{})
+			}
 		}
 	}
 
 	for {
-		ev, ok := gtx.Event(key.Filter{Focus: &a.keyTag, Name: ""})
+		ev, ok := gtx.Event(transfer.TargetFilter{Target: &a.keyTag, Type: "application/text"})
+		if !ok {
+			break
+		}
+		if de, ok := ev.(transfer.DataEvent); ok {
+			r := de.Open()
+			a.pasteText(gtx, r)
+			r.Close()
+			gtx.Execute(op.InvalidateCmd{})
+		}
+	}
+
+	for {
+		ev, ok := gtx.Event(key.Filter{Focus: &a.keyTag, Name: "", Optional: key.ModShift | key.ModShortcut | key.ModAlt})
 		if !ok {
 			break
 		}
@@ -212,63 +1401,408 @@ func (a *Annotator) handleKeys(gtx layout.Context) {
 		if ke.State != key.Press {
 			continue
 		}
+		a.activeAt = gtx.Now
 		if a.debug {
 			log.Printf("key: name=%q mods=%v", ke.Name, ke.Modifiers)
 		}
-		switch ke.Name {
-		case "R":
-			a.col = color.NRGBA{R: 255, A: 255}
-		case "G":
-			a.col = color.NRGBA{G: 255, A: 255}
-		case "B":
-			a.col = color.NRGBA{B: 255, A: 255}
-		case "Y":
-			a.col = color.NRGBA{R: 255, G: 255, A: 255}
-		case "O":
-			a.col = color.NRGBA{R: 255, G: 165, A: 255}
-		case "P":
-			a.col = color.NRGBA{R: 255, G: 105, B: 180, A: 255}
-		case "X":
-			// "Blur" pen: wide semi-transparent black.
-			a.col = color.NRGBA{A: 0x40}
-			a.widthDp = 20
-		case "1":
-			a.widthDp = 3
-		case "2":
-			a.widthDp = 6
-		case "3":
-			a.widthDp = 12
-		case "A":
-			a.dim = !a.dim
-		case "C":
-			a.strokes = nil
-			a.cur = nil
-		case "T":
-			// Toggle click-through (X11 ShapeInput).
-			a.clickThrough = !a.clickThrough
-			if a.x11Display != nil && a.x11Window != 0 {
-				_ = x11SetClickThrough(a.x11Display, a.x11Window, a.clickThrough)
+		a.handleKey(gtx, ke)
+		gtx.Execute(op.InvalidateCmd{})
+	}
+}
+
+// handleKey acts on a key press: prompts and editors that are open take
+// it first, then the quit key, shortcuts and the plain keys. The command
+// palette runs its actions through here too.
+func (a *Annotator) handleKey(gtx layout.Context, ke key.Event) {
+	if a.pending != nil {
+		a.pendingKey(ke)
+		return
+	}
+	if a.hexEntry {
+		a.hexKey(ke)
+		return
+	}
+	if a.paletteEdit {
+		a.paletteKey(ke)
+		return
+	}
+	if a.cmdOpen {
+		a.commandKey(gtx, ke)
+		return
+	}
+	if a.labelEntry {
+		a.labelKey(ke)
+		return
+	}
+	if a.typing != nil {
+		a.typingKey(ke)
+		return
+	}
+	if a.quitKey.matches(ke) {
+		a.requestQuit(gtx.Now)
+		return
+	}
+	if ke.Modifiers.Contain(key.ModAlt) {
+		a.presetKey(ke)
+		return
+	}
+	if ke.Modifiers.Contain(key.ModShortcut) {
+		a.handleShortcut(gtx, ke)
+		return
+	}
+	switch ke.Name {
+	case "R", "G", "B", "Y", "O", "P":
+		// Pen colors from the current palette (Ctrl+T cycles).
+		if c, ok := a.palette().colors[penColorKeys[ke.Name]]; ok {
+			a.col = c
+		}
+	case ",", ".":
+		// Previous/next palette color.
+		if ke.Name == "." {
+			a.stepColor(1)
+		} else {
+			a.stepColor(-1)
+		}
+	case key.NameF12:
+		// Wireframe view of the stroke points, with ANNOTATOR_DEBUG.
+		a.toggleWireframe()
+	case "X":
+		// Blur pen: blurs the captured background under it.
+		a.toggleTool(toolBlur)
+	case "1", "2", "3":
+		// Width presets, 3, 6 and 12 dp unless the config says otherwise.
+		a.widthDp = a.widthKeys[ke.Name[0]-'1']
+	case "A":
+		// Cycle off -> dim -> lighten -> off.
+		switch {
+		case !a.dim:
+			a.dim, a.dimCol = true, a.dimColor(dimDark)
+		case a.dimCol == a.dimColor(dimDark):
+			a.dimCol = a.dimColor(dimLight)
+		default:
+			a.dim = false
+		}
+	case "F":
+		// Spotlight: dim everything except a soft circle at the pointer;
+		// Shift+F, the reading ruler (ruler.go).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.toggleRuler()
+		} else {
+			a.spotlight = !a.spotlight
+		}
+	case "C":
+		// Clear (Shift: keep redactions and fills); see clear.go.
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.confirmThen("Clear the markup, keeping redactions and fills?", a.clearMarkup)
+		} else {
+			a.confirmThen("Clear all strokes (pins and background stay)?", a.clearStrokes)
+		}
+	case "T":
+		if ke.Modifiers.Contain(key.ModShift) {
+			// Text tool: click and type (typing.go).
+			a.toggleTool(toolText)
+			break
+		}
+		// Toggle click-through (X11 ShapeInput).
+		a.clickThrough = !a.clickThrough
+		if a.x11Display != nil && a.x11Window != 0 {
+			if err := x11SetClickThrough(a.x11Display, a.x11Window, a.clickThrough); err != nil {
+				a.notifyErr(fmt.Errorf("click-through: %w", err))
 			}
-		case "[":
-			// More transparent
-			if a.opacity > 0x08000000 { a.opacity -= 0x08000000 }
-			if a.x11Display != nil && a.x11Window != 0 {
-				_ = x11SetOpacity(a.x11Display, a.x11Window, a.opacity)
+		}
+	case "-":
+		// Thinner pen.
+		a.widthDp = max(a.widthDp-float32(a.nudgeSteps(ke.Name)), 1)
+	case "=", "+":
+		// Thicker pen.
+		a.widthDp = min(a.widthDp+float32(a.nudgeSteps(ke.Name)), 100)
+	case "[":
+		// More transparent
+		for range a.nudgeSteps(ke.Name) {
+			if a.opacity > 0x08000000 {
+				a.opacity -= 0x08000000
 			}
-		case "]":
-			// More opaque
-			if a.opacity < 0xF0000000 { a.opacity += 0x08000000 }
-			if a.x11Display != nil && a.x11Window != 0 {
-				_ = x11SetOpacity(a.x11Display, a.x11Window, a.opacity)
+		}
+		if a.x11Display != nil && a.x11Window != 0 {
+			_ = x11SetOpacity(a.x11Display, a.x11Window, a.opacity)
+		}
+	case "]":
+		// More opaque
+		for range a.nudgeSteps(ke.Name) {
+			if a.opacity < 0xF0000000 {
+				a.opacity += 0x08000000
 			}
-		case key.NameEscape:
-			os.Exit(0)
 		}
-		gtx.Execute(op.InvalidateCmd{})
+		if a.x11Display != nil && a.x11Window != 0 {
+			_ = x11SetOpacity(a.x11Display, a.x11Window, a.opacity)
+		}
+	case "I":
+		// Pointer coordinate readout.
+		a.toggleCoords()
+	case "V":
+		// Playback scrubber to review the drawing order.
+		a.scrubber = !a.scrubber
+		a.scrubbing = false
+	case "N":
+		// Snap freehand lines/circles/rectangles to clean shapes.
+		a.recognize = !a.recognize
+	case "W":
+		// Dynamic width: faster strokes come out thinner.
+		a.dynWidth = !a.dynWidth
+	case "J":
+		// Join new strokes to the end of the previous one when
+		// started near it (Shift at press does so for one stroke);
+		// Shift+J merges quick restarts into it (merge.go).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.toggleMerge()
+		} else {
+			a.joinStrokes = !a.joinStrokes
+		}
+	case "Q":
+		// Curved arrow: freehand shaft with an arrowhead at the end.
+		a.toggleTool(toolArrow)
+	case "K":
+		// Redaction pen: pixelates the captured background under
+		// the stroke, in the exports as well. (X is the quick
+		// translucent smear.)
+		a.toggleTool(toolPixelate)
+	case "M":
+		// Measure: a straight line labeled with its length and
+		// angle.
+		a.toggleTool(toolMeasure)
+	case "Z":
+		// Confine drawing to a dragged-out region, or stop.
+		a.toggleRegion()
+	case "S":
+		// Numbered step markers: each click places the next number.
+		a.toggleTool(toolStep)
+	case "D":
+		// Fill bucket: each click floods the similar background
+		// area under it with the pen color.
+		a.toggleTool(toolFill)
+	case ">":
+		// Turn the last scribble into a clean arrow (Shift+.).
+		a.edit(a.arrowifyLast)
+	case "<":
+		// Arrowhead style (Shift+,): open -> closed -> barbed,
+		// then the same at both ends.
+		a.cycleArrowStyle()
+	case "#":
+		// Precise color entry (Shift+3).
+		a.startHexEntry()
+	case "{":
+		// Harder spotlight edge (Shift+[).
+		a.spotFalloffDp = max(a.spotFalloffDp-10*float32(a.nudgeSteps(ke.Name)), 0)
+	case "}":
+		// Softer spotlight edge (Shift+]).
+		a.spotFalloffDp = min(a.spotFalloffDp+10*float32(a.nudgeSteps(ke.Name)), 200)
+	case key.NameRightArrow:
+		// Select the next stroke for inspection (see select.go).
+		a.stepSelection(1)
+	case key.NameLeftArrow:
+		a.stepSelection(-1)
+	case key.NameUpArrow, key.NameDownArrow:
+		// Move the reading ruler a line (Shift: resize it).
+		a.rulerKey(gtx, ke)
+	case key.NameDeleteForward, key.NameDeleteBackward:
+		a.edit(a.deleteSelected)
+	case key.NameSpace:
+		// Freeze the -live background, and back.
+		a.toggleFreeze()
+	case "L":
+		if ke.Modifiers.Contain(key.ModShift) {
+			// Laser pointer: a fading trail, no strokes (laser.go).
+			a.toggleTool(toolLaser)
+			break
+		}
+		// Chalk brush for the pen and arrow, and back.
+		a.toggleChalk()
+	case "U":
+		// Symmetry: off -> vertical axis -> horizontal axis -> off.
+		a.cycleSymmetry()
+	case "H":
+		if ke.Modifiers.Contain(key.ModShift) {
+			// Highlighter: translucent, with a flat nib (highlight.go).
+			a.toggleTool(toolHighlight)
+			break
+		}
+		// Emphasis: double the width, and back.
+		a.toggleEmphasis()
+	case "E":
+		if ke.Modifiers.Contain(key.ModShift) {
+			// Eraser: whole strokes under the pointer (eraser.go).
+			a.toggleTool(toolEraser)
+			break
+		}
+		// Give the selected (or last) stroke the current width.
+		a.edit(func() bool { return a.setTargetWidth(gtx) })
+	case key.NamePageUp:
+		// Bring the selected stroke to the front.
+		a.edit(func() bool { return a.raiseSelected(true) })
+	case key.NamePageDown:
+		// Send it to the back.
+		a.edit(func() bool { return a.raiseSelected(false) })
+	case key.NameReturn, key.NameEnter:
+		// Start the -replay.
+		a.startReplay(gtx.Now)
+	case key.NameTab:
+		// Skip a -template placeholder (Shift: back to the previous).
+		a.skipStep(ke.Modifiers.Contain(key.ModShift))
+	case key.NameEscape:
+		a.cancel()
 	}
 }
 
-
+// handleShortcut handles Ctrl (Cmd on macOS) key combinations, kept apart
+// from the single-letter pen keys so that e.g. Ctrl+C never clears.
+func (a *Annotator) handleShortcut(gtx layout.Context, ke key.Event) {
+	switch ke.Name {
+	case "1", "2", "3", "4", "5", "6", "7", "8":
+		// Recent custom colors, newest first.
+		a.selectRecent(int(ke.Name[0] - '1'))
+	case "R":
+		// Recapture the screen, e.g. after rearranging the windows below
+		// or changing the monitor layout.
+		a.replaceWindow()
+		a.requestCapture(0)
+	case "T":
+		// Switch between the light- and dark-background palettes.
+		a.cycleTheme()
+	case "L":
+		if ke.Modifiers.Contain(key.ModShift) {
+			// Lock or unlock the selected (or last) stroke.
+			a.edit(a.toggleLock)
+			break
+		}
+		// Show or hide the arrows linking the step markers.
+		a.connectSteps = !a.connectSteps
+		if a.connectSteps {
+			a.notify("Step connectors: on")
+		} else {
+			a.notify("Step connectors: off")
+		}
+	case "G":
+		// Blink the selected (or last) stroke to draw attention to it
+		// (Shift: make the whole drawing glow once).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.flashAll(gtx.Now)
+		} else {
+			a.pulseTarget(gtx.Now)
+		}
+	case "A":
+		// Black or white halos for new strokes, against the background.
+		a.toggleAutoContrast()
+	case "D":
+		// Duplicate the selected (or last) stroke a bit down and to
+		// the right, for repeated elements.
+		off := dpToPx(gtx, 16)
+		a.edit(func() bool { return a.duplicateTarget(f32.Pt(off, off)) })
+	case "[", "]":
+		// Rotate the selected (or last) stroke by 15 degrees, ] clockwise.
+		steps := 1
+		if ke.Name == "[" {
+			steps = -1
+		}
+		a.edit(func() bool { return a.rotateTarget(steps) })
+	case "H":
+		// Fill (Ctrl+Shift+H: outline) opacity of new shapes.
+		a.cycleShapeAlpha(ke.Modifiers.Contain(key.ModShift))
+	case ".":
+		// Dot tool: a point per click.
+		a.toggleTool(toolDot)
+	case "K":
+		// Connector tool for flowchart-style arrows (Shift: the caps
+		// of new lines).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.cycleCap()
+		} else {
+			a.toggleTool(toolConnector)
+		}
+	case "Z":
+		// Undo the last stroke (Shift: redo).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.redo(gtx)
+		} else {
+			a.undo(gtx)
+		}
+	case "Y":
+		a.redo(gtx)
+	case "X":
+		// Back to the previous pen color, and forth (Shift: the
+		// previous tool).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.swapTool()
+		} else {
+			a.swapColor()
+		}
+	case "J":
+		// Lasso tool: a freehand loop, closed and filled (Shift: the
+		// joins of new lines).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.cycleJoin()
+		} else {
+			a.toggleTool(toolLasso)
+		}
+	case "M":
+		// Dimension lines (Shift: edit the label of one).
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.startLabelEntry()
+		} else {
+			a.toggleTool(toolDimension)
+		}
+	case "W":
+		// Word tool: highlight (Shift: underline) words read by -ocr.
+		a.toggleTool(toolWord)
+	case "I":
+		// Icon stamps; again for the next icon (icon.go).
+		a.iconKey()
+	case "N":
+		// Lines, rectangles, ellipses and arrows by dragging; again for
+		// the next shape (shape.go).
+		a.shapeKey()
+	case "U":
+		// Hollow pen and arrow strokes: only their outline.
+		a.toggleHollow()
+	case "O":
+		// Straight or orthogonal connectors.
+		a.toggleConnectorRouting()
+	case "E":
+		// Edit the current palette.
+		a.togglePaletteEdit()
+	case "S":
+		// Save to -out (and quit with -oneshot).
+		a.save(gtx.Now)
+	case "F":
+		// Make the -trace layer part of the drawing.
+		a.flattenTrace()
+	case "P":
+		if ke.Modifiers.Contain(key.ModShift) {
+			// The command palette.
+			a.openCommands()
+			break
+		}
+		// Switch between the before/after panes.
+		a.switchPane()
+	case "B":
+		// Fit, fill or stretch a loaded background.
+		a.cycleBackgroundFit()
+	case "V":
+		// Paste clipboard text as a text annotation; the text arrives
+		// as a transfer.DataEvent (see handleKeys). Shift: the history
+		// of exports, to copy one again.
+		if ke.Modifiers.Contain(key.ModShift) {
+			a.toggleHistory()
+		} else {
+			gtx.Execute(clipboard.ReadCmd{Tag: &a.keyTag})
+		}
+	case "C":
+		// Copy the annotations as SVG text for pasting into vector apps.
+		svg := strokesSVG(a.exportStrokes(a.strokes), a.size)
+		gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(svg))})
+		a.notify("Copied %d strokes as SVG", len(a.strokes))
+		a.sounds.play(cueExport)
+	}
+}
 
 func dpToPx(gtx layout.Context, dp float32) float32 {
 	return float32(gtx.Metric.PxPerDp) * dp
@@ -300,13 +1834,141 @@ func appendInterpolated(dst *[]f32.Point, a, b f32.Point, spacing float32) {
 	}
 }
 
+// paintStroke draws any kind of stroke: text, pixelate or plain.
+func (a *Annotator) paintStroke(gtx layout.Context, s *Stroke) {
+	switch {
+	case a.wireframe:
+		a.drawWireframe(gtx, s)
+	case s.Text != "":
+		a.drawTextStroke(gtx, s)
+	case s.Blur:
+		a.drawBlurStroke(gtx, s)
+	case s.Pixelate:
+		a.drawPixelStroke(gtx, s)
+	case s.Measure:
+		a.drawMeasure(gtx, s)
+	case s.Dimension:
+		a.drawDimension(gtx, s)
+	case s.Step > 0:
+		a.drawStep(gtx, s)
+	case s.Icon != "":
+		a.drawIcon(gtx, s)
+	case s.Fill != nil:
+		a.drawFill(gtx, s)
+	default:
+		drawStroke(gtx.Ops, s)
+	}
+}
+
 func drawStroke(ops *op.Ops, s *Stroke) {
 	if len(s.Pts) == 0 {
 		return
 	}
-	r := int(math.Max(1, float64(s.Width/2)))
-	for _, p := range s.Pts {
-		rect := image.Rect(int(p.X)-r, int(p.Y)-r, int(p.X)+r, int(p.Y)+r)
-		paint.FillShape(ops, s.Col, clip.Ellipse(rect).Op(ops))
+	if h, ok := haloStroke(s); ok {
+		drawStroke(ops, &h)
+	}
+	drawShapeFill(ops, s)
+	if s.Highlight {
+		drawHighlight(ops, s)
+		return
+	}
+	if s.exactShape() {
+		drawShape(ops, s)
+		return
+	}
+	body := s
+	if s.Hollow {
+		h := hollowStroke(s)
+		body = &h
+	}
+	switch {
+	case body.Chalk:
+		drawChalk(ops, body)
+	case body.styledLine():
+		drawStyledLine(ops, body)
+	default:
+		drawPolyline(ops, body.Pts, body.Widths, body.Col, body.Width)
 	}
-}
\ No newline at end of file
+	drawHeads(ops, s)
+}
+
+// drawPolyline draws pts as one line of the given width, or of widths[i]
+// at pts[i] if widths is set, filled as a single shape so that
+// translucent strokes come out evenly, without darker overlaps. Lines of
+// one width are stroked along a smoothing of pts, with the round caps and
+// joins of clip.Stroke; dynamic-width lines and dots are the union of the
+// round stamps of stampPath.
+func drawPolyline(ops *op.Ops, pts []f32.Point, widths []float32, col color.NRGBA, width float32) {
+	if len(pts) == 0 {
+		return
+	}
+	if widths == nil && pathLength(pts) > 0 {
+		paint.FillShape(ops, col, clip.Stroke{Path: smoothPath(ops, pts), Width: max(2, width)}.Op())
+		return
+	}
+	paint.FillShape(ops, col, clip.Outline{Path: stampsPath(ops, pts, widths, width)}.Op())
+}
+
+// smoothPath is pts as a path of quadratic curves through the midpoints
+// between them, with the points as controls, so input noise and
+// interpolation do not show as corners. Stored points stay as they are.
+func smoothPath(ops *op.Ops, pts []f32.Point) clip.PathSpec {
+	var p clip.Path
+	p.Begin(ops)
+	p.MoveTo(pts[0])
+	for i := 1; i < len(pts)-1; i++ {
+		p.QuadTo(pts[i], pts[i].Add(pts[i+1]).Mul(0.5))
+	}
+	p.LineTo(pts[len(pts)-1])
+	return p.End()
+}
+
+// stampsPath is the union of the stamps stampPath places along pts, as
+// one path of circles all wound the same way.
+func stampsPath(ops *op.Ops, pts []f32.Point, widths []float32, width float32) clip.PathSpec {
+	// Control points of a quarter circle of radius 1.
+	const k = 0.5523
+	var p clip.Path
+	p.Begin(ops)
+	stampPath(pts, widths, width, func(rect image.Rectangle) {
+		r := float32(rect.Dx()) / 2
+		c := layout.FPt(rect.Min).Add(f32.Pt(r, r))
+		p.MoveTo(c.Add(f32.Pt(r, 0)))
+		p.CubeTo(c.Add(f32.Pt(r, k*r)), c.Add(f32.Pt(k*r, r)), c.Add(f32.Pt(0, r)))
+		p.CubeTo(c.Add(f32.Pt(-k*r, r)), c.Add(f32.Pt(-r, k*r)), c.Add(f32.Pt(-r, 0)))
+		p.CubeTo(c.Add(f32.Pt(-r, -k*r)), c.Add(f32.Pt(-k*r, -r)), c.Add(f32.Pt(0, -r)))
+		p.CubeTo(c.Add(f32.Pt(k*r, -r)), c.Add(f32.Pt(r, -k*r)), c.Add(f32.Pt(r, 0)))
+	})
+	return p.End()
+}
+
+// stampPath calls stamp with the bounds of every round stamp along pts,
+// of the given width or of widths[i] at pts[i] if widths is set.
+func stampPath(pts []f32.Point, widths []float32, width float32, stamp func(rect image.Rectangle)) {
+	if len(pts) == 0 {
+		return
+	}
+	radius := func(i int) float32 {
+		if widths != nil {
+			return max(1, widths[i]/2)
+		}
+		return max(1, width/2)
+	}
+	at := func(p f32.Point, rf float32) {
+		r := int(rf)
+		stamp(image.Rect(int(p.X)-r, int(p.Y)-r, int(p.X)+r, int(p.Y)+r))
+	}
+	at(pts[0], radius(0))
+	for i := 1; i < len(pts); i++ {
+		p0, p1 := pts[i-1], pts[i]
+		r0, r1 := radius(i-1), radius(i)
+		// Raw strokes keep only the input samples; fill the gaps here
+		// so they render as continuously as interpolated ones.
+		steps := int(dist(p0, p1) / float32(int(min(r0, r1))))
+		for j := 1; j < steps; j++ {
+			t := float32(j) / float32(steps)
+			at(p0.Add(p1.Sub(p0).Mul(t)), r0+(r1-r0)*t)
+		}
+		at(p1, r1)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+)
+
+// measureLabel describes a measure line: its Euclidean length in px and
+// its angle in degrees, counter-clockwise from pointing right as on a
+// protractor (screen y grows downwards).
+func measureLabel(s *Stroke) string {
+	if len(s.Pts) < 2 {
+		return ""
+	}
+	from, to := s.Pts[0], s.Pts[len(s.Pts)-1]
+	d := to.Sub(from)
+	deg := math.Atan2(float64(-d.Y), float64(d.X)) * 180 / math.Pi
+	return fmt.Sprintf("%.0f px  %.0f°", dist(from, to), deg)
+}
+
+// measureLabelAt is where the label of a measure line goes: just below
+// and to the right of its midpoint.
+func measureLabelAt(s *Stroke, off float32) f32.Point {
+	from, to := s.Pts[0], s.Pts[len(s.Pts)-1]
+	return from.Add(to).Mul(0.5).Add(f32.Pt(off, off))
+}
+
+func (a *Annotator) drawMeasure(gtx layout.Context, s *Stroke) {
+	drawStroke(gtx.Ops, s)
+	if txt := measureLabel(s); txt != "" {
+		p := measureLabelAt(s, float32(gtx.Dp(8)))
+		a.drawLabel(gtx, image.Pt(int(p.X), int(p.Y)), txt, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	}
+}
+
+// rasterMeasureLabel draws the label of a measure line onto dst.
+func rasterMeasureLabel(dst *image.RGBA, s *Stroke) {
+	if txt := measureLabel(s); txt != "" {
+		p := measureLabelAt(s, 8)
+		rasterLabel(dst, image.Pt(int(p.X), int(p.Y)), txt)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// Merging (-merge, Shift+J) mends lines broken by a pen that briefly
+// loses contact with the tablet: a pen stroke pressed within -merge-gap
+// of the previous release and -merge-dist of where that stroke ended
+// continues it instead of starting a new one. The continuation is drawn
+// as a stroke of its own, from the previous end, and only on release are
+// its points appended to the previous stroke, so Escape or anything else
+// dropping it leaves that stroke as it was. Shift at press, as for
+// joining, draws a separate stroke.
+
+// mergePress starts the stroke of a pen press as the continuation of the
+// last stroke if it may be one, and reports whether it did.
+func (a *Annotator) mergePress(gtx layout.Context, pe pointer.Event) bool {
+	a.merging = false
+	n := len(a.strokes)
+	if !a.merge || pe.Modifiers.Contain(key.ModShift) || a.tool != toolPen || n == 0 || n != a.mergeLen || pe.Time-a.mergeAt > a.mergeGap {
+		return false
+	}
+	// Mirrored strokes come in pairs, the last one being the mirror,
+	// and shapes are recognized from whole strokes.
+	if a.symmetry != symmetryOff || a.recognize {
+		return false
+	}
+	last := &a.strokes[n-1]
+	if len(last.Pts) == 0 || last.Arrow || last.Pixelate || last.Measure || last.FillAlpha != 0 || last.Locked || last.Col != a.col || last.Chalk != a.chalk || last.Hollow != a.hollow || (last.Widths != nil) != a.dynWidth || last.Join != a.penJoin || last.Cap != a.penCap ||
+		last.Widths == nil && last.Width != dpToPx(gtx, a.widthDp) {
+		return false
+	}
+	end := last.Pts[len(last.Pts)-1]
+	if dist(pe.Position, end) > dpToPx(gtx, a.mergeDistDp) {
+		return false
+	}
+	a.cur = a.newStroke(gtx, end)
+	appendInterpolated(&a.cur.Pts, end, pe.Position, a.cur.Width/2)
+	if a.cur.Widths != nil {
+		a.cur.Widths[0] = last.Widths[len(last.Widths)-1]
+		a.cur.extendWidths(a.cur.Widths[0])
+	}
+	a.merging = true
+	return true
+}
+
+// finishMerge appends the continuation to the last stroke, if that is
+// still the one it continues, and reports whether it did.
+func (a *Annotator) finishMerge() bool {
+	if !a.merging || len(a.strokes) != a.mergeLen {
+		return false
+	}
+	a.merging = false
+	last := &a.strokes[len(a.strokes)-1]
+	last.Pts = append(last.Pts, a.cur.Pts[1:]...)
+	if last.Widths != nil {
+		last.Widths = append(last.Widths, a.cur.Widths[1:]...)
+	}
+	if a.debug {
+		log.Printf("merged %d points into the previous stroke", len(a.cur.Pts)-1)
+	}
+	return true
+}
+
+// penReleased notes the release, at t, of the pen stroke that the next
+// one may continue.
+func (a *Annotator) penReleased(t time.Duration) {
+	a.mergeLen, a.mergeAt = len(a.strokes), t
+}
+
+// toggleMerge switches merging on or off.
+func (a *Annotator) toggleMerge() {
+	a.merge = !a.merge
+	if a.merge {
+		a.notify("Merging strokes resumed within %v", a.mergeGap)
+	} else {
+		a.notify("Merging strokes off")
+	}
+}
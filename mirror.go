@@ -0,0 +1,87 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// A mirror (-mirror) is a second window, on another monitor, that shows
+// what an overlay draws for an audience looking at that monitor. It has no
+// state of its own: after every frame the overlay publishes a copy of its
+// strokes to the mirror's feed, which the mirror's event loop, a different
+// goroutine, only ever reads. It takes no input and is click-through.
+
+// mirrorFeed is what an overlay last published to its mirror. Published
+// values are never modified afterwards, so a reader may keep using them
+// after unlocking.
+type mirrorFeed struct {
+	mu      sync.Mutex
+	strokes []Stroke
+	bg      *image.RGBA // for pixelate strokes
+	connect bool        // show step connectors
+}
+
+// publishMirror hands the current drawing, including the stroke in
+// progress, to the mirror and has it redrawn.
+func (a *Annotator) publishMirror() {
+	m := a.mirror
+	if m == nil {
+		return
+	}
+	strokes := make([]Stroke, 0, len(a.strokes)+1)
+	for _, s := range a.strokes {
+		strokes = append(strokes, cloneStroke(s))
+	}
+	if a.cur != nil {
+		strokes = append(strokes, cloneStroke(*a.cur))
+		if m, ok := a.symmetric(a.cur); ok {
+			strokes = append(strokes, m)
+		}
+	}
+	m.feed.mu.Lock()
+	m.feed.strokes, m.feed.bg, m.feed.connect = strokes, a.bg, a.connectSteps
+	m.feed.mu.Unlock()
+	m.w.Invalidate()
+}
+
+// cloneStroke copies s along with its points and widths, which the
+// overlay may still change in place.
+func cloneStroke(s Stroke) Stroke {
+	s.Pts = append(s.Pts[:0:0], s.Pts...)
+	if s.Widths != nil {
+		s.Widths = append(s.Widths[:0:0], s.Widths...)
+	}
+	return s
+}
+
+// mirrorFrame draws the published strokes; it replaces frame for mirrors.
+func (a *Annotator) mirrorFrame(gtx layout.Context) {
+	a.size = gtx.Constraints.Max
+	a.feed.mu.Lock()
+	strokes, bg, connect := a.feed.strokes, a.feed.bg, a.feed.connect
+	a.feed.mu.Unlock()
+	if bg != a.bg {
+		a.bg = bg
+		if a.bgSrc != nil && bg != nil {
+			a.bgOp = paint.NewImageOp(bg)
+		}
+	}
+
+	paint.FillShape(gtx.Ops, color.NRGBA{A: 0}, clip.Rect{Max: gtx.Constraints.Max}.Op())
+	// A loaded background is shown, as on the overlay; the composed
+	// one comes with the feed.
+	a.drawBackground(gtx)
+	if connect {
+		for _, c := range stepConnectors(strokes, nil) {
+			drawStroke(gtx.Ops, &c)
+		}
+	}
+	for i := range strokes {
+		a.paintStroke(gtx, &strokes[i])
+	}
+}
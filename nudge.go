@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	"gioui.org/io/key"
+)
+
+// Holding a nudge key (width, opacity, spotlight edge) auto-repeats as
+// a stream of key presses. A tap moves one step; while the same key keeps
+// repeating, the step grows so long adjustments do not take dozens of
+// presses.
+
+const (
+	// Presses of the same key closer than this count as a held key.
+	nudgeRepeatGap = 150 * time.Millisecond
+	// Repeats per extra step of acceleration.
+	nudgeRampEvery = 6
+	nudgeMaxSteps  = 4
+)
+
+// nudgeSteps reports how many steps the press of name should move.
+func (a *Annotator) nudgeSteps(name key.Name) int {
+	now := time.Now()
+	if name == a.nudgeKey && now.Sub(a.nudgeAt) < nudgeRepeatGap {
+		a.nudgeRepeats++
+	} else {
+		a.nudgeRepeats = 0
+	}
+	a.nudgeKey, a.nudgeAt = name, now
+	return min(1+a.nudgeRepeats/nudgeRampEvery, nudgeMaxSteps)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// OCR (-ocr, the path of a tesseract binary) finds the words of the
+// background, in the background, each time it changes (but not while
+// -live refreshes it), so the word tool (Ctrl+W) can mark them exactly: a
+// click highlights the word under the pointer with a translucent stripe
+// of the pen color, a drag every word from there to the release, in
+// reading order and a stripe per line; with Shift held at the press they
+// are underlined with the pen instead.
+
+var toolWord = registerTool("word", wordTool{})
+
+const (
+	// wordSnapDp is how far from a word a press or release still picks it.
+	wordSnapDp = 12
+	// wordHighlightAlpha is the opacity of highlights in an opaque color.
+	wordHighlightAlpha = 0x60
+)
+
+// ocrWord is a word found on the background, in window px.
+type ocrWord struct {
+	r    image.Rectangle
+	line [3]int // block, paragraph and line numbers
+}
+
+// ocrResult carries the words of an image back to the event loop.
+type ocrResult struct {
+	img   *image.RGBA
+	words []ocrWord
+	err   error
+}
+
+// refreshOCR starts reading the background if it is new.
+func (a *Annotator) refreshOCR() {
+	if a.ocrBin == "" || a.ocrBusy || a.bg == nil || a.bg == a.ocrImg || a.liveEvery > 0 && a.bgSrc == nil && !a.frozen {
+		return
+	}
+	img, bin := a.bg, a.ocrBin
+	a.ocrImg, a.ocrBusy = img, true
+	go func() {
+		words, err := runOCR(bin, img)
+		a.ocrDone <- ocrResult{img: img, words: words, err: err}
+		a.w.Invalidate()
+	}()
+}
+
+// applyOCR takes the words of a finished run, if they are still of the
+// current background.
+func (a *Annotator) applyOCR() {
+	select {
+	case res := <-a.ocrDone:
+		a.ocrBusy = false
+		if res.err != nil {
+			a.notifyErr(fmt.Errorf("ocr: %w", res.err))
+			return
+		}
+		if res.img != a.bg {
+			return
+		}
+		a.ocrWords = res.words
+		if a.debug {
+			log.Printf("ocr: %d words", len(res.words))
+		}
+	default:
+	}
+}
+
+// runOCR has tesseract read img and returns the words of its TSV output.
+func runOCR(bin string, img image.Image) ([]ocrWord, error) {
+	f, err := os.CreateTemp("", "screenpen-ocr-*.png")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	err = png.Encode(f, img)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, f.Name(), "stdout", "tsv")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", bin, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", bin, err)
+	}
+	return parseOCR(out)
+}
+
+// parseOCR reads the words off tesseract's TSV: level, page, block,
+// paragraph, line and word numbers, left, top, width, height, confidence
+// and text; words are level 5.
+func parseOCR(tsv []byte) ([]ocrWord, error) {
+	var words []ocrWord
+	sc := bufio.NewScanner(bytes.NewReader(tsv))
+	for n := 0; sc.Scan(); n++ {
+		fields := strings.Split(sc.Text(), "\t")
+		if n == 0 || len(fields) < 12 || fields[0] != "5" || strings.TrimSpace(fields[11]) == "" {
+			// The header, and what is not a word.
+			continue
+		}
+		var v [10]int
+		for i := range v {
+			x, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %q: %w", n+1, fields[i], err)
+			}
+			v[i] = x
+		}
+		words = append(words, ocrWord{
+			r:    image.Rect(v[6], v[7], v[6]+v[8], v[7]+v[9]),
+			line: [3]int{v[2], v[3], v[4]},
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) == 0 && len(tsv) == 0 {
+		return nil, errors.New("no output")
+	}
+	return words, nil
+}
+
+// wordAt is the index of the word at p or nearest to it within snap px,
+// or -1.
+func (a *Annotator) wordAt(p f32.Point, snap float32) int {
+	best, bestD := -1, snap
+	for i, w := range a.ocrWords {
+		lo, hi := layout.FPt(w.r.Min), layout.FPt(w.r.Max)
+		dx := max(lo.X-p.X, 0, p.X-hi.X)
+		dy := max(lo.Y-p.Y, 0, p.Y-hi.Y)
+		if d := dist(f32.Pt(dx, dy), f32.Point{}); d <= bestD {
+			best, bestD = i, d
+		}
+	}
+	return best
+}
+
+// wordMarks are the strokes marking the words from index i to j, one per
+// line: highlights, or underlines of the given width.
+func (a *Annotator) wordMarks(i, j int, underline bool, width float32) []Stroke {
+	if i > j {
+		i, j = j, i
+	}
+	col := a.col
+	if !underline && col.A == 0xff {
+		col.A = wordHighlightAlpha
+	}
+	var marks []Stroke
+	var r image.Rectangle
+	for k := i; k <= j; k++ {
+		r = r.Union(a.ocrWords[k].r)
+		if k < j && a.ocrWords[k+1].line == a.ocrWords[k].line {
+			continue
+		}
+		marks = append(marks, wordMark(r, col, underline, width))
+		r = image.Rectangle{}
+	}
+	return marks
+}
+
+// wordMark marks the words in r.
+func wordMark(r image.Rectangle, col color.NRGBA, underline bool, width float32) Stroke {
+	lo, hi := layout.FPt(r.Min), layout.FPt(r.Max)
+	s := Stroke{Col: col}
+	var from, to f32.Point
+	if underline {
+		y := hi.Y + width/2 + 1
+		from, to, s.Width = f32.Pt(lo.X, y), f32.Pt(hi.X, y), width
+	} else {
+		// A stripe as tall as the words; its round caps end at them.
+		h := hi.Y - lo.Y
+		y := (lo.Y + hi.Y) / 2
+		from, to, s.Width = f32.Pt(lo.X+h/2, y), f32.Pt(max(hi.X-h/2, lo.X+h/2), y), h
+	}
+	s.Pts = polylinePoints([]f32.Point{from, to}, s.Width/2)
+	return s
+}
+
+// wordTool marks OCR words, see above.
+type wordTool struct{}
+
+func (wordTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.wordPreview = nil
+	switch {
+	case a.ocrBin == "":
+		a.notify("The word tool needs -ocr")
+		return
+	case a.liveEvery > 0 && a.bgSrc == nil && !a.frozen:
+		a.notify("Freeze the live background (Space) to mark its words")
+		return
+	case a.ocrBusy || a.bg != a.ocrImg:
+		a.notify("Still reading the words")
+		return
+	}
+	a.wordFrom = a.wordAt(pe.Position, dpToPx(gtx, wordSnapDp))
+	if a.wordFrom < 0 {
+		a.notify("No word here")
+		return
+	}
+	a.wordUnderline = pe.Modifiers.Contain(key.ModShift)
+	a.wordPreview = a.wordMarks(a.wordFrom, a.wordFrom, a.wordUnderline, dpToPx(gtx, a.widthDp))
+}
+
+func (wordTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.wordPreview == nil {
+		return
+	}
+	if to := a.wordAt(pe.Position, dpToPx(gtx, wordSnapDp)); to >= 0 {
+		a.wordPreview = a.wordMarks(a.wordFrom, to, a.wordUnderline, dpToPx(gtx, a.widthDp))
+	}
+}
+
+func (wordTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	for _, s := range a.wordPreview {
+		s.At = gtx.Now
+		a.strokes = append(a.strokes, s)
+	}
+	a.wordPreview = nil
+}
+
+func (wordTool) Render(a *Annotator, gtx layout.Context) {
+	for i := range a.wordPreview {
+		a.paintStroke(gtx, &a.wordPreview[i])
+	}
+}
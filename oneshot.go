@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Saving with Ctrl+S writes the annotations to -out, in any export format
+// (a timestamped PNG without it, in $SCREENPENGO_OUT if set, otherwise
+// the current directory). One-shot mode
+// (-oneshot) is for "grab the screen, draw one thing, save, done": the
+// first export, with Ctrl+S or the control socket, also quits.
+
+// saveName is where Ctrl+S saves without -out.
+const saveName = "screenpen-20060102-150405.png"
+
+// save exports to the save path, and ends a one-shot session.
+func (a *Annotator) save(now time.Time) {
+	path := a.savePath
+	if path == "" {
+		path = now.Format(saveName)
+		if dir := os.Getenv("SCREENPENGO_OUT"); dir != "" {
+			path = filepath.Join(dir, path)
+		}
+	}
+	if err := a.export(path); err != nil {
+		a.notifyErr(err)
+		return
+	}
+	a.sounds.play(cueExport)
+	log.Printf("saved %s", path)
+	if a.endOneshot() {
+		return
+	}
+	a.notify("Saved %s", path)
+}
+
+// endOneshot quits after an export in one-shot mode.
+func (a *Annotator) endOneshot() bool {
+	if !a.oneshot {
+		return false
+	}
+	a.exit()
+	return true
+}
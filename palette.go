@@ -0,0 +1,153 @@
+package main
+
+import (
+	"image/color"
+
+	"gioui.org/io/key"
+)
+
+// penColorKeys maps the color keys to the palette slot they select.
+var penColorKeys = map[key.Name]string{
+	"R": "red",
+	"G": "green",
+	"B": "blue",
+	"Y": "yellow",
+	"O": "orange",
+	"P": "pink",
+}
+
+// palette is a set of colors for the color keys, tuned for one kind of
+// background.
+type palette struct {
+	name   string
+	colors map[string]color.NRGBA // by penColorKeys slot
+	// oneOff marks a palette given with -palette, for this run only;
+	// edited, one changed in the palette editor (paletteedit.go).
+	oneOff, edited bool
+}
+
+// defaultPalettes are the built-in themes, cycled with Ctrl+T: bright
+// colors that stand out on dark screens, and darker, saturated ones that
+// keep their contrast on light screens.
+var defaultPalettes = []palette{
+	{
+		name: "dark-bg",
+		colors: map[string]color.NRGBA{
+			"red":    {R: 255, A: 255},
+			"green":  {G: 255, A: 255},
+			"blue":   {B: 255, A: 255},
+			"yellow": {R: 255, G: 255, A: 255},
+			"orange": {R: 255, G: 165, A: 255},
+			"pink":   {R: 255, G: 105, B: 180, A: 255},
+		},
+	},
+	{
+		name: "light-bg",
+		colors: map[string]color.NRGBA{
+			"red":    {R: 0xc6, G: 0x00, B: 0x00, A: 255},
+			"green":  {R: 0x00, G: 0x7a, B: 0x1f, A: 255},
+			"blue":   {R: 0x00, G: 0x3c, B: 0xc8, A: 255},
+			"yellow": {R: 0xa6, G: 0x7c, B: 0x00, A: 255},
+			"orange": {R: 0xc8, G: 0x50, B: 0x00, A: 255},
+			"pink":   {R: 0xb0, G: 0x1a, B: 0x6e, A: 255},
+		},
+	},
+}
+
+// namedPalettes can be selected by name with -palette; Ctrl+T then still
+// reaches the default ones. okabe-ito is the colorblind-safe set of Okabe
+// and Ito, whose colors stay distinguishable with any kind of color vision
+// deficiency; it has no green or pink, so those keys get its bluish green
+// and reddish purple.
+var namedPalettes = []palette{
+	{
+		name: "okabe-ito",
+		colors: map[string]color.NRGBA{
+			"red":    {R: 0xd5, G: 0x5e, B: 0x00, A: 255}, // vermillion
+			"green":  {R: 0x00, G: 0x9e, B: 0x73, A: 255}, // bluish green
+			"blue":   {R: 0x56, G: 0xb4, B: 0xe9, A: 255}, // sky blue
+			"yellow": {R: 0xf0, G: 0xe4, B: 0x42, A: 255},
+			"orange": {R: 0xe6, G: 0x9f, B: 0x00, A: 255},
+			"pink":   {R: 0xcc, G: 0x79, B: 0xa7, A: 255}, // reddish purple
+		},
+	},
+}
+
+// namedPalette returns the built-in palette called name.
+func namedPalette(name string) (palette, bool) {
+	for _, p := range namedPalettes {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return palette{}, false
+}
+
+func (a *Annotator) palette() palette {
+	return a.palettes[a.paletteIdx]
+}
+
+// cycleTheme switches to the next palette. A pen color taken from the old
+// palette becomes the same slot in the new one, so a red pen stays red.
+func (a *Annotator) cycleTheme() {
+	old := a.palette()
+	a.paletteIdx = (a.paletteIdx + 1) % len(a.palettes)
+	for slot, c := range old.colors {
+		if c == a.col {
+			if nc, ok := a.palette().colors[slot]; ok {
+				a.col = nc
+			}
+			break
+		}
+	}
+	a.notify("Palette: %s", a.palette().name)
+}
+
+// stepColor moves the pen d slots along the palette (paletteSlots order),
+// wrapping around. From a color that is not in the palette it starts at
+// the first or, backwards, the last slot.
+func (a *Annotator) stepColor(d int) {
+	p := a.palette()
+	i := -1
+	for j, slot := range paletteSlots {
+		if p.colors[slot] == a.col {
+			i = j
+			break
+		}
+	}
+	n := len(paletteSlots)
+	switch {
+	case i < 0 && d > 0:
+		i = 0
+	case i < 0:
+		i = n - 1
+	default:
+		i = ((i+d)%n + n) % n
+	}
+	a.col = p.colors[paletteSlots[i]]
+	a.notify("Color: %s", paletteSlots[i])
+}
+
+// trackColor notices pen color changes, however they were made, so that
+// swapColor can go back: prevCol is the color before the current one.
+func (a *Annotator) trackColor() {
+	if a.col == a.lastCol {
+		return
+	}
+	if a.lastCol != (color.NRGBA{}) {
+		a.prevCol = a.lastCol
+	}
+	a.lastCol = a.col
+}
+
+// swapColor switches the pen to the previous color, alternating between
+// the two on repeated use, for good/bad or before/after marks.
+func (a *Annotator) swapColor() {
+	if a.prevCol == (color.NRGBA{}) || a.prevCol == a.col {
+		a.notify("No previous color")
+		return
+	}
+	a.col = a.prevCol
+	a.trackColor()
+	a.notify("Color: %s", formatHexColor(a.col))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"maps"
+	"slices"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The palette editor (Ctrl+E) changes the current palette in the session:
+// a row of its slots under their color keys, one of them picked with the
+// arrows. Enter puts the pen color (say, one from the # prompt) into the
+// picked slot, Shift+arrows move the picked color along the row, so
+// another key selects it, and Delete resets the slot to the default
+// color. Closing the editor with Escape or Ctrl+E saves the palettes to
+// the config file, where they replace the built-in ones from then on.
+// The one given with -palette is only saved once edited: otherwise a
+// palette meant for one run would stay, and come twice with the flag.
+// While it is open, other keys are ignored.
+
+// togglePaletteEdit opens the editor, or closes and saves it.
+func (a *Annotator) togglePaletteEdit() {
+	if !a.paletteEdit {
+		a.paletteEdit, a.paletteSlot, a.paletteDirty = true, 0, false
+		return
+	}
+	a.paletteEdit = false
+	if !a.paletteDirty {
+		return
+	}
+	path, err := a.savePalettes()
+	if err != nil {
+		a.notifyErr(fmt.Errorf("palette: %w", err))
+		return
+	}
+	a.notify("Palettes saved to %s", path)
+}
+
+// paletteKey handles the keys of the open editor.
+func (a *Annotator) paletteKey(ke key.Event) {
+	n := len(paletteSlots)
+	switch ke.Name {
+	case key.NameLeftArrow, key.NameRightArrow:
+		d := 1
+		if ke.Name == key.NameLeftArrow {
+			d = -1
+		}
+		to := ((a.paletteSlot+d)%n + n) % n
+		if ke.Modifiers.Contain(key.ModShift) {
+			from := a.paletteSlot
+			a.editPalette(func(colors map[string]color.NRGBA) {
+				colors[paletteSlots[from]], colors[paletteSlots[to]] = colors[paletteSlots[to]], colors[paletteSlots[from]]
+			})
+		}
+		a.paletteSlot = to
+	case key.NameReturn, key.NameEnter:
+		a.editPalette(func(colors map[string]color.NRGBA) {
+			colors[paletteSlots[a.paletteSlot]] = a.col
+		})
+	case key.NameDeleteForward, key.NameDeleteBackward:
+		a.editPalette(func(colors map[string]color.NRGBA) {
+			slot := paletteSlots[a.paletteSlot]
+			colors[slot] = defaultPalettes[0].colors[slot]
+		})
+	case key.NameEscape:
+		a.togglePaletteEdit()
+	case "E":
+		if ke.Modifiers.Contain(key.ModShortcut) {
+			a.togglePaletteEdit()
+		}
+	}
+}
+
+// editPalette applies edit to a copy of the current palette's colors, as
+// the palettes are shared with the other overlays.
+func (a *Annotator) editPalette(edit func(colors map[string]color.NRGBA)) {
+	a.palettes = slices.Clone(a.palettes)
+	p := &a.palettes[a.paletteIdx]
+	p.colors, p.edited = maps.Clone(p.colors), true
+	edit(p.colors)
+	a.paletteDirty = true
+}
+
+// savePalettes writes the palettes, but for an unedited -palette one,
+// into the config file, keeping the rest of it as it is, and returns its
+// path.
+func (a *Annotator) savePalettes() (string, error) {
+	var pjs []paletteJSON
+	for _, p := range a.palettes {
+		if p.oneOff && !p.edited {
+			continue
+		}
+		pj := paletteJSON{Name: p.name, Colors: make(map[string]string)}
+		for slot, c := range p.colors {
+			pj.Colors[slot] = formatHexColor(c)
+		}
+		pjs = append(pjs, pj)
+	}
+	if a.debug {
+		log.Printf("palette: writing %d palettes", len(pjs))
+	}
+	return saveConfig("palettes", pjs)
+}
+
+// drawPaletteEdit shows the slots of the current palette as swatches with
+// their keys, the picked one outlined, centered near the top.
+func (a *Annotator) drawPaletteEdit(gtx layout.Context) {
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	pos := image.Pt(gtx.Constraints.Max.X/2-gtx.Dp(160), gtx.Dp(24))
+	sz := a.drawLabel(gtx, pos, fmt.Sprintf("Palette %s: arrows pick, Shift+arrows move, Enter pen color, Del reset, Esc save", a.palette().name), white)
+	side, gap := gtx.Dp(32), gtx.Dp(8)
+	y := pos.Y + sz.Y + gap
+	for i, slot := range paletteSlots {
+		x := pos.X + i*(side+gap)
+		sw := image.Rect(x, y, x+side, y+side)
+		rr := clip.UniformRRect(sw, gtx.Dp(4))
+		paint.FillShape(gtx.Ops, a.palette().colors[slot], rr.Op(gtx.Ops))
+		width := gtx.Dp(1)
+		if i == a.paletteSlot {
+			width = gtx.Dp(3)
+		}
+		paint.FillShape(gtx.Ops, white, clip.Stroke{Path: rr.Path(gtx.Ops), Width: float32(width)}.Op())
+		a.drawLabel(gtx, image.Pt(x, y+side+gtx.Dp(4)), string(paletteKeyOf(slot)), white)
+	}
+}
+
+// paletteKeyOf is the color key selecting slot.
+func paletteKeyOf(slot string) key.Name {
+	for k, s := range penColorKeys {
+		if s == slot {
+			return k
+		}
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// paletteSlots lists the palette slots in the order of their keys
+// (R G B Y O P), which is the order colors are taken from palette files.
+var paletteSlots = []string{"red", "green", "blue", "yellow", "orange", "pink"}
+
+// loadPaletteFile reads a GIMP (.gpl) or Paint.NET (.txt) palette. Its
+// first colors go to the color keys in paletteSlots order; slots beyond
+// the file's colors keep the default ones. Lines that are not valid
+// colors are skipped with a warning.
+func loadPaletteFile(path string) (palette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return palette{}, err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var cols []color.NRGBA
+	sc := bufio.NewScanner(f)
+	gimp := false
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case n == 1 && line == "GIMP Palette":
+			gimp = true
+			continue
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case gimp && strings.HasPrefix(line, "Name:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "Name:"))
+			continue
+		case gimp && strings.HasPrefix(line, "Columns:"):
+			continue
+		}
+		var c color.NRGBA
+		if gimp {
+			c, err = parseGIMPColor(line)
+		} else {
+			c, err = parsePaintNETColor(line)
+		}
+		if err != nil {
+			log.Printf("palette %s:%d: %v; skipped", path, n, err)
+			continue
+		}
+		cols = append(cols, c)
+	}
+	if err := sc.Err(); err != nil {
+		return palette{}, err
+	}
+	if len(cols) == 0 {
+		return palette{}, fmt.Errorf("%s: no colors", path)
+	}
+	p := palette{name: name, colors: make(map[string]color.NRGBA)}
+	for i, slot := range paletteSlots {
+		p.colors[slot] = defaultPalettes[0].colors[slot]
+		if i < len(cols) {
+			p.colors[slot] = cols[i]
+		}
+	}
+	if len(cols) > len(paletteSlots) {
+		log.Printf("palette %s: using the first %d of %d colors", path, len(paletteSlots), len(cols))
+	}
+	return p, nil
+}
+
+// parseGIMPColor parses "R G B [name]" with decimal components.
+func parseGIMPColor(line string) (color.NRGBA, error) {
+	f := strings.Fields(line)
+	if len(f) < 3 {
+		return color.NRGBA{}, fmt.Errorf("want R G B, got %q", line)
+	}
+	var rgb [3]uint8
+	for i := range rgb {
+		v, err := strconv.ParseUint(f[i], 10, 8)
+		if err != nil {
+			return color.NRGBA{}, fmt.Errorf("component %q: want 0..255", f[i])
+		}
+		rgb[i] = uint8(v)
+	}
+	return color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}, nil
+}
+
+// parsePaintNETColor parses "AARRGGBB" (or "RRGGBB") hex.
+func parsePaintNETColor(line string) (color.NRGBA, error) {
+	if len(line) == 6 {
+		return parseHexColor(line)
+	}
+	if len(line) != 8 {
+		return color.NRGBA{}, fmt.Errorf("want AARRGGBB, got %q", line)
+	}
+	// Paint.NET puts alpha first.
+	return parseHexColor(line[2:] + line[:2])
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGIMPColor(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want color.NRGBA
+		err  bool
+	}{
+		{in: "255 0 0", want: color.NRGBA{R: 255, A: 255}},
+		{in: "  0 114\t178   Blue", want: color.NRGBA{G: 114, B: 178, A: 255}},
+		{in: "1 2 3 a name with spaces", want: color.NRGBA{R: 1, G: 2, B: 3, A: 255}},
+		{in: "255 0", err: true},
+		{in: "256 0 0", err: true},
+		{in: "-1 0 0", err: true},
+		{in: "ff 00 00", err: true},
+	} {
+		got, err := parseGIMPColor(tc.in)
+		if (err != nil) != tc.err {
+			t.Errorf("parseGIMPColor(%q) error %v, want error %v", tc.in, err, tc.err)
+			continue
+		}
+		if err == nil && got != tc.want {
+			t.Errorf("parseGIMPColor(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParsePaintNETColor(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want color.NRGBA
+		err  bool
+	}{
+		// Alpha comes first, and may be left out.
+		{in: "FFFF0000", want: color.NRGBA{R: 0xff, A: 0xff}},
+		{in: "80FF0000", want: color.NRGBA{R: 0xff, A: 0x80}},
+		{in: "00FF0000", want: color.NRGBA{R: 0xff}},
+		{in: "ff0000", want: color.NRGBA{R: 0xff, A: 0xff}},
+		{in: "FF00000", err: true},
+		{in: "FFFF00000", err: true},
+		{in: "FFGG0000", err: true},
+	} {
+		got, err := parsePaintNETColor(tc.in)
+		if (err != nil) != tc.err {
+			t.Errorf("parsePaintNETColor(%q) error %v, want error %v", tc.in, err, tc.err)
+			continue
+		}
+		if err == nil && got != tc.want {
+			t.Errorf("parsePaintNETColor(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLoadPaletteFile(t *testing.T) {
+	for _, tc := range []struct {
+		name, file, data string
+		wantName         string
+		want             map[string]color.NRGBA // the slots checked
+		err              bool
+	}{
+		{
+			name:     "gimp",
+			file:     "brand.gpl",
+			data:     "GIMP Palette\nName: Brand\nColumns: 4\n# comment\n\n255 0 0 Red\n0 255 0\nnot a color\n0 0 255\n",
+			wantName: "Brand",
+			want: map[string]color.NRGBA{
+				"red":    {R: 255, A: 255},
+				"green":  {G: 255, A: 255},
+				"blue":   {B: 255, A: 255},
+				"yellow": defaultPalettes[0].colors["yellow"],
+			},
+		},
+		{
+			name:     "paint.net",
+			file:     "team.txt",
+			data:     "; Paint.NET palette\n80112233\n445566\nxyz\n",
+			wantName: "team",
+			want: map[string]color.NRGBA{
+				"red":   {R: 0x11, G: 0x22, B: 0x33, A: 0x80},
+				"green": {R: 0x44, G: 0x55, B: 0x66, A: 0xff},
+			},
+		},
+		{
+			name: "more colors than slots",
+			file: "many.gpl",
+			data: "GIMP Palette\n1 1 1\n2 2 2\n3 3 3\n4 4 4\n5 5 5\n6 6 6\n7 7 7\n",
+			want: map[string]color.NRGBA{
+				"red":  {R: 1, G: 1, B: 1, A: 255},
+				"pink": {R: 6, G: 6, B: 6, A: 255},
+			},
+			wantName: "many",
+		},
+		{
+			// Without the header, the lines are read as Paint.NET hex.
+			name: "no header",
+			file: "plain.gpl",
+			data: "255 0 0\n",
+			err:  true,
+		},
+		{name: "empty", file: "empty.gpl", data: "GIMP Palette\n", err: true},
+	} {
+		path := filepath.Join(t.TempDir(), tc.file)
+		if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := loadPaletteFile(path)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: error %v, want error %v", tc.name, err, tc.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if p.name != tc.wantName {
+			t.Errorf("%s: name %q, want %q", tc.name, p.name, tc.wantName)
+		}
+		if len(p.colors) != len(paletteSlots) {
+			t.Errorf("%s: %d slots, want %d", tc.name, len(p.colors), len(paletteSlots))
+		}
+		for slot, want := range tc.want {
+			if got := p.colors[slot]; got != want {
+				t.Errorf("%s: %s = %v, want %v", tc.name, slot, got, want)
+			}
+		}
+	}
+	if _, err := loadPaletteFile(filepath.Join(t.TempDir(), "missing.gpl")); err == nil {
+		t.Error("missing file: no error")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+)
+
+// Pinned notes (-pin, the pin control command) are HUD labels such as
+// "recording": they sit in the top-left corner of the window, one under
+// the other, whatever happens to the strokes and the background. They
+// are not strokes, so clearing, selecting and the timeline leave them
+// alone; PNG exports include them only with -pin-export.
+
+// pinMargin is the distance of the notes from the window corner and each
+// other, in dp on screen and px in exports.
+const pinMargin = 12
+
+// pinFlag collects the repeatable -pin flag.
+type pinFlag []string
+
+func (p *pinFlag) String() string { return "" }
+
+func (p *pinFlag) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+func (a *Annotator) drawPins(gtx layout.Context) {
+	pos := image.Pt(gtx.Dp(pinMargin), gtx.Dp(pinMargin))
+	for _, txt := range a.pins {
+		sz := a.drawLabel(gtx, pos, txt, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+		pos.Y += sz.Y + gtx.Dp(pinMargin/2)
+	}
+}
+
+// rasterPins draws the notes onto an export if -pin-export asks for it.
+func (a *Annotator) rasterPins(dst *image.RGBA) {
+	if !a.pinExport {
+		return
+	}
+	pos := dst.Bounds().Min.Add(image.Pt(pinMargin, pinMargin))
+	for _, txt := range a.pins {
+		sz := rasterLabel(dst, pos, txt)
+		pos.Y += sz.Y + pinMargin/2
+	}
+}
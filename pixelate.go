@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// pixelBlock is the cell size of pixelated (redacted) areas, in px. Cells
+// are aligned to a fixed grid, so overlapping strokes and the on-screen
+// and exported renderings agree.
+const pixelBlock = 12
+
+// pixelPenColor is what a pixelate stroke looks like where there is no
+// background to pixelate: the translucent blur pen.
+var pixelPenColor = color.NRGBA{A: 0x40}
+
+// pixelate returns a copy of the area r of src in which every grid cell is
+// filled with its average color.
+func pixelate(src *image.RGBA, r image.Rectangle) *image.RGBA {
+	dst := image.NewRGBA(r)
+	bounds := src.Bounds()
+	floor := func(v int) int {
+		if v < 0 {
+			return (v - pixelBlock + 1) / pixelBlock * pixelBlock
+		}
+		return v / pixelBlock * pixelBlock
+	}
+	for y := floor(r.Min.Y); y < r.Max.Y; y += pixelBlock {
+		for x := floor(r.Min.X); x < r.Max.X; x += pixelBlock {
+			cell := image.Rect(x, y, x+pixelBlock, y+pixelBlock)
+			var sr, sg, sb, sa, n int
+			in := cell.Intersect(bounds)
+			for py := in.Min.Y; py < in.Max.Y; py++ {
+				i := src.PixOffset(in.Min.X, py)
+				for px := in.Min.X; px < in.Max.X; px++ {
+					sr += int(src.Pix[i])
+					sg += int(src.Pix[i+1])
+					sb += int(src.Pix[i+2])
+					sa += int(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			c := color.RGBA{uint8(sr / n), uint8(sg / n), uint8(sb / n), uint8(sa / n)}
+			out := cell.Intersect(r)
+			for py := out.Min.Y; py < out.Max.Y; py++ {
+				for px := out.Min.X; px < out.Max.X; px++ {
+					dst.SetRGBA(px, py, c)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// pixelBgOp returns the pixelated background as an image op, rebuilt only
+// when the background has been (re)captured.
+func (a *Annotator) pixelBgOp() (paint.ImageOp, bool) {
+	if a.bg == nil {
+		return paint.ImageOp{}, false
+	}
+	if a.pixelOf != a.bg {
+		a.pixelOf = a.bg
+		a.pixelOp = paint.NewImageOp(pixelate(a.bg, a.bg.Bounds()))
+	}
+	return a.pixelOp, true
+}
+
+// drawPixelStroke shows the pixelated background through the stroke.
+func (a *Annotator) drawPixelStroke(gtx layout.Context, s *Stroke) {
+	img, ok := a.pixelBgOp()
+	if !ok {
+		drawStroke(gtx.Ops, s)
+		return
+	}
+	defer clip.Outline{Path: stampsPath(gtx.Ops, s.Pts, s.Widths, s.Width)}.Op().Push(gtx.Ops).Pop()
+	img.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+
+	"gioui.org/f32"
+)
+
+// Pixel snapping (-pixel-snap) keeps thin lines crisp: the antialiased
+// edges of a 1px line between pixel centers smear it into two gray rows.
+// Strokes up to pixelSnapMax px wide are snapped when committed, so the
+// overlay, exports and sessions all get the snapped points: odd widths
+// onto pixel centers, even ones onto the pixel grid, where their edges
+// fall on pixel boundaries. Wider pens keep their smooth freehand
+// points. Like fading in, it is spotted in frame by the stroke list
+// growing, so every way of adding strokes takes part.
+
+// pixelSnapMax is the widest stroke, in px, that is snapped.
+const pixelSnapMax = 2
+
+// snapCommits snaps the thin strokes committed since the last frame.
+func (a *Annotator) snapCommits() {
+	if !a.pixelSnap {
+		return
+	}
+	n := len(a.strokes)
+	for i := min(a.snapSeen, n); i < n; i++ {
+		if s := &a.strokes[i]; snappable(s) {
+			s.Pts = snapPoints(s.Pts, s.Width)
+		}
+	}
+	a.snapSeen = n
+}
+
+// snappable reports whether s is a thin plain line.
+func snappable(s *Stroke) bool {
+	return s.Width <= pixelSnapMax && s.Widths == nil && s.Text == "" && s.Step == 0 && s.Fill == nil && !s.Pixelate && !s.Chalk
+}
+
+// snapPoints moves pts onto the pixel positions that make a line of the
+// given width crisp, dropping the repeats that snapping makes of points
+// closer together than a pixel.
+func snapPoints(pts []f32.Point, width float32) []f32.Point {
+	off := float32(0)
+	if int(math.Round(float64(width)))%2 == 1 {
+		off = 0.5
+	}
+	snap := func(v float32) float32 { return float32(math.Floor(float64(v))) + off }
+	out := make([]f32.Point, 0, len(pts))
+	for _, p := range pts {
+		q := f32.Pt(snap(p.X), snap(p.Y))
+		if len(out) == 0 || q != out[len(out)-1] {
+			out = append(out, q)
+		}
+	}
+	return out
+}
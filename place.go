@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/paint"
+)
+
+// Placing: pasted text and loaded sessions do not land at once but follow
+// the pointer as a translucent ghost (centered on it) until a click drops
+// them there; Escape cancels. A set of strokes thus works as a template
+// that can be reused anywhere on the screen.
+
+// ghostOpacity is how strongly strokes being placed are shown.
+const ghostOpacity = 0.5
+
+// startPlacing makes strokes the ghost, replacing any other one.
+func (a *Annotator) startPlacing(strokes []Stroke) {
+	a.cur = nil
+	a.placing = strokes
+	var r image.Rectangle
+	for i := range strokes {
+		r = r.Union(strokeBounds(&strokes[i]))
+	}
+	a.placeAnchor = layout.FPt(r.Min.Add(r.Max)).Mul(0.5)
+	a.notify("Click to place %d strokes, Esc to cancel", len(strokes))
+}
+
+// placeOffset is how far the ghost is moved from where its strokes are:
+// onto the pointer, or the window center when the pointer is elsewhere.
+func (a *Annotator) placeOffset() f32.Point {
+	at := layout.FPt(a.size.Div(2))
+	if a.ptrIn {
+		at = a.ptr
+	}
+	return at.Sub(a.placeAnchor)
+}
+
+// finishPlacing drops the ghost where it is shown.
+func (a *Annotator) finishPlacing() {
+	d := a.placeOffset()
+	now := time.Now()
+	for _, s := range a.placing {
+		for j := range s.Pts {
+			s.Pts[j] = s.Pts[j].Add(d)
+		}
+		// They are being drawn now, as far as the timeline goes.
+		s.At = now
+		a.strokes = append(a.strokes, s)
+	}
+	a.placing = nil
+}
+
+func (a *Annotator) drawGhost(gtx layout.Context) {
+	if a.placing == nil {
+		return
+	}
+	defer paint.PushOpacity(gtx.Ops, ghostOpacity).Pop()
+	defer op.Affine(f32.Affine2D{}.Offset(a.placeOffset())).Push(gtx.Ops).Pop()
+	for i := range a.placing {
+		a.paintStroke(gtx, &a.placing[i])
+	}
+}
+
+// placeSession loads a session file as the ghost.
+func (a *Annotator) placeSession(path string) error {
+	strokes, canvas, err := loadSession(path)
+	if err != nil {
+		return err
+	}
+	a.fitStrokes(strokes, canvas)
+	a.startPlacing(strokes)
+	return nil
+}
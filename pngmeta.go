@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// PNG exports of the overlay carry where they came from in text chunks:
+// when they were made, by which version, the screen area that was
+// captured and, with -follow-window, the title of the window. Viewers
+// show them as properties (exiftool, identify -verbose). -png-metadata=false
+// writes clean files.
+
+// pngText is a text chunk: a keyword and its (UTF-8) text.
+type pngText struct {
+	key, text string
+}
+
+// pngMetadata returns the text chunks for an export now, or nil with
+// -png-metadata=false.
+func (a *Annotator) pngMetadata(now time.Time) []pngText {
+	if !a.pngMeta {
+		return nil
+	}
+	var meta []pngText
+	if !a.deterministic {
+		meta = append(meta, pngText{"Creation Time", now.Format(time.RFC1123Z)})
+	}
+	meta = append(meta, pngText{"Software", "screenpengo " + buildVersion()})
+	if r := a.captureRect; !r.Empty() {
+		meta = append(meta, pngText{"Capture Geometry", fmt.Sprintf("%dx%d+%d+%d", r.Dx(), r.Dy(), r.Min.X, r.Min.Y)})
+	}
+	if a.follow != 0 {
+		if title, err := x11WindowTitle(a.x11Display, a.follow); err == nil {
+			meta = append(meta, pngText{"Window Title", title})
+		} else if a.debug {
+			log.Printf("png metadata: %v", err)
+		}
+	}
+	return meta
+}
+
+// buildVersion is the module version, or the VCS revision of a local
+// build.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 12 && (v == "" || v == "(devel)") {
+			v = s.Value[:12]
+		}
+	}
+	return v
+}
+
+// encodePNG writes img as a PNG with the text chunks of meta after its
+// header, as iTXt where the text is not plain ASCII.
+func encodePNG(w io.Writer, img image.Image, meta []pngText) error {
+	if len(meta) == 0 {
+		return png.Encode(w, img)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	// The signature and the IHDR chunk: 8 + 4 + 4 + 13 + 4 bytes.
+	const headerLen = 33
+	data := buf.Bytes()
+	if _, err := w.Write(data[:headerLen]); err != nil {
+		return err
+	}
+	for _, t := range meta {
+		if err := writePNGText(w, t); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(data[headerLen:])
+	return err
+}
+
+func writePNGText(w io.Writer, t pngText) error {
+	typ, body := "tEXt", []byte(t.key+"\x00"+t.text)
+	for _, r := range t.text {
+		if r >= 0x80 {
+			// Keyword, no compression, no language or translation.
+			typ, body = "iTXt", []byte(t.key+"\x00\x00\x00\x00\x00"+t.text)
+			break
+		}
+	}
+	chunk := append([]byte(typ), body...)
+	if err := binary.Write(w, binary.BigEndian, uint32(len(body))); err != nil {
+		return err
+	}
+	if _, err := w.Write(chunk); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+}
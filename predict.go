@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+)
+
+// Pointer prediction (-predict) hides some of the lag between the pointer
+// and the ink: the stroke in progress is drawn one sample ahead, where the
+// pointer would be if it kept the velocity of its last two samples. The
+// predicted part is only drawn, never stored, so the next real sample
+// simply replaces it. It stays conservative: nothing is predicted while
+// the path turns sharply, when the pointer has stopped (no sample for
+// longer than a couple of sample intervals) or beyond a short distance.
+
+const (
+	// predictMaxTurn is the sharpest turn between the last two segments
+	// still predicted along, in radians.
+	predictMaxTurn = math.Pi / 6
+	// predictMaxDp caps how far ahead the stroke is drawn.
+	predictMaxDp = 12
+)
+
+// predictor holds the last raw pointer samples of the stroke in progress.
+type predictor struct {
+	pts   [3]f32.Point
+	times [3]time.Duration // pointer event times
+	n     int
+	at    time.Time // frame time of the last sample
+}
+
+// add records a sample at event time t, seen in the frame at now.
+func (p *predictor) add(pt f32.Point, t time.Duration, now time.Time) {
+	copy(p.pts[:], p.pts[1:])
+	copy(p.times[:], p.times[1:])
+	p.pts[2], p.times[2], p.at = pt, t, now
+	p.n = min(p.n+1, len(p.pts))
+}
+
+// next returns the predicted next sample as of now, no further than limit
+// px from the last one.
+func (p *predictor) next(now time.Time, limit float32) (f32.Point, bool) {
+	if p.n < len(p.pts) {
+		return f32.Point{}, false
+	}
+	interval := p.times[2] - p.times[1]
+	if interval <= 0 || now.Sub(p.at) > 2*interval {
+		return f32.Point{}, false
+	}
+	u, v := p.pts[1].Sub(p.pts[0]), p.pts[2].Sub(p.pts[1])
+	turn := math.Abs(math.Atan2(float64(u.X*v.Y-u.Y*v.X), float64(u.X*v.X+u.Y*v.Y)))
+	if turn > predictMaxTurn {
+		return f32.Point{}, false
+	}
+	if l := dist(v, f32.Point{}); l > limit {
+		v = v.Mul(limit / l)
+	}
+	return p.pts[2].Add(v), true
+}
+
+// expiry is when the current prediction runs out, if there is one.
+func (p *predictor) expiry() (time.Time, bool) {
+	if p.n < len(p.pts) || p.times[2] <= p.times[1] {
+		return time.Time{}, false
+	}
+	return p.at.Add(2 * (p.times[2] - p.times[1])), true
+}
+
+// predicted returns the stroke in progress extended to the predicted
+// sample, or the stroke itself.
+func (a *Annotator) predicted(gtx layout.Context) *Stroke {
+	s := a.cur
+	if !a.predict || s.Measure || len(s.Pts) == 0 {
+		return s
+	}
+	to, ok := a.predictor.next(gtx.Now, dpToPx(gtx, predictMaxDp))
+	if !ok {
+		return s
+	}
+	// Fresh slices, so the stored points are not appended to.
+	p := *s
+	last := s.Pts[len(s.Pts)-1]
+	p.Pts = slices.Clip(p.Pts)
+	appendInterpolated(&p.Pts, last, to, s.Width/2)
+	if s.Widths != nil {
+		p.Widths = slices.Clip(p.Widths)
+		for len(p.Widths) < len(p.Pts) {
+			p.Widths = append(p.Widths, s.Widths[len(s.Widths)-1])
+		}
+	}
+	return &p
+}
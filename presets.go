@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"gioui.org/io/key"
+)
+
+// Presets are named pen setups (a thin red pen, a wide yellow
+// highlighter, the blur pen) that switch everything at once: Alt+1..9
+// pick one, Alt+N goes to the next, and Alt+S adds the current settings
+// as a new one. They live in the "presets" of the config file, which
+// Alt+S writes back.
+
+// preset is a pen setup: color (alpha included), width, tool and brush.
+type preset struct {
+	name     string
+	col      color.NRGBA
+	widthDp  float32
+	tool     tool
+	chalk    bool
+	dynWidth bool
+	head     arrowStyle
+	bothEnds bool
+}
+
+// presetJSON is the config form of a preset.
+type presetJSON struct {
+	Name  string  `json:"name"`
+	Color string  `json:"color"`           // RRGGBB[AA]
+	Width float32 `json:"width"`           // dp
+	Tool  string  `json:"tool,omitempty"`  // pen by default
+	Chalk bool    `json:"chalk,omitempty"` // chalk brush
+	// Dynamic makes the width follow the drawing speed.
+	Dynamic bool `json:"dynamic,omitempty"`
+	// Head and BothEnds shape the arrows of the arrow tool.
+	Head     string `json:"head,omitempty"`
+	BothEnds bool   `json:"both_ends,omitempty"`
+}
+
+// maxPresets is how many presets have a key of their own.
+const maxPresets = 9
+
+// preset converts the i-th configured preset.
+func (pj presetJSON) preset(i int) (preset, error) {
+	p := preset{name: pj.Name, widthDp: pj.Width, chalk: pj.Chalk, dynWidth: pj.Dynamic, bothEnds: pj.BothEnds}
+	if p.name == "" {
+		p.name = fmt.Sprintf("preset %d", i+1)
+	}
+	var err error
+	if p.col, err = parseHexColor(pj.Color); err != nil {
+		return preset{}, fmt.Errorf("preset %q: %w", p.name, err)
+	}
+	if p.widthDp < 1 || p.widthDp > 100 {
+		return preset{}, fmt.Errorf("preset %q: width %v: want 1..100", p.name, p.widthDp)
+	}
+	if pj.Tool != "" {
+		if p.tool, err = parseTool(pj.Tool); err != nil {
+			return preset{}, fmt.Errorf("preset %q: %w", p.name, err)
+		}
+	}
+	var ok bool
+	if p.head, ok = parseArrowStyle(pj.Head); !ok {
+		return preset{}, fmt.Errorf("preset %q: head %q: want open, closed or barbed", p.name, pj.Head)
+	}
+	return p, nil
+}
+
+func (p preset) json() presetJSON {
+	pj := presetJSON{Name: p.name, Color: formatHexColor(p.col), Width: p.widthDp, Chalk: p.chalk, Dynamic: p.dynWidth, BothEnds: p.bothEnds}
+	if p.tool != toolPen {
+		pj.Tool = p.tool.String()
+	}
+	if p.head != arrowOpen {
+		pj.Head = p.head.String()
+	}
+	return pj
+}
+
+// presetKey handles Alt+1..9, Alt+N and Alt+S.
+func (a *Annotator) presetKey(ke key.Event) {
+	switch n := ke.Name; {
+	case len(n) == 1 && n >= "1" && n <= "9":
+		a.applyPreset(int(n[0] - '1'))
+	case n == "N":
+		if len(a.presets) == 0 {
+			a.notify("No presets yet (Alt+S saves the current pen)")
+			return
+		}
+		a.applyPreset((a.presetIdx + 1) % len(a.presets))
+	case n == "S":
+		a.savePreset()
+	}
+}
+
+// applyPreset switches to the i-th preset.
+func (a *Annotator) applyPreset(i int) {
+	if i >= len(a.presets) {
+		a.notify("No preset %d", i+1)
+		return
+	}
+	p := a.presets[i]
+	a.col, a.widthDp, a.tool = p.col, p.widthDp, p.tool
+	a.chalk, a.dynWidth, a.arrowStyle, a.arrowBoth = p.chalk, p.dynWidth, p.head, p.bothEnds
+	a.presetIdx = i
+	a.notify("Preset %d: %s", i+1, p.name)
+}
+
+// savePreset adds the current pen settings as a preset and saves them.
+func (a *Annotator) savePreset() {
+	if len(a.presets) >= maxPresets {
+		a.notify("Presets are full (%d); remove some from the config file", maxPresets)
+		return
+	}
+	p := preset{
+		name: fmt.Sprintf("preset %d", len(a.presets)+1),
+		col:  a.col, widthDp: a.widthDp, tool: a.tool,
+		chalk: a.chalk, dynWidth: a.dynWidth, head: a.arrowStyle, bothEnds: a.arrowBoth,
+	}
+	presets := append(a.presets[:len(a.presets):len(a.presets)], p)
+	pjs := make([]presetJSON, len(presets))
+	for i, p := range presets {
+		pjs[i] = p.json()
+	}
+	path, err := saveConfig("presets", pjs)
+	if err != nil {
+		a.notifyErr(fmt.Errorf("preset: %w", err))
+		return
+	}
+	a.presets, a.presetIdx = presets, len(presets)-1
+	a.notify("Saved Alt+%d to %s", len(presets), path)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"time"
+)
+
+// Pulsing (Ctrl+G) blinks the selected stroke, or the last one, a few
+// times (-pulse-count, each -pulse-period long) by dipping its opacity,
+// as a "look here" during a presentation. It only affects the overlay.
+
+// pulseDepth is how far the opacity dips at the bottom of a pulse.
+const pulseDepth = 0.85
+
+// pulseTarget starts the edit target pulsing.
+func (a *Annotator) pulseTarget(now time.Time) {
+	s := a.editTarget()
+	if s == nil {
+		a.notify("Nothing to pulse")
+		return
+	}
+	s.pulsed = now
+}
+
+// pulseOpacity is the opacity of s in its pulse, and whether it still is
+// pulsing.
+func (a *Annotator) pulseOpacity(s *Stroke, now time.Time) (float32, bool) {
+	if s.pulsed.IsZero() || a.pulseCount <= 0 || a.pulsePeriod <= 0 {
+		return 1, false
+	}
+	t := now.Sub(s.pulsed)
+	if t >= time.Duration(a.pulseCount)*a.pulsePeriod {
+		s.pulsed = time.Time{}
+		return 1, false
+	}
+	phase := 2 * math.Pi * float64(t) / float64(a.pulsePeriod)
+	return float32(1 - pulseDepth*(0.5-0.5*math.Cos(phase))), true
+}
+
+// Flashing (Ctrl+Shift+G) is the same cue for the whole drawing at once:
+// every line swells into a bright glow and back, once, over
+// flashDuration, to win back attention. Text, step markers, fills and
+// redactions have no line to widen and stay as they are.
+
+const (
+	flashDuration = 700 * time.Millisecond
+	// flashGrow is how much wider than a line its glow gets at the peak.
+	flashGrow = 1.5
+)
+
+// flashAll starts the flash.
+func (a *Annotator) flashAll(now time.Time) {
+	if len(a.strokes) == 0 {
+		a.notify("Nothing to flash")
+		return
+	}
+	a.flashed = now
+}
+
+// flashLevel is how far into its swell the flash is, 0..1, and whether
+// it is still going.
+func (a *Annotator) flashLevel(now time.Time) (float32, bool) {
+	if a.flashed.IsZero() {
+		return 0, false
+	}
+	t := now.Sub(a.flashed)
+	if t >= flashDuration {
+		a.flashed = time.Time{}
+		return 0, false
+	}
+	return float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(t)/float64(flashDuration))), true
+}
+
+// flashGlow is the glow drawn under s at the given flash level, if s is
+// a line.
+func flashGlow(s *Stroke, level float32) (Stroke, bool) {
+	if s.Text != "" || s.Step > 0 || s.Icon != "" || s.Fill != nil || s.Pixelate || level <= 0 {
+		return Stroke{}, false
+	}
+	g := *s
+	g.Width *= 1 + flashGrow*level
+	if g.Widths != nil {
+		g.Widths = make([]float32, len(s.Widths))
+		for i, w := range s.Widths {
+			g.Widths[i] = w * (1 + flashGrow*level)
+		}
+	}
+	// Halfway to white, and translucent, so the line shows through.
+	g.Col.R = uint8((int(s.Col.R) + 0xff) / 2)
+	g.Col.G = uint8((int(s.Col.G) + 0xff) / 2)
+	g.Col.B = uint8((int(s.Col.B) + 0xff) / 2)
+	g.Col.A = uint8(0xa0 * level)
+	g.Halo, g.FillAlpha, g.Chalk, g.Measure = color.NRGBA{}, 0, false, false
+	return g, true
+}
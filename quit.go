@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gioui.org/io/key"
+)
+
+// quitConfirmWindow is how long a -quit-confirm prompt waits for the
+// second press.
+const quitConfirmWindow = 2 * time.Second
+
+// keyChord is a key together with the modifiers that must be held.
+type keyChord struct {
+	mods key.Modifiers
+	name key.Name
+}
+
+// parseKeyChord parses chords such as "Escape", "Ctrl+Q" or "Shift+F10".
+// Ctrl stands for the platform shortcut modifier (Cmd on macOS).
+func parseKeyChord(s string) (keyChord, error) {
+	parts := strings.Split(s, "+")
+	var c keyChord
+	for _, m := range parts[:len(parts)-1] {
+		switch strings.ToLower(m) {
+		case "ctrl", "cmd", "shortcut":
+			c.mods |= key.ModShortcut
+		case "shift":
+			c.mods |= key.ModShift
+		default:
+			return keyChord{}, fmt.Errorf("key %q: unknown modifier %q (want Ctrl or Shift)", s, m)
+		}
+	}
+	name := parts[len(parts)-1]
+	switch {
+	case name == "":
+		return keyChord{}, fmt.Errorf("key %q: missing key name", s)
+	case strings.EqualFold(name, "esc"), strings.EqualFold(name, "escape"):
+		c.name = key.NameEscape
+	case len(name) == 1:
+		c.name = key.Name(strings.ToUpper(name))
+	default:
+		c.name = key.Name(name)
+	}
+	return c, nil
+}
+
+func (c keyChord) String() string {
+	s := string(c.name)
+	if c.name == key.NameEscape {
+		s = "Esc"
+	}
+	if c.mods.Contain(key.ModShift) {
+		s = "Shift+" + s
+	}
+	if c.mods.Contain(key.ModShortcut) {
+		s = "Ctrl+" + s
+	}
+	return s
+}
+
+func (c keyChord) matches(ke key.Event) bool {
+	return ke.Name == c.name && ke.Modifiers == c.mods
+}
+
+// requestQuit exits, with quitConfirm only when the quit key is pressed a
+// second time while the prompt is showing, and otherwise after the
+// confirmation of confirmThen.
+func (a *Annotator) requestQuit(now time.Time) {
+	if a.quitConfirm {
+		if now.Sub(a.quitPromptAt) > quitConfirmWindow {
+			a.quitPromptAt = now
+			a.notify("Press %v again to quit", a.quitKey)
+			return
+		}
+		a.exit()
+		return
+	}
+	a.confirmThen("Quit?", a.exit)
+}
+
+// cancel is what a bare Escape does when it is not the quit key: it backs
+// out of whatever is in progress, drops the selection and returns to the
+// plain pen.
+func (a *Annotator) cancel() {
+	a.cur = nil
+	a.placing = nil
+	a.scrubber, a.scrubbing = false, false
+	a.tool = toolPen
+	a.sel = -1
+	a.historyOpen = false
+	a.regionPick, a.regionSizing = false, false
+	a.quitPromptAt = time.Time{}
+	a.clearPromptAt = time.Time{}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"gioui.org/f32"
+)
+
+// Off-screen rendering of strokes onto an image, for output that does not
+// go through the GPU. Geometry follows drawStroke. Lines of one width are
+// stroked there along the curve of smoothPath with round caps and joins,
+// which covers what round stamps of radius Width/2 along that curve do:
+// here the curve is cut into short straight pieces and stamped. Lines of
+// dynamic width are stamps along their points on screen too, and shapes
+// and lines of other joins and caps the same polygons of shapeRing and
+// linePolygons in both. Each stroke
+// is rasterized into a coverage mask first and composited once, so
+// translucent strokes have a uniform alpha.
+
+// rasterStrokes draws strokes onto dst in order.
+func rasterStrokes(dst *image.RGBA, strokes []Stroke) {
+	for i := range strokes {
+		switch s := &strokes[i]; {
+		case s.Text != "":
+			rasterTextStroke(dst, s)
+		case s.Measure:
+			rasterStroke(dst, s)
+			rasterMeasureLabel(dst, s)
+		case s.Dimension:
+			rasterDimension(dst, s)
+		case s.Step > 0:
+			rasterStep(dst, s)
+		case s.Icon != "":
+			rasterIcon(dst, s)
+		case s.Fill != nil:
+			rasterFill(dst, s)
+		default:
+			rasterStroke(dst, s)
+		}
+	}
+}
+
+func rasterStroke(dst *image.RGBA, s *Stroke) {
+	rasterStrokeOver(dst, dst, s)
+}
+
+// rasterStrokeOver draws s into dst, pixelating (or blurring) under for
+// pixelate strokes rather than dst itself.
+func rasterStrokeOver(dst, under *image.RGBA, s *Stroke) {
+	if len(s.Pts) == 0 {
+		return
+	}
+	if h, ok := haloStroke(s); ok {
+		rasterStrokeOver(dst, under, &h)
+	}
+	r := float32(math.Max(1, float64(s.Width/2)))
+	minP, maxP := bounds(s.withHeads())
+	area := image.Rect(
+		int(math.Floor(float64(minP.X-r-1))), int(math.Floor(float64(minP.Y-r-1))),
+		int(math.Ceil(float64(maxP.X+r+1))), int(math.Ceil(float64(maxP.Y+r+1))),
+	).Intersect(dst.Bounds())
+	if area.Empty() {
+		return
+	}
+	rasterShapeFill(dst, area, s)
+	if s.Highlight {
+		rasterHighlight(dst, area, s)
+		return
+	}
+	body := s
+	if s.Hollow {
+		h := hollowStroke(s)
+		body = &h
+	}
+	var mask *image.Alpha
+	switch {
+	case body.exactShape():
+		mask = image.NewAlpha(area)
+		rasterShape(mask, body)
+	case body.Chalk:
+		mask = chalkMask(body, area)
+	case body.styledLine():
+		mask = image.NewAlpha(area)
+		rasterPolygons(mask, linePolygons(body.Pts, max(2, body.Width), body.Join, body.Cap))
+	default:
+		mask = image.NewAlpha(area)
+		rasterPolyline(mask, body.Pts, body.Widths, body.Width)
+	}
+	rasterHeads(mask, s)
+	var src image.Image = image.NewUniform(s.Col)
+	switch {
+	case s.Blur:
+		src = blur(under, area, blurRadius(s))
+	case s.Pixelate:
+		src = pixelate(under, area)
+	}
+	draw.DrawMask(dst, area, src, area.Min, mask, area.Min, draw.Over)
+}
+
+// rasterPolyline is the mask counterpart of drawPolyline, into mask.
+func rasterPolyline(mask *image.Alpha, pts []f32.Point, widths []float32, width float32) {
+	if widths == nil && pathLength(pts) > 0 {
+		pts = smoothPoints(pts)
+	}
+	stampLine(mask, pts, widths, width)
+}
+
+// smoothPoints is the curve of smoothPath as points, each of its
+// quadratic pieces cut into segments a few pixels long.
+func smoothPoints(pts []f32.Point) []f32.Point {
+	if len(pts) < 3 {
+		return pts
+	}
+	out := []f32.Point{pts[0]}
+	from := pts[0]
+	for i := 1; i < len(pts)-1; i++ {
+		ctrl, to := pts[i], pts[i].Add(pts[i+1]).Mul(0.5)
+		n := max(1, int((dist(from, ctrl)+dist(ctrl, to))/4))
+		for j := 1; j <= n; j++ {
+			t := float32(j) / float32(n)
+			u := 1 - t
+			out = append(out, from.Mul(u*u).Add(ctrl.Mul(2*u*t)).Add(to.Mul(t*t)))
+		}
+		from = to
+	}
+	return append(out, pts[len(pts)-1])
+}
+
+// stampLine stamps discs along pts into mask, the way stampPath does on
+// screen.
+func stampLine(mask *image.Alpha, pts []f32.Point, widths []float32, width float32) {
+	if len(pts) == 0 {
+		return
+	}
+	radius := func(i int) float32 {
+		if widths != nil {
+			return max(1, widths[i]/2)
+		}
+		return max(1, width/2)
+	}
+	stampDisc(mask, pts[0], radius(0))
+	for i := 1; i < len(pts); i++ {
+		p0, p1 := pts[i-1], pts[i]
+		r0, r1 := radius(i-1), radius(i)
+		steps := int(dist(p0, p1) / min(r0, r1))
+		for j := 1; j < steps; j++ {
+			t := float32(j) / float32(steps)
+			stampDisc(mask, p0.Add(p1.Sub(p0).Mul(t)), r0+(r1-r0)*t)
+		}
+		stampDisc(mask, p1, r1)
+	}
+}
+
+// stampDisc merges an antialiased disc into mask, keeping the maximum
+// coverage so overlapping stamps do not darken.
+func stampDisc(mask *image.Alpha, c f32.Point, r float32) {
+	b := image.Rect(
+		int(math.Floor(float64(c.X-r-1))), int(math.Floor(float64(c.Y-r-1))),
+		int(math.Ceil(float64(c.X+r+1))), int(math.Ceil(float64(c.Y+r+1))),
+	).Intersect(mask.Rect)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			d := float32(math.Hypot(float64(float32(x)+0.5-c.X), float64(float32(y)+0.5-c.Y)))
+			cov := r + 0.5 - d
+			if cov <= 0 {
+				continue
+			}
+			a := uint8(255)
+			if cov < 1 {
+				a = uint8(cov * 255)
+			}
+			i := mask.PixOffset(x, y)
+			if a > mask.Pix[i] {
+				mask.Pix[i] = a
+			}
+		}
+	}
+}
+
+// newCanvas returns an RGBA copy of bg, or a transparent canvas of the
+// given size when there is no background.
+func newCanvas(bg image.Image, size image.Point) *image.RGBA {
+	if bg == nil {
+		return image.NewRGBA(image.Rectangle{Max: size})
+	}
+	b := bg.Bounds()
+	dst := image.NewRGBA(image.Rectangle{Max: b.Size()})
+	draw.Draw(dst, dst.Bounds(), bg, b.Min, draw.Src)
+	return dst
+}
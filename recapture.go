@@ -0,0 +1,138 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/f32"
+)
+
+// What happens to existing strokes when the background is recaptured
+// (-recapture).
+//
+// Strokes are stored in window pixels, and the background is captured at
+// the window's own position and size, so window, background and stroke
+// coordinates coincide. A recapture therefore never moves strokes on the
+// screen by itself; whether they still mark the right content depends on
+// whether that content moved:
+//
+//   - keep: strokes stay in window space, where they were drawn. Right
+//     for content that did not move (a new app window, a changed value).
+//   - clear: strokes are dropped, as they belonged to the old content.
+//   - follow: strokes are anchored to the background. The shift between
+//     the old and new capture (e.g. after scrolling) is estimated and the
+//     strokes are moved by it; if no convincing shift is found they are
+//     kept in place.
+const (
+	recaptureKeep   = "keep"
+	recaptureClear  = "clear"
+	recaptureFollow = "follow"
+)
+
+// Offset search parameters for the follow mode. Captures are compared
+// as grayscale downsampled by shiftScale, over shifts of up to
+// shiftMaxFrac of the screen size.
+const (
+	shiftScale   = 4
+	shiftMaxFrac = 0.5
+	// A match must cut the mean difference to below this fraction of
+	// the unshifted one to count as a shift rather than changed content.
+	shiftMinGain = 0.5
+	// Mean absolute difference (0..255) above which the best match is
+	// rejected outright.
+	shiftMaxDiff = 12
+)
+
+// grayImage is a downsampled luminance copy of a capture.
+type grayImage struct {
+	w, h int
+	pix  []uint8
+}
+
+func downsampleGray(img *image.RGBA, scale int) grayImage {
+	b := img.Bounds()
+	g := grayImage{w: b.Dx() / scale, h: b.Dy() / scale}
+	g.pix = make([]uint8, g.w*g.h)
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			var sum int
+			for dy := 0; dy < scale; dy++ {
+				i := img.PixOffset(b.Min.X+x*scale, b.Min.Y+y*scale+dy)
+				for dx := 0; dx < scale; dx++ {
+					p := img.Pix[i+dx*4 : i+dx*4+3]
+					sum += (299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])) / 1000
+				}
+			}
+			g.pix[y*g.w+x] = uint8(sum / (scale * scale))
+		}
+	}
+	return g
+}
+
+// meanDiff is the mean absolute difference between a and b shifted by
+// (dx, dy), i.e. comparing a(x, y) with b(x+dx, y+dy), over their overlap.
+func meanDiff(a, b grayImage, dx, dy int) float64 {
+	x0, x1 := max(0, -dx), min(a.w, b.w-dx)
+	y0, y1 := max(0, -dy), min(a.h, b.h-dy)
+	if x1-x0 < a.w/4 || y1-y0 < a.h/4 {
+		return math.Inf(1)
+	}
+	var sum int
+	for y := y0; y < y1; y++ {
+		ra := a.pix[y*a.w+x0 : y*a.w+x1]
+		rb := b.pix[(y+dy)*b.w+x0+dx : (y+dy)*b.w+x1+dx]
+		for i, v := range ra {
+			d := int(v) - int(rb[i])
+			if d < 0 {
+				d = -d
+			}
+			sum += d
+		}
+	}
+	return float64(sum) / float64((x1-x0)*(y1-y0))
+}
+
+// estimateShift finds how far the content of prev moved in next, in
+// pixels. Scrolling moves content along one axis, so the vertical and the
+// horizontal shift are searched for separately. ok is false when the two
+// captures are not related by a shift.
+func estimateShift(prev, next *image.RGBA) (shift image.Point, ok bool) {
+	if prev == nil || next == nil || prev.Bounds().Size() != next.Bounds().Size() {
+		return image.Point{}, false
+	}
+	a, b := downsampleGray(prev, shiftScale), downsampleGray(next, shiftScale)
+	base := meanDiff(a, b, 0, 0)
+	if base < 1 {
+		// Nothing changed.
+		return image.Point{}, false
+	}
+	best, bestDiff := image.Point{}, base
+	try := func(dx, dy int) {
+		if d := meanDiff(a, b, dx, dy); d < bestDiff {
+			best, bestDiff = image.Pt(dx, dy), d
+		}
+	}
+	maxY, maxX := int(float64(a.h)*shiftMaxFrac), int(float64(a.w)*shiftMaxFrac)
+	for dy := -maxY; dy <= maxY; dy++ {
+		try(0, dy)
+	}
+	for dx := -maxX; dx <= maxX; dx++ {
+		try(dx, 0)
+	}
+	if best == (image.Point{}) || bestDiff > shiftMaxDiff || bestDiff > base*shiftMinGain {
+		return image.Point{}, false
+	}
+	return best.Mul(shiftScale), true
+}
+
+// shiftStrokes moves all strokes by d pixels.
+func (a *Annotator) shiftStrokes(d image.Point) {
+	off := f32.Pt(float32(d.X), float32(d.Y))
+	for i := range a.strokes {
+		pts := a.strokes[i].Pts
+		for j := range pts {
+			pts[j] = pts[j].Add(off)
+		}
+	}
+	a.dropHistory()
+}
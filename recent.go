@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"slices"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// maxRecentColors bounds the most-recently-used list of custom colors.
+const maxRecentColors = 8
+
+// useCustomColor selects c and moves it to the front of the recent list,
+// which is saved so it survives restarts.
+func (a *Annotator) useCustomColor(c color.NRGBA) {
+	a.col = c
+	a.recent = slices.DeleteFunc(a.recent, func(r color.NRGBA) bool { return r == c })
+	a.recent = slices.Insert(a.recent, 0, c)
+	if len(a.recent) > maxRecentColors {
+		a.recent = a.recent[:maxRecentColors]
+	}
+	st, err := loadState()
+	if err == nil {
+		st.RecentColors = st.RecentColors[:0]
+		for _, r := range a.recent {
+			st.RecentColors = append(st.RecentColors, formatHexColor(r))
+		}
+		err = saveState(st)
+	}
+	if err != nil && a.debug {
+		log.Printf("save recent colors: %v", err)
+	}
+}
+
+// loadRecentColors restores the recent list saved by useCustomColor.
+func (a *Annotator) loadRecentColors() {
+	st, err := loadState()
+	if err != nil {
+		if a.debug {
+			log.Printf("load state: %v", err)
+		}
+		return
+	}
+	for _, s := range st.RecentColors {
+		if c, err := parseHexColor(s); err == nil && len(a.recent) < maxRecentColors {
+			a.recent = append(a.recent, c)
+		}
+	}
+}
+
+// selectRecent picks the i-th recent color (Ctrl+1 is the newest).
+func (a *Annotator) selectRecent(i int) {
+	if i < 0 || i >= len(a.recent) {
+		return
+	}
+	a.useCustomColor(a.recent[i])
+}
+
+// drawRecentColors lays out the recent colors as clickable swatches
+// starting at pos, one swatch of side sz per color.
+func (a *Annotator) drawRecentColors(gtx layout.Context, pos image.Point, sz int) {
+	for i := range a.recent {
+		for {
+			ev, ok := gtx.Event(pointer.Filter{Target: &a.recentTags[i], Kinds: pointer.Press})
+			if !ok {
+				break
+			}
+			if ev.(pointer.Event).Kind == pointer.Press {
+				a.selectRecent(i)
+				a.hexEntry = false
+				gtx.Execute(op.InvalidateCmd{})
+			}
+		}
+	}
+	gap := gtx.Dp(4)
+	for i, c := range a.recent {
+		x := pos.X + i*(sz+gap)
+		r := image.Rect(x, pos.Y, x+sz, pos.Y+sz)
+		rr := clip.UniformRRect(r, gtx.Dp(4))
+		area := rr.Push(gtx.Ops)
+		event.Op(gtx.Ops, &a.recentTags[i])
+		pointer.CursorPointer.Add(gtx.Ops)
+		area.Pop()
+		paint.FillShape(gtx.Ops, c, rr.Op(gtx.Ops))
+		if c == a.col {
+			paint.FillShape(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+				clip.Stroke{Path: rr.Path(gtx.Ops), Width: float32(gtx.Dp(2))}.Op())
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math"
+
+	"gioui.org/f32"
+)
+
+// Freehand shape recognition. A committed stroke is classified as a
+// straight line, an axis-aligned ellipse or an axis-aligned rectangle;
+// when the fit is good enough the stroke's points are replaced with a
+// clean version of that shape, and ellipses and rectangles become shape
+// strokes (shape.go), as those of the shape tool are. Anything ambiguous
+// stays freehand.
+
+const (
+	// Max deviation from the chord, relative to its length.
+	lineTolerance = 0.05
+	// Gap between the ends, relative to the path length, below which
+	// the stroke counts as a closed loop.
+	closedTolerance = 0.2
+	// Mean normalized error allowed for closed shapes.
+	ellipseTolerance = 0.10
+	rectTolerance    = 0.06
+	// Strokes shorter than this (px) are left alone: dots and ticks.
+	minRecognizeLength = 40
+)
+
+// recognizeShape returns a cleaned-up copy of s and true if s looks like
+// one of the known shapes.
+func recognizeShape(s Stroke) (Stroke, bool) {
+	pts := s.Pts
+	if len(pts) < 3 {
+		return s, false
+	}
+	length := pathLength(pts)
+	if length < minRecognizeLength {
+		return s, false
+	}
+	first, last := pts[0], pts[len(pts)-1]
+	spacing := s.Width / 2
+	// Clean shapes get an even width.
+	s.Widths = nil
+
+	if maxChordDeviation(pts, first, last)/length < lineTolerance {
+		out := s
+		out.Pts = []f32.Point{first}
+		appendInterpolated(&out.Pts, first, last, spacing)
+		return out, true
+	}
+
+	if dist(first, last)/length > closedTolerance {
+		return s, false
+	}
+	minP, maxP := bounds(pts)
+	w, h := maxP.X-minP.X, maxP.Y-minP.Y
+	if w < 1 || h < 1 {
+		return s, false
+	}
+	ee := ellipseError(pts, minP, maxP)
+	re := rectError(pts, minP, maxP)
+	// Compare the fits relative to their own tolerances, so a fairly
+	// round rectangle does not lose to a poor ellipse and vice versa.
+	switch {
+	case ee < ellipseTolerance && ee/ellipseTolerance <= re/rectTolerance:
+		out := s
+		out.Pts, out.Shape = ellipsePoints(minP, maxP, spacing), "ellipse"
+		return out, true
+	case re < rectTolerance:
+		out := s
+		out.Pts, out.Shape = rectPoints(minP, maxP, spacing), "rectangle"
+		return out, true
+	}
+	return s, false
+}
+
+func dist(a, b f32.Point) float32 {
+	return float32(math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y)))
+}
+
+func pathLength(pts []f32.Point) float32 {
+	var l float32
+	for i := 1; i < len(pts); i++ {
+		l += dist(pts[i-1], pts[i])
+	}
+	return l
+}
+
+func bounds(pts []f32.Point) (minP, maxP f32.Point) {
+	minP, maxP = pts[0], pts[0]
+	for _, p := range pts[1:] {
+		minP.X = min(minP.X, p.X)
+		minP.Y = min(minP.Y, p.Y)
+		maxP.X = max(maxP.X, p.X)
+		maxP.Y = max(maxP.Y, p.Y)
+	}
+	return minP, maxP
+}
+
+// segmentDist is the distance from p to the segment ab.
+func segmentDist(p, a, b f32.Point) float32 {
+	return dist(p, segmentClosest(p, a, b))
+}
+
+// segmentClosest is the point of the segment ab nearest p.
+func segmentClosest(p, a, b f32.Point) f32.Point {
+	ab := b.Sub(a)
+	l2 := ab.X*ab.X + ab.Y*ab.Y
+	if l2 == 0 {
+		return a
+	}
+	t := ((p.X-a.X)*ab.X + (p.Y-a.Y)*ab.Y) / l2
+	t = max(0, min(1, t))
+	return a.Add(ab.Mul(t))
+}
+
+func maxChordDeviation(pts []f32.Point, a, b f32.Point) float32 {
+	var m float32
+	for _, p := range pts {
+		m = max(m, segmentDist(p, a, b))
+	}
+	return m
+}
+
+// ellipseError is the mean deviation of the normalized radius from 1
+// for the ellipse inscribed in the bounding box.
+func ellipseError(pts []f32.Point, minP, maxP f32.Point) float32 {
+	cx, cy := (minP.X+maxP.X)/2, (minP.Y+maxP.Y)/2
+	rx, ry := (maxP.X-minP.X)/2, (maxP.Y-minP.Y)/2
+	var sum float64
+	for _, p := range pts {
+		r := math.Hypot(float64((p.X-cx)/rx), float64((p.Y-cy)/ry))
+		sum += math.Abs(r - 1)
+	}
+	return float32(sum / float64(len(pts)))
+}
+
+// rectError is the mean distance to the nearest bounding-box edge,
+// relative to the shorter side.
+func rectError(pts []f32.Point, minP, maxP f32.Point) float32 {
+	side := min(maxP.X-minP.X, maxP.Y-minP.Y)
+	var sum float32
+	for _, p := range pts {
+		d := min(p.X-minP.X, maxP.X-p.X, p.Y-minP.Y, maxP.Y-p.Y)
+		sum += d
+	}
+	return sum / float32(len(pts)) / side
+}
+
+func ellipsePoints(minP, maxP f32.Point, spacing float32) []f32.Point {
+	cx, cy := (minP.X+maxP.X)/2, (minP.Y+maxP.Y)/2
+	rx, ry := (maxP.X-minP.X)/2, (maxP.Y-minP.Y)/2
+	const segs = 72
+	corners := make([]f32.Point, 0, segs+1)
+	for i := 0; i <= segs; i++ {
+		th := 2 * math.Pi * float64(i) / segs
+		corners = append(corners, f32.Pt(cx+rx*float32(math.Cos(th)), cy+ry*float32(math.Sin(th))))
+	}
+	return polylinePoints(corners, spacing)
+}
+
+// rectPoints is the outline of the box from minP to maxP, clockwise from
+// its top-left corner.
+func rectPoints(minP, maxP f32.Point, spacing float32) []f32.Point {
+	return polylinePoints([]f32.Point{
+		minP, {X: maxP.X, Y: minP.Y}, maxP, {X: minP.X, Y: maxP.Y}, minP,
+	}, spacing)
+}
+
+// polylinePoints densifies a polyline the same way live strokes are.
+func polylinePoints(corners []f32.Point, spacing float32) []f32.Point {
+	out := []f32.Point{corners[0]}
+	for i := 1; i < len(corners); i++ {
+		appendInterpolated(&out, corners[i-1], corners[i], spacing)
+	}
+	return out
+}
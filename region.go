@@ -0,0 +1,99 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The drawing region confines annotation to a rectangle, e.g. one app's
+// window: presses outside it are ignored, strokes are kept inside it, and
+// the rest of the overlay is masked. It is set by dragging after Z and
+// removed by pressing Z again.
+
+// regionMask shades the overlay outside the drawing region.
+var regionMask = color.NRGBA{A: 0x60}
+
+// minRegion is the smallest region (px per side) a drag sets; anything
+// smaller is taken as a slip and leaves the region unset.
+const minRegion = 16
+
+// toggleRegion removes the region if there is one, and otherwise has the
+// next drag define it.
+func (a *Annotator) toggleRegion() {
+	if !a.region.Empty() || a.regionPick {
+		a.region, a.regionPick, a.regionSizing = image.Rectangle{}, false, false
+		a.notify("Drawing region: off")
+		return
+	}
+	a.regionPick = true
+	a.notify("Drag to set the drawing region")
+}
+
+// regionDragRect is the rectangle of the region being dragged out.
+func (a *Annotator) regionDragRect() image.Rectangle {
+	return image.Rectangle{
+		Min: image.Pt(int(a.regionFrom.X), int(a.regionFrom.Y)),
+		Max: image.Pt(int(a.ptr.X), int(a.ptr.Y)),
+	}.Canon()
+}
+
+// finishRegion sets the region from the drag that just ended.
+func (a *Annotator) finishRegion() {
+	r := a.regionDragRect()
+	a.regionPick, a.regionSizing = false, false
+	if r.Dx() < minRegion || r.Dy() < minRegion {
+		a.notify("Drawing region: too small, not set")
+		return
+	}
+	a.region = r
+	a.notify("Drawing region: %v", r)
+}
+
+// inRegion reports whether p may start a stroke.
+func (a *Annotator) inRegion(p f32.Point) bool {
+	return a.region.Empty() || image.Pt(int(p.X), int(p.Y)).In(a.region)
+}
+
+// clampToRegion moves p to the nearest point inside the region, so
+// strokes dragged out of it run along its edge instead.
+func (a *Annotator) clampToRegion(p f32.Point) f32.Point {
+	if a.region.Empty() {
+		return p
+	}
+	r := a.region
+	return f32.Pt(
+		min(max(p.X, float32(r.Min.X)), float32(r.Max.X)),
+		min(max(p.Y, float32(r.Min.Y)), float32(r.Max.Y)),
+	)
+}
+
+// drawRegion masks the overlay outside the region, or outlines the region
+// being dragged out.
+func (a *Annotator) drawRegion(gtx layout.Context) {
+	r := a.region
+	if a.regionSizing {
+		r = a.regionDragRect()
+		path := clip.Rect(r).Path()
+		paint.FillShape(gtx.Ops, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, clip.Stroke{Path: path, Width: float32(gtx.Dp(1))}.Op())
+		return
+	}
+	if r.Empty() {
+		return
+	}
+	win := image.Rectangle{Max: gtx.Constraints.Max}
+	for _, m := range []image.Rectangle{
+		{Max: image.Pt(win.Max.X, r.Min.Y)},
+		{Min: image.Pt(0, r.Max.Y), Max: win.Max},
+		{Min: image.Pt(0, r.Min.Y), Max: image.Pt(r.Min.X, r.Max.Y)},
+		{Min: image.Pt(r.Max.X, r.Min.Y), Max: image.Pt(win.Max.X, r.Max.Y)},
+	} {
+		if m = m.Intersect(win); !m.Empty() {
+			paint.FillShape(gtx.Ops, regionMask, clip.Rect(m).Op())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"image"
+	"log"
+	"slices"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Replay (-replay) draws a saved session again as if live, for "watch me
+// draw" explainers: Enter starts it, every stroke begins at its recorded
+// time (scaled by -replay-speed) and grows along its points, and when the
+// last one is done they all become ordinary strokes. Sessions keep only
+// when strokes started, so each is drawn at a steady pace that fits before
+// the next, and long pauses are shortened.
+
+const (
+	replayPace   = 1500                   // px per second along a stroke
+	replayMinDur = 150 * time.Millisecond // shortest drawing of a stroke
+	replayMaxGap = 2 * time.Second        // longest pause between strokes
+)
+
+type replay struct {
+	strokes    []Stroke
+	start, dur []time.Duration // per stroke, from the start of the replay
+	began      time.Time       // when Enter was pressed, zero before
+	canvas     image.Point     // the session's, for fitStartup
+	total      time.Duration
+	speed      float64
+}
+
+// loadReplay reads a session for replaying at speed times the original.
+func loadReplay(path string, speed float64) (*replay, error) {
+	strokes, canvas, err := loadSession(path)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(strokes, func(a, b Stroke) int { return a.At.Compare(b.At) })
+	r := &replay{strokes: strokes, canvas: canvas, speed: speed}
+	r.plan()
+	return r, nil
+}
+
+// plan lays the strokes out on the replay's timeline.
+func (r *replay) plan() {
+	n := len(r.strokes)
+	r.start, r.dur = make([]time.Duration, n), make([]time.Duration, n)
+	var at time.Duration
+	for i := range r.strokes {
+		s := &r.strokes[i]
+		if i > 0 {
+			prev := &r.strokes[i-1]
+			gap := time.Duration(float64(s.At.Sub(prev.At)) / r.speed)
+			at += min(max(gap, r.dur[i-1]), r.dur[i-1]+replayMaxGap)
+		}
+		r.start[i] = at
+		r.dur[i] = replayMinDur
+		if s.Text == "" && s.Step == 0 && s.Fill == nil {
+			d := time.Duration(float64(pathLength(s.Pts)) / replayPace * float64(time.Second) / r.speed)
+			r.dur[i] = max(d, replayMinDur)
+		}
+		r.total = max(r.total, at+r.dur[i])
+	}
+}
+
+// clone copies r with its strokes, for another overlay.
+func (r *replay) clone() *replay {
+	c := *r
+	c.strokes = make([]Stroke, len(r.strokes))
+	for i, s := range r.strokes {
+		c.strokes[i] = cloneStroke(s)
+	}
+	return &c
+}
+
+// startReplay starts a loaded replay that has not started yet.
+func (a *Annotator) startReplay(now time.Time) {
+	if a.replay != nil && a.replay.began.IsZero() {
+		a.replay.began = now
+	}
+}
+
+// drawReplay shows the replay at the current moment, and ends it once
+// everything is drawn.
+func (a *Annotator) drawReplay(gtx layout.Context) {
+	r := a.replay
+	if r == nil || r.began.IsZero() {
+		return
+	}
+	t := gtx.Now.Sub(r.began)
+	if t >= r.total {
+		a.strokes = append(a.strokes, r.strokes...)
+		a.replay = nil
+		if a.debug {
+			log.Printf("replay finished: %d strokes", len(r.strokes))
+		}
+		for i := range r.strokes {
+			a.paintStroke(gtx, &r.strokes[i])
+		}
+		return
+	}
+	for i := range r.strokes {
+		if t < r.start[i] {
+			break
+		}
+		s := r.strokes[i]
+		if f := float64(t-r.start[i]) / float64(r.dur[i]); f < 1 && len(s.Pts) > 1 && s.Text == "" && s.Step == 0 && s.Fill == nil {
+			// The part drawn so far; arrowheads and labels wait for
+			// the end, so they do not swing around.
+			n := max(1, int(f*float64(len(s.Pts))))
+			s.Pts = s.Pts[:n]
+			if s.Widths != nil {
+				s.Widths = s.Widths[:n]
+			}
+			s.Arrow, s.Measure, s.Shape = false, false, ""
+		}
+		a.paintStroke(gtx, &s)
+	}
+	gtx.Execute(op.InvalidateCmd{})
+}
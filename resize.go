@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image"
+	"log"
+	"time"
+)
+
+// Resizes (a resolution change, a monitor plugged in or out and the WM
+// refitting the fullscreen window) keep strokes in window pixels: they
+// stay where they were relative to the top-left corner, at their size,
+// and ones that end up outside the window come back if it grows again.
+// Scaling them instead would distort everything drawn over content that
+// did not itself scale. What does go stale is the screen capture, so it
+// is taken again.
+
+// resizeRecaptureDelay lets the screen settle after a mode change before
+// it is captured.
+const resizeRecaptureDelay = 500 * time.Millisecond
+
+// checkResize reacts to the window size changing from prev to a.size.
+func (a *Annotator) checkResize(prev image.Point) {
+	if prev == (image.Point{}) {
+		a.fitStartup()
+		return
+	}
+	if prev == a.size {
+		return
+	}
+	if a.debug {
+		log.Printf("window resized from %v to %v", prev, a.size)
+	}
+	win := image.Rectangle{Max: a.size}
+	if !a.region.Empty() {
+		if a.region = a.region.Intersect(win); a.region.Empty() {
+			a.notify("Drawing region is off screen now; removed")
+		}
+	}
+	if a.bgSrc != nil || a.bg == nil || a.feed != nil {
+		// A loaded background is laid out again by layoutBackground.
+		return
+	}
+	// Dropping the stale capture makes the new one count as the first,
+	// so -recapture clear or follow does not act on it.
+	a.bg = nil
+	a.requestCapture(resizeRecaptureDelay)
+}
+
+// replaceWindow covers the target monitor again with -fullscreen
+// override, where nothing else resizes the window (other modes follow
+// the WM), for a recapture after the monitor layout changed.
+func (a *Annotator) replaceWindow() {
+	if a.fullscreen != fullscreenOverride || a.x11Display == nil || a.x11Window == 0 {
+		return
+	}
+	a.coverMonitor(a.x11Display, a.x11Window)
+}
+
+// fitStartup scales the -trace layer and the -restore strokes, loaded
+// before the window had a size, from the canvas they were recorded on to
+// the window, as on a monitor of another resolution; ones recorded in
+// image space go onto the image.
+func (a *Annotator) fitStartup() {
+	a.layoutBackground()
+	if a.fitStrokes(a.trace, a.traceCanvas) {
+		log.Printf("-trace: scaled from %v to %v", a.traceCanvas, a.size)
+	}
+	if a.fitStrokes(a.strokes[a.restoreFrom:], a.restoreCanvas) {
+		log.Printf("-restore: scaled from %v to %v", a.restoreCanvas, a.size)
+	}
+	if r := a.replay; r != nil {
+		if a.fitStrokes(r.strokes, r.canvas) {
+			log.Printf("-replay: scaled from %v to %v", r.canvas, a.size)
+		}
+		a.notify("Press Enter to replay %d strokes", len(r.strokes))
+	}
+	a.startGuide()
+}
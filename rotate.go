@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+
+	"gioui.org/f32"
+)
+
+// Rotation (Ctrl+] clockwise, Ctrl+[ counterclockwise) turns the selected
+// stroke, or else the last one, by rotateStepDeg about the center of the
+// box of its points, for lining up an arrow or a shape after the fact.
+// Like the handles it works on the points, so text, step markers, icons
+// and fills do not turn, and a locked stroke stays as it is. A rotated
+// rectangle or ellipse is no longer drawn exactly in its box (shape.go)
+// but along its points, the outline it had. The key runs it as an edit.
+
+// rotateStepDeg is the angle of one rotation key press.
+const rotateStepDeg = 15
+
+// rotateTarget turns the edit target by steps of rotateStepDeg, positive
+// clockwise on screen.
+func (a *Annotator) rotateTarget(steps int) bool {
+	s := a.editTarget()
+	if s == nil {
+		a.notify("Nothing to rotate")
+		return false
+	}
+	if !resizable(s) {
+		a.notify("A %s does not rotate", strokeKind(s))
+		return false
+	}
+	if !a.checkUnlocked(s) {
+		return false
+	}
+	// Name the kind before the shape goes.
+	kind := strokeKind(s)
+	minP, maxP := bounds(s.Pts)
+	rotatePoints(s.Pts, minP.Add(maxP).Mul(0.5), float64(steps*rotateStepDeg)*math.Pi/180)
+	s.Shape = ""
+	a.notify("Rotated %s by %d°", kind, steps*rotateStepDeg)
+	return true
+}
+
+// rotatePoints turns pts in place by angle radians about c; with y down,
+// a positive angle is clockwise.
+func rotatePoints(pts []f32.Point, c f32.Point, angle float64) {
+	sin, cos := math.Sincos(angle)
+	for i, p := range pts {
+		d := p.Sub(c)
+		pts[i] = c.Add(f32.Pt(
+			float32(float64(d.X)*cos-float64(d.Y)*sin),
+			float32(float64(d.X)*sin+float64(d.Y)*cos),
+		))
+	}
+}
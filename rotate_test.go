@@ -0,0 +1,19 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/f32"
+)
+
+func TestRotatePoints(t *testing.T) {
+	pts := []f32.Point{{X: 20, Y: 10}, {X: 10, Y: 0}}
+	rotatePoints(pts, f32.Pt(10, 10), math.Pi/2)
+	// Clockwise on screen, with y down: right goes to below.
+	for i, want := range []f32.Point{{X: 10, Y: 20}, {X: 20, Y: 10}} {
+		if dist(pts[i], want) > 1e-4 {
+			t.Errorf("point %d: %v, want %v", i, pts[i], want)
+		}
+	}
+}
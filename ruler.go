@@ -0,0 +1,68 @@
+package main
+
+import (
+	"image"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The reading ruler (Shift+F) is a horizontal band across the window,
+// clear, with everything above and below it dimmed, for walking through
+// text or a table one line or row at a time. Up and Down move it by its
+// own height, so one press goes on to the next line; Shift+Up and
+// Shift+Down make it taller or shorter. Ctrl+press moves it to the
+// pointer, and dragging on keeps it there. Like the spotlight, it is on
+// screen only.
+
+const (
+	// rulerStepDp is how much Shift+Up and Shift+Down change the height.
+	rulerStepDp = 4
+	rulerMinDp  = 12
+	rulerMaxDp  = 400
+)
+
+// toggleRuler shows or hides the ruler; it starts at the pointer, or in
+// the middle of the window.
+func (a *Annotator) toggleRuler() {
+	a.ruler = !a.ruler
+	if !a.ruler {
+		a.rulerDrag = false
+		return
+	}
+	if a.rulerY == 0 {
+		a.rulerY = float32(a.size.Y) / 2
+		if a.ptrIn {
+			a.rulerY = a.ptr.Y
+		}
+	}
+}
+
+// rulerKey moves or resizes the ruler for the Up and Down keys.
+func (a *Annotator) rulerKey(gtx layout.Context, ke key.Event) {
+	if !a.ruler {
+		return
+	}
+	dir := float32(1)
+	if ke.Name == key.NameUpArrow {
+		dir = -1
+	}
+	if ke.Modifiers.Contain(key.ModShift) {
+		step := rulerStepDp * float32(a.nudgeSteps(ke.Name))
+		a.rulerHeightDp = min(max(a.rulerHeightDp-dir*step, rulerMinDp), rulerMaxDp)
+		return
+	}
+	a.rulerY = min(max(a.rulerY+dir*dpToPx(gtx, a.rulerHeightDp), 0), float32(a.size.Y))
+}
+
+// drawRuler dims the window above and below the band.
+func (a *Annotator) drawRuler(gtx layout.Context) {
+	size := gtx.Constraints.Max
+	h := dpToPx(gtx, a.rulerHeightDp)
+	top := int(a.rulerY - h/2)
+	bottom := int(a.rulerY + h/2)
+	paint.FillShape(gtx.Ops, a.dimCol, clip.Rect{Max: image.Pt(size.X, max(top, 0))}.Op())
+	paint.FillShape(gtx.Ops, a.dimCol, clip.Rect{Min: image.Pt(0, min(bottom, size.Y)), Max: size}.Op())
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"log"
+	"math"
+	"os"
+
+	"gioui.org/f32"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// annotationScript describes a batch annotation: an optional background
+// image, the annotations to put on it, and where to write the result.
+// Strokes use the session format; shapes and texts are conveniences for
+// hand-written scripts.
+//
+//	{
+//	  "background": "shot.png",
+//	  "out": "annotated.png",
+//	  "strokes": [{"points": [[10, 10], [200, 40]], "color": "#ff0000", "width": 6}],
+//	  "shapes": [{"kind": "rect", "from": [50, 50], "to": [300, 120], "color": "#ffa500", "width": 4}],
+//	  "texts": [{"text": "Click here", "at": [60, 160], "color": "#ffffff", "size": 24}]
+//	}
+type annotationScript struct {
+	sessionFile
+	Background string        `json:"background,omitempty"`
+	Out        string        `json:"out,omitempty"`
+	Shapes     []scriptShape `json:"shapes,omitempty"`
+	Texts      []scriptText  `json:"texts,omitempty"`
+}
+
+type scriptShape struct {
+	Kind  string     `json:"kind"` // line, rect or ellipse
+	From  [2]float32 `json:"from"`
+	To    [2]float32 `json:"to"`
+	Color string     `json:"color"`
+	Width float32    `json:"width"`
+}
+
+type scriptText struct {
+	Text  string     `json:"text"`
+	At    [2]float32 `json:"at"` // baseline origin
+	Color string     `json:"color"`
+	Size  float64    `json:"size"` // px
+}
+
+// runScript renders the script at path without opening a window. out, if
+// set, overrides the script's own output path; deterministic rounds the
+// positions as for -deterministic exports.
+func runScript(path, out string, deterministic bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var sc annotationScript
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if out == "" {
+		out = sc.Out
+	}
+	if out == "" {
+		return fmt.Errorf("%s: no output path (set \"out\" or -out)", path)
+	}
+
+	var bg image.Image
+	if sc.Background != "" {
+		if bg, err = loadImage(sc.Background); err != nil {
+			return err
+		}
+	} else if sc.Width <= 0 || sc.Height <= 0 {
+		return fmt.Errorf("%s: need a background or a width and height", path)
+	}
+	dst := newCanvas(bg, image.Pt(sc.Width, sc.Height))
+
+	strokes := make([]Stroke, 0, len(sc.Strokes)+len(sc.Shapes))
+	for i, sj := range sc.Strokes {
+		s, err := sj.stroke()
+		if err != nil {
+			return fmt.Errorf("%s: stroke %d: %w", path, i, err)
+		}
+		strokes = append(strokes, s)
+	}
+	for i, sh := range sc.Shapes {
+		s, err := sh.stroke()
+		if err != nil {
+			return fmt.Errorf("%s: shape %d: %w", path, i, err)
+		}
+		strokes = append(strokes, s)
+	}
+	if deterministic {
+		strokes = roundStrokes(strokes)
+	}
+	rasterStrokes(dst, strokes)
+	for i, t := range sc.Texts {
+		if deterministic {
+			t.At[0], t.At[1] = float32(math.Round(float64(t.At[0]))), float32(math.Round(float64(t.At[1])))
+		}
+		if err := rasterText(dst, t); err != nil {
+			return fmt.Errorf("%s: text %d: %w", path, i, err)
+		}
+	}
+
+	if err := writePNG(out, dst); err != nil {
+		return err
+	}
+	log.Printf("script %s: wrote %s", path, out)
+	return nil
+}
+
+func (sh scriptShape) stroke() (Stroke, error) {
+	s, err := strokeJSON{Color: sh.Color, Width: sh.Width}.stroke()
+	if err != nil {
+		return Stroke{}, err
+	}
+	a, b := f32.Pt(sh.From[0], sh.From[1]), f32.Pt(sh.To[0], sh.To[1])
+	minP := f32.Pt(min(a.X, b.X), min(a.Y, b.Y))
+	maxP := f32.Pt(max(a.X, b.X), max(a.Y, b.Y))
+	spacing := s.Width / 2
+	switch sh.Kind {
+	case "line":
+		s.Pts = polylinePoints([]f32.Point{a, b}, spacing)
+	case "rect":
+		s.Pts = polylinePoints([]f32.Point{
+			minP, {X: maxP.X, Y: minP.Y}, maxP, {X: minP.X, Y: maxP.Y}, minP,
+		}, spacing)
+	case "ellipse":
+		s.Pts = ellipsePoints(minP, maxP, spacing)
+	default:
+		return Stroke{}, fmt.Errorf("unknown shape kind %q", sh.Kind)
+	}
+	return s, nil
+}
+
+func rasterText(dst *image.RGBA, t scriptText) error {
+	col, err := parseHexColor(t.Color)
+	if err != nil {
+		return err
+	}
+	size := t.Size
+	if size <= 0 {
+		size = 20
+	}
+	face, err := goFace(size)
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(col),
+		Face: face,
+		Dot:  fixed.P(int(t.At[0]), int(t.At[1])),
+	}
+	d.DrawString(t.Text)
+	return nil
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+// writePNG writes img to path, with the text chunks of meta if any.
+func writePNG(path string, img image.Image, meta ...pngText) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encodePNG(f, img, meta); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The playback scrubber is a bar along the bottom edge. Dragging it shows
+// only the strokes started up to the corresponding moment, to review how
+// a drawing was built; releasing restores the full view.
+
+// scrubberRect is the bar's area in window px.
+func scrubberRect(gtx layout.Context) image.Rectangle {
+	m, h := gtx.Dp(16), gtx.Dp(28)
+	max := gtx.Constraints.Max
+	return image.Rect(m, max.Y-m-h, max.X-m, max.Y-m)
+}
+
+// strokeTimeRange returns the start times of the first and last strokes.
+func strokeTimeRange(strokes []Stroke) (t0, t1 time.Time) {
+	for i, s := range strokes {
+		if i == 0 || s.At.Before(t0) {
+			t0 = s.At
+		}
+		if i == 0 || s.At.After(t1) {
+			t1 = s.At
+		}
+	}
+	return t0, t1
+}
+
+// handleScrubber processes drags on the bar and updates the cutoff.
+func (a *Annotator) handleScrubber(gtx layout.Context) {
+	if !a.scrubber {
+		return
+	}
+	r := scrubberRect(gtx)
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: &a.scrubTag,
+			Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+		pe := ev.(pointer.Event)
+		switch pe.Kind {
+		case pointer.Press, pointer.Drag:
+			a.scrubbing = true
+			a.scrubPos = max(0, min(1, (pe.Position.X-float32(r.Min.X))/float32(r.Dx())))
+		case pointer.Release, pointer.Cancel:
+			a.scrubbing = false
+		}
+		gtx.Execute(op.InvalidateCmd{})
+	}
+}
+
+// scrubVisible reports whether s is shown at the current scrub position.
+func (a *Annotator) scrubVisible(s *Stroke) bool {
+	if !a.scrubbing {
+		return true
+	}
+	t0, t1 := strokeTimeRange(a.strokes)
+	cutoff := t0.Add(time.Duration(float64(t1.Sub(t0)) * float64(a.scrubPos)))
+	return !s.At.After(cutoff)
+}
+
+// drawScrubber paints the bar, a tick per stroke and the knob, and
+// registers the bar for pointer input above the drawing area.
+func (a *Annotator) drawScrubber(gtx layout.Context) {
+	if !a.scrubber {
+		return
+	}
+	r := scrubberRect(gtx)
+	area := clip.Rect(r).Push(gtx.Ops)
+	event.Op(gtx.Ops, &a.scrubTag)
+	pointer.CursorPointer.Add(gtx.Ops)
+	area.Pop()
+
+	paint.FillShape(gtx.Ops, labelBg, clip.UniformRRect(r, gtx.Dp(6)).Op(gtx.Ops))
+	t0, t1 := strokeTimeRange(a.strokes)
+	span := t1.Sub(t0)
+	xAt := func(f float32) int { return r.Min.X + int(f*float32(r.Dx())) }
+	tick := color.NRGBA{R: 255, G: 255, B: 255, A: 0x80}
+	for i := range a.strokes {
+		f := float32(1)
+		if span > 0 {
+			f = float32(a.strokes[i].At.Sub(t0)) / float32(span)
+		}
+		x := xAt(f)
+		paint.FillShape(gtx.Ops, tick, clip.Rect{Min: image.Pt(x, r.Min.Y+gtx.Dp(8)), Max: image.Pt(x+gtx.Dp(1), r.Max.Y-gtx.Dp(8))}.Op())
+	}
+	pos := float32(1)
+	if a.scrubbing {
+		pos = a.scrubPos
+	}
+	x, kw := xAt(pos), gtx.Dp(4)
+	knob := image.Rect(x-kw, r.Min.Y+gtx.Dp(3), x+kw, r.Max.Y-gtx.Dp(3))
+	paint.FillShape(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, clip.UniformRRect(knob, kw).Op(gtx.Ops))
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The selection is one stroke picked from the keyboard for inspection,
+// removal or reordering; sel is its index in a.strokes, -1 for none. Since
+// strokes are only ever appended, moved or removed one at a time (with sel
+// kept up to date) or cleared, an index that went out of range simply means
+// the selection is gone.
+
+// selected returns the selected stroke, or nil.
+func (a *Annotator) selected() *Stroke {
+	if a.sel < 0 || a.sel >= len(a.strokes) {
+		a.sel = -1
+		return nil
+	}
+	return &a.strokes[a.sel]
+}
+
+// stepSelection moves the selection by d strokes, wrapping around; from no
+// selection, forward starts at the first stroke and backward at the last.
+func (a *Annotator) stepSelection(d int) {
+	n := len(a.strokes)
+	if n == 0 {
+		a.sel = -1
+		return
+	}
+	switch {
+	case a.selected() == nil && d > 0:
+		a.sel = 0
+	case a.selected() == nil:
+		a.sel = n - 1
+	default:
+		a.sel = ((a.sel+d)%n + n) % n
+	}
+	// notify also logs it.
+	a.notify("Stroke %s", a.describeStroke(a.sel))
+}
+
+// deleteSelected removes the selected stroke; the selection moves on to
+// the stroke that took its place, or the new last one. The key runs it as
+// an edit, for undo to bring the stroke back where it was.
+func (a *Annotator) deleteSelected() bool {
+	if s := a.selected(); s == nil || !a.checkUnlocked(s) {
+		return false
+	}
+	a.strokes = append(a.strokes[:a.sel], a.strokes[a.sel+1:]...)
+	if a.sel >= len(a.strokes) {
+		a.sel = len(a.strokes) - 1
+	}
+	return true
+}
+
+// raiseSelected moves the selected stroke to the top of the drawing order
+// (front) or the bottom (back); the selection follows it. The keys run it
+// as an edit, for undo to move the stroke back.
+func (a *Annotator) raiseSelected(front bool) bool {
+	s := a.selected()
+	if s == nil {
+		return false
+	}
+	moved := *s
+	a.strokes = append(a.strokes[:a.sel], a.strokes[a.sel+1:]...)
+	if front {
+		a.strokes = append(a.strokes, moved)
+		a.sel = len(a.strokes) - 1
+	} else {
+		a.strokes = slices.Insert(a.strokes, 0, moved)
+		a.sel = 0
+	}
+	return true
+}
+
+// editTarget is the stroke property edits apply to: the selected one, or
+// else the last one drawn.
+func (a *Annotator) editTarget() *Stroke {
+	if s := a.selected(); s != nil {
+		return s
+	}
+	if len(a.strokes) == 0 {
+		return nil
+	}
+	return &a.strokes[len(a.strokes)-1]
+}
+
+// setTargetWidth gives the edit target the current pen width. Text, step
+// markers and icons get the size that goes with it, and dynamic-width strokes
+// are scaled, keeping their thick and thin parts. The key runs it as an
+// edit, for undo to give back the old width.
+func (a *Annotator) setTargetWidth(gtx layout.Context) bool {
+	s := a.editTarget()
+	if s == nil || !a.checkUnlocked(s) {
+		return false
+	}
+	w := dpToPx(gtx, a.widthDp)
+	switch {
+	case s.Text != "":
+		w = dpToPx(gtx, textSizeDp(a.widthDp))
+	case s.Step > 0, s.Icon != "":
+		w = dpToPx(gtx, stepDiameter(a.widthDp))
+	}
+	for i := range s.Widths {
+		s.Widths[i] *= w / s.Width
+	}
+	s.Width = w
+	a.notify("Width %g dp", a.widthDp)
+	return true
+}
+
+// duplicateTarget adds a copy of the edit target moved by d and selects
+// it. A copied step marker takes the next number. The key runs it as an
+// edit, as a copy of a stroke in the middle is not the last one drawn.
+func (a *Annotator) duplicateTarget(d f32.Point) bool {
+	s := a.editTarget()
+	if s == nil {
+		return false
+	}
+	c := cloneStroke(*s)
+	for i := range c.Pts {
+		c.Pts[i] = c.Pts[i].Add(d)
+	}
+	if c.Step > 0 {
+		c.Step = a.nextStep()
+	}
+	c.At = time.Now()
+	a.strokes = append(a.strokes, c)
+	a.sel = len(a.strokes) - 1
+	return true
+}
+
+// strokeKind names the kind of s, as the tool that drew it.
+func strokeKind(s *Stroke) string {
+	kind := "pen"
+	switch {
+	case s.Text != "":
+		kind = "text"
+	case s.Step > 0:
+		kind = fmt.Sprintf("step %d", s.Step)
+	case s.Icon != "":
+		kind = "icon " + s.Icon
+	case s.Fill != nil:
+		kind = "fill"
+	case s.Blur:
+		kind = "blur"
+	case s.Pixelate:
+		kind = "pixelate"
+	case s.Measure:
+		kind = "measure"
+	case s.Dimension:
+		kind = "dimension"
+	case s.Arrow:
+		kind = "arrow"
+	case s.Shape != "":
+		kind = s.Shape
+	}
+	if s.Highlight {
+		kind = "highlighter " + kind
+	}
+	if s.Chalk {
+		kind = "chalk " + kind
+	}
+	if s.Hollow {
+		kind = "hollow " + kind
+	}
+	return kind
+}
+
+// describeStroke summarizes stroke i for the log and the toast.
+func (a *Annotator) describeStroke(i int) string {
+	s := &a.strokes[i]
+	kind := strokeKind(s)
+	r := strokeBounds(s)
+	desc := fmt.Sprintf("%d/%d: %s %s %.0fpx, %d pts, box %v", i+1, len(a.strokes), kind, formatHexColor(s.Col), s.Width, len(s.Pts), r)
+	if s.Text != "" {
+		first, _, _ := strings.Cut(s.Text, "\n")
+		desc += fmt.Sprintf(", %q", first)
+	} else {
+		desc += fmt.Sprintf(", length %.0fpx", pathLength(s.Pts))
+	}
+	if !s.At.IsZero() {
+		desc += ", at " + s.At.Format("15:04:05")
+	}
+	return desc
+}
+
+// strokeBounds is the area a stroke covers in window px, including its
+// width. The extent of text is estimated from its line lengths, as it is
+// only known after layout.
+func strokeBounds(s *Stroke) image.Rectangle {
+	if len(s.Pts) == 0 {
+		return image.Rectangle{}
+	}
+	if s.Fill != nil {
+		return fillBounds(s)
+	}
+	if s.Text != "" {
+		var cols int
+		lines := strings.Split(s.Text, "\n")
+		for _, l := range lines {
+			cols = max(cols, len([]rune(l)))
+		}
+		p := s.Pts[0]
+		return image.Rect(int(p.X), int(p.Y),
+			int(p.X+0.6*s.Width*float32(cols)), int(p.Y+1.2*s.Width*float32(len(lines))))
+	}
+	minP, maxP := bounds(s.withHeads())
+	r := s.Width / 2
+	minP, maxP = minP.Sub(f32.Pt(r, r)), maxP.Add(f32.Pt(r, r))
+	return image.Rect(
+		int(math.Floor(float64(minP.X))), int(math.Floor(float64(minP.Y))),
+		int(math.Ceil(float64(maxP.X))), int(math.Ceil(float64(maxP.Y))),
+	)
+}
+
+// drawSelection outlines the selected stroke's bounds in black and white,
+// so the box shows on light and dark content alike.
+func (a *Annotator) drawSelection(gtx layout.Context) {
+	s := a.selected()
+	if s == nil {
+		return
+	}
+	r := strokeBounds(s).Inset(-gtx.Dp(4))
+	for _, o := range []struct {
+		col   color.NRGBA
+		width int
+	}{
+		{color.NRGBA{A: 0xff}, gtx.Dp(3)},
+		{color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, gtx.Dp(1)},
+	} {
+		path := clip.UniformRRect(r, gtx.Dp(2)).Path(gtx.Ops)
+		paint.FillShape(gtx.Ops, o.col, clip.Stroke{Path: path, Width: float32(o.width)}.Op())
+	}
+	if s.Locked {
+		drawLock(gtx, r)
+	}
+	a.drawHandles(gtx)
+}
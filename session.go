@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gioui.org/f32"
+)
+
+// sessionFile is the JSON form of a set of annotations. Coordinates are
+// window pixels, or with Normalized fractions of the canvas from 0 to 1;
+// Width/Height record the canvas they were drawn on, and widths are in
+// its pixels either way. Strokes loaded onto a canvas of another size
+// are scaled to it (scaleStrokes), so the recorded size is what makes a
+// session resolution-independent; normalized ones are easier to write by
+// hand or to use from other programs.
+type sessionFile struct {
+	Width      int          `json:"width,omitempty"`
+	Height     int          `json:"height,omitempty"`
+	Normalized bool         `json:"normalized,omitempty"`
+	Strokes    []strokeJSON `json:"strokes"`
+}
+
+// Session coordinate units (-session-coords).
+const (
+	sessionPixels     = "px"
+	sessionNormalized = "normalized"
+)
+
+type strokeJSON struct {
+	Points [][2]float32 `json:"points"`
+	Color  string       `json:"color"`
+	Width  float32      `json:"width"`
+	// Widths, for dynamic-width strokes, gives the width at each point.
+	Widths []float32 `json:"widths,omitempty"`
+	// Time drawing started, in Unix milliseconds.
+	Time  int64 `json:"t,omitempty"`
+	Arrow bool  `json:"arrow,omitempty"`
+	// Head is the arrowhead style, open if empty: closed or barbed.
+	Head     string `json:"head,omitempty"`
+	BothEnds bool   `json:"both_ends,omitempty"`
+	// Pixelate redacts the background under the stroke instead of
+	// painting Color.
+	Pixelate bool `json:"pixelate,omitempty"`
+	// Blur, with Pixelate, blurs the background instead.
+	Blur bool `json:"blur,omitempty"`
+	// Measure labels a two-point line with its length.
+	Measure bool `json:"measure,omitempty"`
+	// Text makes this a text annotation at the single point, with Width
+	// as the font size.
+	Text string `json:"text,omitempty"`
+	// Step makes this a numbered step marker at the single point, with
+	// Width as the diameter.
+	Step int `json:"step,omitempty"`
+	// Icon makes this an icon stamp (check, cross, star or warning)
+	// centered on the single point, with Width as its size.
+	Icon string `json:"icon,omitempty"`
+	// Chalk draws the stroke with the grainy chalk brush.
+	Chalk bool `json:"chalk,omitempty"`
+	// Hollow draws only the boundary of the stroke.
+	Hollow bool `json:"hollow,omitempty"`
+	// Highlight draws the stroke with the flat highlighter nib.
+	Highlight bool `json:"highlight,omitempty"`
+	// Shape makes this a rectangle or an ellipse in the box of the
+	// points, which trace its outline.
+	Shape string `json:"shape,omitempty"`
+	// Dimension makes this a two-point dimension line, labeled with
+	// Label or else its length.
+	Dimension bool   `json:"dimension,omitempty"`
+	Label     string `json:"label,omitempty"`
+	// Fill makes this a filled area with its top-left corner at the
+	// single point; the mask is a base64 PNG whose alpha is the coverage.
+	Fill string `json:"fill,omitempty"`
+	// FillAlpha is the opacity of the fill of a closed shape, 1..255.
+	FillAlpha uint8 `json:"fill_alpha,omitempty"`
+	// Halo is the color of the auto-contrast outline.
+	Halo string `json:"halo,omitempty"`
+	// Locked protects the stroke from edits.
+	Locked bool `json:"locked,omitempty"`
+	// Join is the shape of the corners of the line, round if empty:
+	// miter or bevel; Cap that of its ends, round if empty: square or
+	// flat.
+	Join string `json:"join,omitempty"`
+	Cap  string `json:"cap,omitempty"`
+}
+
+// session returns the overlay's strokes in the session format, on the
+// image in image space.
+func (a *Annotator) session() sessionFile {
+	strokes, size := a.strokes, a.size
+	if a.inImageSpace() {
+		strokes, size = a.strokesToImage(strokes), a.bgRes
+	}
+	sf := sessionFile{Width: size.X, Height: size.Y, Strokes: make([]strokeJSON, len(strokes))}
+	sf.Normalized = a.normalizedSessions && size.X > 0 && size.Y > 0
+	for i, s := range strokes {
+		sf.Strokes[i] = strokeToJSON(s)
+		if sf.Normalized {
+			for j := range sf.Strokes[i].Points {
+				p := &sf.Strokes[i].Points[j]
+				p[0] /= float32(size.X)
+				p[1] /= float32(size.Y)
+			}
+		}
+	}
+	return sf
+}
+
+// canvas is the size the session was recorded on, if known.
+func (sf sessionFile) canvas() image.Point {
+	return image.Pt(sf.Width, sf.Height)
+}
+
+// scaleStrokes moves strokes recorded on a canvas of size from onto one
+// of size to, stretching the coordinates per axis and the widths (and
+// text sizes) by the smaller factor so nothing grows out of proportion.
+// It reports whether anything changed; an unknown size changes nothing.
+func scaleStrokes(strokes []Stroke, from, to image.Point) bool {
+	if from.X <= 0 || from.Y <= 0 || to.X <= 0 || to.Y <= 0 || from == to {
+		return false
+	}
+	sx, sy := float32(to.X)/float32(from.X), float32(to.Y)/float32(from.Y)
+	k := min(sx, sy)
+	for i := range strokes {
+		s := &strokes[i]
+		for j, p := range s.Pts {
+			s.Pts[j] = f32.Pt(p.X*sx, p.Y*sy)
+		}
+		s.Width *= k
+		for j := range s.Widths {
+			s.Widths[j] *= k
+		}
+		if s.Fill != nil {
+			s.Fill = scaleFillMask(s.Fill, sx, sy)
+		}
+	}
+	return true
+}
+
+// dumpSessions writes each overlay's session to w as one line of JSON,
+// which can be fed back through -script or processed with line-based
+// tools. Logging goes to stderr, so stdout stays clean.
+func dumpSessions(w io.Writer, overlays []*Annotator) error {
+	enc := json.NewEncoder(w)
+	for _, a := range overlays {
+		if err := enc.Encode(a.session()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSession reads the strokes of a session file, which must have some,
+// and the canvas they were recorded on.
+func loadSession(path string) ([]Stroke, image.Point, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	var sf sessionFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, image.Point{}, fmt.Errorf("%s: %w", path, err)
+	}
+	strokes, err := sf.strokes()
+	if err != nil {
+		return nil, image.Point{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(strokes) == 0 {
+		return nil, image.Point{}, fmt.Errorf("%s: no strokes", path)
+	}
+	return strokes, sf.canvas(), nil
+}
+
+// strokes converts the strokes of sf, in pixels of its canvas.
+func (sf sessionFile) strokes() ([]Stroke, error) {
+	if sf.Normalized && (sf.Width <= 0 || sf.Height <= 0) {
+		return nil, fmt.Errorf("normalized coordinates need the width and height of the canvas")
+	}
+	strokes := make([]Stroke, 0, len(sf.Strokes))
+	for i, sj := range sf.Strokes {
+		s, err := sj.stroke()
+		if err != nil {
+			return nil, fmt.Errorf("stroke %d: %w", i, err)
+		}
+		if sf.Normalized {
+			for j, p := range s.Pts {
+				s.Pts[j] = f32.Pt(p.X*float32(sf.Width), p.Y*float32(sf.Height))
+			}
+		}
+		strokes = append(strokes, s)
+	}
+	return strokes, nil
+}
+
+func strokeToJSON(s Stroke) strokeJSON {
+	pts := make([][2]float32, len(s.Pts))
+	for i, p := range s.Pts {
+		pts[i] = [2]float32{p.X, p.Y}
+	}
+	sj := strokeJSON{Points: pts, Color: formatHexColor(s.Col), Width: s.Width, Widths: s.Widths, Arrow: s.Arrow, Pixelate: s.Pixelate, Blur: s.Blur, Measure: s.Measure, Text: s.Text, Step: s.Step, Icon: s.Icon, Chalk: s.Chalk, Hollow: s.Hollow, Highlight: s.Highlight, Shape: s.Shape, FillAlpha: s.FillAlpha, Dimension: s.Dimension, Label: s.Label, Locked: s.Locked}
+	if !s.At.IsZero() {
+		sj.Time = s.At.UnixMilli()
+	}
+	if s.Arrow {
+		sj.BothEnds = s.BothEnds
+		if s.Head != arrowOpen {
+			sj.Head = s.Head.String()
+		}
+	}
+	if s.Join != joinRound {
+		sj.Join = s.Join.String()
+	}
+	if s.Cap != capRound {
+		sj.Cap = s.Cap.String()
+	}
+	if s.Fill != nil {
+		sj.Fill = encodeFillMask(s.Fill)
+	}
+	if s.Halo.A != 0 {
+		sj.Halo = formatHexColor(s.Halo)
+	}
+	return sj
+}
+
+func (sj strokeJSON) stroke() (Stroke, error) {
+	col, err := parseHexColor(sj.Color)
+	if err != nil {
+		return Stroke{}, err
+	}
+	if sj.Width <= 0 {
+		return Stroke{}, fmt.Errorf("stroke width %v: must be positive", sj.Width)
+	}
+	if sj.Text != "" && len(sj.Points) == 0 {
+		return Stroke{}, fmt.Errorf("text %q: missing position", sj.Text)
+	}
+	if sj.Step < 0 || sj.Step > 0 && len(sj.Points) == 0 {
+		return Stroke{}, fmt.Errorf("step %d: want a positive number and a position", sj.Step)
+	}
+	if _, ok := iconShapes[sj.Icon]; sj.Icon != "" && (!ok || len(sj.Points) == 0) {
+		return Stroke{}, fmt.Errorf("icon %q: want %s and a position", sj.Icon, strings.Join(iconNames, ", "))
+	}
+	if sj.Shape != "" && sj.Shape != "rectangle" && sj.Shape != "ellipse" || sj.Shape != "" && len(sj.Points) == 0 {
+		return Stroke{}, fmt.Errorf("shape %q: want rectangle or ellipse and its outline", sj.Shape)
+	}
+	if sj.Widths != nil && len(sj.Widths) != len(sj.Points) {
+		return Stroke{}, fmt.Errorf("%d widths for %d points", len(sj.Widths), len(sj.Points))
+	}
+	s := Stroke{Col: col, Width: sj.Width, Widths: sj.Widths, Arrow: sj.Arrow, Pixelate: sj.Pixelate, Blur: sj.Blur, Measure: sj.Measure, Text: sj.Text, Step: sj.Step, Icon: sj.Icon, Chalk: sj.Chalk, Hollow: sj.Hollow, Highlight: sj.Highlight, Shape: sj.Shape, FillAlpha: sj.FillAlpha, Dimension: sj.Dimension, Label: sj.Label, Locked: sj.Locked, Pts: make([]f32.Point, len(sj.Points))}
+	if sj.Time != 0 {
+		s.At = time.UnixMilli(sj.Time)
+	}
+	if s.Arrow {
+		var ok bool
+		if s.Head, ok = parseArrowStyle(sj.Head); !ok {
+			return Stroke{}, fmt.Errorf("head %q: want open, closed or barbed", sj.Head)
+		}
+		s.BothEnds = sj.BothEnds
+	}
+	var ok bool
+	if s.Join, ok = parseLineJoin(sj.Join); !ok {
+		return Stroke{}, fmt.Errorf("join %q: want round, miter or bevel", sj.Join)
+	}
+	if s.Cap, ok = parseLineCap(sj.Cap); !ok {
+		return Stroke{}, fmt.Errorf("cap %q: want round, square or flat", sj.Cap)
+	}
+	if sj.Fill != "" {
+		if len(sj.Points) != 1 {
+			return Stroke{}, fmt.Errorf("fill: want one point, got %d", len(sj.Points))
+		}
+		if s.Fill, err = decodeFillMask(sj.Fill); err != nil {
+			return Stroke{}, err
+		}
+	}
+	if sj.Halo != "" {
+		if s.Halo, err = parseHexColor(sj.Halo); err != nil {
+			return Stroke{}, fmt.Errorf("halo: %w", err)
+		}
+	}
+	for i, p := range sj.Points {
+		s.Pts[i] = f32.Pt(p[0], p[1])
+	}
+	return s, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// The shape tool (Ctrl+N) draws a clean line, rectangle, ellipse or
+// straight arrow from the press to the pointer, previewed while dragging
+// and committed on release. Ctrl+N again, while the tool is on, goes on
+// to the next shape. Shift while dragging keeps lines and arrows at
+// multiples of 45 degrees and makes rectangles and ellipses squares and
+// circles; Alt grows rectangles and ellipses from the press as their
+// center rather than a corner. Ctrl while dragging a line or arrow keeps
+// it parallel to the last one drawn, for a row of matching callouts; it
+// is read while dragging, so with the reading ruler on (where Ctrl at the
+// press moves the ruler) it is taken after the press. Lines and arrows
+// are strokes of points like any other, and their ends snap onto strokes
+// already drawn (snap.go).
+// Rectangles and ellipses, and those recognized from freehand loops (N),
+// are shape strokes: Stroke.Shape names the kind, and the shape is drawn
+// exactly in the box of the points, with square corners, on screen, in
+// PNGs and as an SVG <rect> or <ellipse>, however the handles resize it.
+// The points still trace the outline, for hit tests, selection, sessions
+// and the chalk and hollow strokes, which are drawn along them. Both take
+// the shape fill and outline opacities (Ctrl+H, Ctrl+Shift+H).
+
+var toolShape = registerTool("shape", shapeTool{})
+
+// shapeNames are the shapes in the order Ctrl+N goes through them.
+var shapeNames = []string{"line", "rectangle", "ellipse", "arrow"}
+
+// shapeKey turns the shape tool on, or while it is on goes to the next
+// shape.
+func (a *Annotator) shapeKey() {
+	if a.tool == toolShape {
+		a.shapeIdx = (a.shapeIdx + 1) % len(shapeNames)
+	}
+	a.tool = toolShape
+	a.notify("Shape: %s", shapeNames[a.shapeIdx])
+}
+
+// shapePoints returns the points of the current shape from the anchor
+// from to the pointer at to, constrained by the modifiers held: Shift
+// for 45 degrees or squares, Alt for from as the center of a rectangle or
+// ellipse, Ctrl for a line parallel to a.lineDir.
+func (a *Annotator) shapePoints(from, to f32.Point, spacing float32, mods key.Modifiers) []f32.Point {
+	constrain, center := mods.Contain(key.ModShift), mods.Contain(key.ModAlt)
+	d := to.Sub(from)
+	switch shapeNames[a.shapeIdx] {
+	case "rectangle", "ellipse":
+		if constrain {
+			side := max(abs32(d.X), abs32(d.Y))
+			d = f32.Pt(float32(math.Copysign(float64(side), float64(d.X))), float32(math.Copysign(float64(side), float64(d.Y))))
+		}
+		to = from.Add(d)
+		if center {
+			from = from.Sub(d)
+		}
+		minP := f32.Pt(min(from.X, to.X), min(from.Y, to.Y))
+		maxP := f32.Pt(max(from.X, to.X), max(from.Y, to.Y))
+		if shapeNames[a.shapeIdx] == "ellipse" {
+			return ellipsePoints(minP, maxP, spacing)
+		}
+		return rectPoints(minP, maxP, spacing)
+	}
+	if mods.Contain(key.ModShortcut) && a.lineDir != (f32.Point{}) {
+		// The projection onto the last line's direction, either way along it.
+		to = from.Add(a.lineDir.Mul(d.X*a.lineDir.X + d.Y*a.lineDir.Y))
+	} else if constrain {
+		angle := math.Round(math.Atan2(float64(d.Y), float64(d.X))/(math.Pi/4)) * math.Pi / 4
+		l := float64(dist(from, to))
+		to = from.Add(f32.Pt(float32(l*math.Cos(angle)), float32(l*math.Sin(angle))))
+	}
+	pts := []f32.Point{from}
+	appendInterpolated(&pts, from, to, spacing)
+	return pts
+}
+
+// shapeTool draws the current shape per drag; a.shapeFrom is the anchor.
+type shapeTool struct{}
+
+func (shapeTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.shapeFrom = pe.Position
+	a.cur = &Stroke{
+		Pts:    []f32.Point{pe.Position},
+		Col:    a.col,
+		Width:  dpToPx(gtx, a.widthDp),
+		At:     gtx.Now,
+		Chalk:  a.chalk,
+		Hollow: a.hollow,
+		Join:   a.penJoin,
+		Cap:    a.penCap,
+	}
+	switch name := shapeNames[a.shapeIdx]; name {
+	case "arrow":
+		a.cur.Arrow, a.cur.Head, a.cur.BothEnds = true, a.arrowStyle, a.arrowBoth
+	case "rectangle", "ellipse":
+		a.cur.Shape = name
+	}
+	a.snapFrom, a.snapTo = false, false
+	if a.cur.Shape == "" {
+		a.shapeFrom, a.snapFrom = a.snapToStroke(pe.Position, dpToPx(gtx, strokeSnapDp))
+		a.cur.Pts[0] = a.shapeFrom
+	}
+}
+
+func (shapeTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur == nil {
+		return
+	}
+	to := pe.Position
+	a.snapTo = false
+	if a.cur.Shape == "" && !pe.Modifiers.Contain(key.ModShift) && !pe.Modifiers.Contain(key.ModShortcut) {
+		to, a.snapTo = a.snapToStroke(to, dpToPx(gtx, strokeSnapDp))
+	}
+	a.cur.Pts = a.shapePoints(a.shapeFrom, to, a.cur.Width/2, pe.Modifiers)
+}
+
+func (shapeTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	s := a.cur
+	a.cur = nil
+	a.snapFrom, a.snapTo = false, false
+	if s == nil || len(s.Pts) < 2 || pathLength(s.Pts) < float32(gtx.Dp(4)) {
+		// A click without a drag draws nothing.
+		return
+	}
+	if s.Shape == "" {
+		d := s.Pts[len(s.Pts)-1].Sub(s.Pts[0])
+		a.lineDir = d.Mul(1 / float32(math.Hypot(float64(d.X), float64(d.Y))))
+	}
+	a.styleShape(s)
+	a.autoHalo(s)
+	a.strokes = append(a.strokes, *s)
+}
+
+func (shapeTool) Render(a *Annotator, gtx layout.Context) {
+	if a.cur != nil {
+		a.paintStroke(gtx, a.cur)
+		a.drawSnaps(gtx, a.cur)
+	}
+}
+
+// exactShape reports whether s is drawn as its shape; chalk and hollow
+// shapes are drawn along the points.
+func (s *Stroke) exactShape() bool {
+	return s.Shape != "" && !s.Chalk && !s.Hollow && len(s.Pts) > 0
+}
+
+// shapePolygon is the shape of kind in the box from minP to maxP as a
+// closed polygon, clockwise, ellipses with sides about 2px long.
+func shapePolygon(kind string, minP, maxP f32.Point) []f32.Point {
+	if kind != "ellipse" {
+		return []f32.Point{minP, {X: maxP.X, Y: minP.Y}, maxP, {X: minP.X, Y: maxP.Y}}
+	}
+	c := minP.Add(maxP).Mul(0.5)
+	rx, ry := (maxP.X-minP.X)/2, (maxP.Y-minP.Y)/2
+	n := max(16, int(math.Pi*(rx+ry)/2))
+	pts := make([]f32.Point, n)
+	for i := range pts {
+		th := 2 * math.Pi * float64(i) / float64(n)
+		pts[i] = c.Add(f32.Pt(rx*float32(math.Cos(th)), ry*float32(math.Sin(th))))
+	}
+	return pts
+}
+
+// shapeRing is the outline of the shape of s, Width wide and centered on
+// its edge, as the polygon outside and, wound the other way, the one
+// inside, which nonzero filling leaves out. An outline wider than the
+// shape has no inside.
+func shapeRing(s *Stroke) [][]f32.Point {
+	minP, maxP := bounds(s.Pts)
+	h := f32.Pt(s.Width/2, s.Width/2)
+	ring := [][]f32.Point{shapePolygon(s.Shape, minP.Sub(h), maxP.Add(h))}
+	if in0, in1 := minP.Add(h), maxP.Sub(h); in0.X < in1.X && in0.Y < in1.Y {
+		in := shapePolygon(s.Shape, in0, in1)
+		slices.Reverse(in)
+		ring = append(ring, in)
+	}
+	return ring
+}
+
+// drawShape draws the outline of a shape stroke.
+func drawShape(ops *op.Ops, s *Stroke) {
+	fillPolygons(ops, shapeRing(s), s.Col)
+}
+
+// writeSVGShape writes a shape stroke as a <rect> or an <ellipse>, with
+// the square corners it has on screen.
+func writeSVGShape(b *strings.Builder, s *Stroke, style string) {
+	minP, maxP := bounds(s.Pts)
+	style = strings.Replace(style, `stroke-linejoin="round"`, `stroke-linejoin="miter"`, 1)
+	if s.Shape == "ellipse" {
+		c := minP.Add(maxP).Mul(0.5)
+		fmt.Fprintf(b, `  <ellipse cx="%.1f" cy="%.1f" rx="%.1f" ry="%.1f" %s/>`+"\n",
+			c.X, c.Y, (maxP.X-minP.X)/2, (maxP.Y-minP.Y)/2, style)
+		return
+	}
+	fmt.Fprintf(b, `  <rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" %s/>`+"\n",
+		minP.X, minP.Y, maxP.X-minP.X, maxP.Y-minP.Y, style)
+}
+
+// rasterShape is the mask counterpart of drawShape, into mask.
+func rasterShape(mask *image.Alpha, s *Stroke) {
+	rasterPolygons(mask, shapeRing(s))
+}
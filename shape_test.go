@@ -0,0 +1,96 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+)
+
+// TestRasterShape checks that rectangles come out with square corners and
+// a hollow inside, and ellipses without their box's corners.
+func TestRasterShape(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	for _, tc := range []struct {
+		kind       string
+		in, out    []image.Point // pixels covered, and not
+		minP, maxP f32.Point
+		width      float32
+	}{
+		{
+			kind: "rectangle", minP: f32.Pt(10, 10), maxP: f32.Pt(50, 30), width: 8,
+			// The outer corner of the outline, its edges, the inside.
+			in:  []image.Point{{6, 6}, {53, 33}, {30, 10}, {10, 20}},
+			out: []image.Point{{30, 20}, {4, 4}, {56, 36}},
+		},
+		{
+			kind: "ellipse", minP: f32.Pt(10, 10), maxP: f32.Pt(50, 30), width: 4,
+			in:  []image.Point{{30, 10}, {10, 20}, {49, 20}},
+			out: []image.Point{{30, 20}, {9, 9}, {50, 30}},
+		},
+	} {
+		pts := rectPoints(tc.minP, tc.maxP, tc.width/2)
+		if tc.kind == "ellipse" {
+			pts = ellipsePoints(tc.minP, tc.maxP, tc.width/2)
+		}
+		s := Stroke{Pts: pts, Col: red, Width: tc.width, Shape: tc.kind}
+		dst := image.NewRGBA(image.Rect(0, 0, 64, 48))
+		rasterStrokes(dst, []Stroke{s})
+		for _, p := range tc.in {
+			if a := dst.RGBAAt(p.X, p.Y).A; a < 0x80 {
+				t.Errorf("%s: %v not covered (alpha %#x)", tc.kind, p, a)
+			}
+		}
+		for _, p := range tc.out {
+			if a := dst.RGBAAt(p.X, p.Y).A; a != 0 {
+				t.Errorf("%s: %v covered (alpha %#x)", tc.kind, p, a)
+			}
+		}
+	}
+}
+
+// TestShapePointsParallel checks that Ctrl keeps a line along the last
+// one's direction, either way, and that it is free before the first.
+func TestShapePointsParallel(t *testing.T) {
+	a := &Annotator{}
+	from := f32.Pt(10, 10)
+	if pts := a.shapePoints(from, f32.Pt(20, 13), 100, key.ModShortcut); pts[len(pts)-1] != f32.Pt(20, 13) {
+		t.Errorf("no last line: ends at %v", pts[len(pts)-1])
+	}
+	a.lineDir = f32.Pt(0.6, 0.8)
+	for _, tc := range []struct{ to, want f32.Point }{
+		{to: f32.Pt(16, 18), want: f32.Pt(16, 18)},
+		{to: f32.Pt(20, 10), want: f32.Pt(13.6, 14.8)},
+		{to: f32.Pt(4, 2), want: f32.Pt(4, 2)},
+	} {
+		pts := a.shapePoints(from, tc.to, 100, key.ModShortcut)
+		if got := pts[len(pts)-1]; dist(got, tc.want) > 1e-3 {
+			t.Errorf("to %v: ends at %v, want %v", tc.to, got, tc.want)
+		}
+	}
+}
+
+// TestSnapToStroke checks that line ends snap to a stroke's ends before
+// its path, and not from out of reach.
+func TestSnapToStroke(t *testing.T) {
+	a := &Annotator{strokes: []Stroke{
+		{Pts: []f32.Point{{X: 10, Y: 10}, {X: 50, Y: 10}}, Width: 2},
+		{Pts: []f32.Point{{X: 10, Y: 40}, {X: 10, Y: 80}}, Width: 2},
+	}}
+	for _, tc := range []struct {
+		p, want f32.Point
+		ok      bool
+	}{
+		{p: f32.Pt(30, 14), want: f32.Pt(30, 10), ok: true},
+		{p: f32.Pt(48, 13), want: f32.Pt(50, 10), ok: true},
+		{p: f32.Pt(13, 42), want: f32.Pt(10, 40), ok: true},
+		{p: f32.Pt(30, 30), want: f32.Pt(30, 30)},
+	} {
+		got, ok := a.snapToStroke(tc.p, 8)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("snapToStroke(%v) = %v, %v; want %v, %v", tc.p, got, ok, tc.want, tc.ok)
+		}
+	}
+}
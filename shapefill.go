@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"gioui.org/op"
+)
+
+// Shape fills: a freehand loop snapped to a rectangle or an ellipse (N)
+// can be filled, with an opacity of its own, and its outline given
+// another, for the common translucent highlight box with a crisp border.
+// Ctrl+H steps the fill of new shapes through none, 25%, 50% and opaque,
+// Ctrl+Shift+H their outline from opaque down to 25%. The fill is in the
+// stroke's color; Stroke.FillAlpha stores its opacity.
+
+var (
+	shapeFillSteps    = []uint8{0, 0x40, 0x80, 0xff}
+	shapeOutlineSteps = []uint8{0xff, 0xc0, 0x80, 0x40}
+)
+
+// cycleShapeAlpha steps the fill or, with outline, the outline opacity
+// of new shapes.
+func (a *Annotator) cycleShapeAlpha(outline bool) {
+	steps, v, what := shapeFillSteps, &a.shapeFill, "fill"
+	if outline {
+		steps, v, what = shapeOutlineSteps, &a.shapeOutline, "outline"
+	}
+	i := 0
+	for j, s := range steps {
+		if s == *v {
+			i = (j + 1) % len(steps)
+		}
+	}
+	*v = steps[i]
+	a.notify("Shape %s: %d%%", what, (int(*v)*100+127)/255)
+}
+
+// styleShape applies the shape opacities to s, a stroke recognized as a
+// shape; closed ones (rectangles and ellipses) get the fill.
+func (a *Annotator) styleShape(s *Stroke) {
+	s.Col.A = uint8(int(s.Col.A) * int(a.shapeOutline) / 0xff)
+	if n := len(s.Pts); n > 2 && dist(s.Pts[0], s.Pts[n-1]) < 1 {
+		s.FillAlpha = a.shapeFill
+	}
+}
+
+// fillColor is the color of the fill of s.
+func (s *Stroke) fillColor() color.NRGBA {
+	c := s.Col
+	c.A = s.FillAlpha
+	return c
+}
+
+// drawShapeFill draws the fill of s, under its outline.
+func drawShapeFill(ops *op.Ops, s *Stroke) {
+	if s.FillAlpha > 0 && len(s.Pts) > 2 {
+		fillPolygon(ops, s.Pts, s.fillColor())
+	}
+}
+
+// rasterShapeFill is the raster counterpart of drawShapeFill, over area
+// of dst.
+func rasterShapeFill(dst *image.RGBA, area image.Rectangle, s *Stroke) {
+	if s.FillAlpha == 0 || len(s.Pts) < 3 {
+		return
+	}
+	mask := image.NewAlpha(area)
+	rasterPolygon(mask, s.Pts)
+	draw.DrawMask(dst, area, image.NewUniform(s.fillColor()), area.Min, mask, area.Min, draw.Over)
+}
+
+// svgShapeFill returns the fill attributes of s for style.
+func svgShapeFill(s *Stroke) string {
+	if s.FillAlpha == 0 {
+		return `fill="none"`
+	}
+	return fmt.Sprintf(`fill="#%02x%02x%02x" fill-opacity="%.3f"`, s.Col.R, s.Col.G, s.Col.B, float32(s.FillAlpha)/255)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"gioui.org/f32"
+)
+
+// Export smoothing (-export-smooth) takes the hand tremor out of
+// freehand strokes in PNG and SVG exports only. The overlay draws the
+// points as they came, which costs nothing while drawing; exports, which
+// are rasterized on the CPU anyway (raster.go), first thin each freehand
+// stroke out to points the smoothing scale apart and round it off with
+// two passes of Chaikin's corner cutting, widths along with the points.
+// On-screen and exported strokes therefore differ slightly by design.
+// Sessions keep the points as drawn. Closed strokes (recognized shapes,
+// lassos) are left alone, so rectangles keep their corners, as are
+// measures, dimensions, text, markers, icons and fills.
+
+// smoothPasses is how many times the corners are cut.
+const smoothPasses = 2
+
+// smoothStrokes returns strokes with the freehand ones smoothed at scale
+// px; it returns strokes itself when scale is not positive.
+func smoothStrokes(strokes []Stroke, scale float32) []Stroke {
+	if scale <= 0 {
+		return strokes
+	}
+	out := make([]Stroke, len(strokes))
+	for i, s := range strokes {
+		if smoothable(&s) {
+			s = smoothStroke(s, scale)
+		}
+		out[i] = s
+	}
+	return out
+}
+
+// smoothable reports whether s is an open freehand line.
+func smoothable(s *Stroke) bool {
+	n := len(s.Pts)
+	return n > 2 && dist(s.Pts[0], s.Pts[n-1]) >= 1 && s.Text == "" && s.Step == 0 && s.Icon == "" && s.Fill == nil && !s.Measure && !s.Dimension
+}
+
+// smoothStroke returns s with new, smoothed points (and widths).
+func smoothStroke(s Stroke, scale float32) Stroke {
+	// A point and its width, smoothed together.
+	type sample struct {
+		p f32.Point
+		w float32
+	}
+	n := len(s.Pts)
+	kept := []sample{{s.Pts[0], s.widthAt(0)}}
+	for i := 1; i < n-1; i++ {
+		if dist(s.Pts[i], kept[len(kept)-1].p) >= scale {
+			kept = append(kept, sample{s.Pts[i], s.widthAt(i)})
+		}
+	}
+	kept = append(kept, sample{s.Pts[n-1], s.widthAt(n - 1)})
+	if len(kept) < 3 {
+		return s
+	}
+	mix := func(a, b sample, t float32) sample {
+		return sample{a.p.Add(b.p.Sub(a.p).Mul(t)), a.w + (b.w-a.w)*t}
+	}
+	for range smoothPasses {
+		cut := make([]sample, 0, 2*len(kept))
+		cut = append(cut, kept[0])
+		for i := 1; i < len(kept); i++ {
+			cut = append(cut, mix(kept[i-1], kept[i], 0.25), mix(kept[i-1], kept[i], 0.75))
+		}
+		kept = append(cut, kept[len(kept)-1])
+	}
+	s.Pts = make([]f32.Point, len(kept))
+	for i, k := range kept {
+		s.Pts[i] = k.p
+	}
+	if s.Widths != nil {
+		s.Widths = make([]float32, len(kept))
+		for i, k := range kept {
+			s.Widths[i] = k.w
+		}
+	}
+	return s
+}
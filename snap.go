@@ -0,0 +1,74 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// Lines and arrows of the shape tool snap their ends onto strokes already
+// drawn, so a flowchart's arrows meet its boxes without a gap: an end
+// pressed or dragged within strokeSnapDp of a stroke goes to its nearest
+// end, or failing one in reach to the nearest point of its path, and a
+// ring marks each snapped end while dragging. A dragged end is left free
+// while Shift or Ctrl constrains the line's angle.
+
+// strokeSnapDp is how close to a stroke a line's end snaps onto it.
+const strokeSnapDp = 10
+
+// snapToStroke returns the stroke point nearest p within d, and whether
+// there is one; a stroke's ends come before the points of any path.
+func (a *Annotator) snapToStroke(p f32.Point, d float32) (f32.Point, bool) {
+	best, found, onEnd := p, false, false
+	bestEnd, bestPath := d, d
+	for i := range a.strokes {
+		s := &a.strokes[i]
+		if len(s.Pts) == 0 || !p.Round().In(strokeBounds(s).Inset(-int(d))) {
+			continue
+		}
+		for _, e := range []f32.Point{s.Pts[0], s.Pts[len(s.Pts)-1]} {
+			if l := dist(p, e); l <= bestEnd {
+				best, bestEnd, found, onEnd = e, l, true, true
+			}
+		}
+		if onEnd {
+			continue
+		}
+		for j := 1; j < len(s.Pts); j++ {
+			q := segmentClosest(p, s.Pts[j-1], s.Pts[j])
+			if l := dist(p, q); l <= bestPath {
+				best, bestPath, found = q, l, true
+			}
+		}
+	}
+	return best, found
+}
+
+// drawSnaps rings the ends of s that snapped onto strokes.
+func (a *Annotator) drawSnaps(gtx layout.Context, s *Stroke) {
+	r := gtx.Dp(6)
+	for _, e := range []struct {
+		p       f32.Point
+		snapped bool
+	}{{s.Pts[0], a.snapFrom}, {s.Pts[len(s.Pts)-1], a.snapTo}} {
+		if !e.snapped {
+			continue
+		}
+		c := e.p.Round()
+		box := image.Rect(c.X-r, c.Y-r, c.X+r, c.Y+r)
+		for _, o := range []struct {
+			col   color.NRGBA
+			width int
+		}{
+			{color.NRGBA{A: 0xff}, gtx.Dp(3)},
+			{color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, gtx.Dp(1)},
+		} {
+			path := clip.Ellipse(box).Path(gtx.Ops)
+			paint.FillShape(gtx.Ops, o.col, clip.Stroke{Path: path, Width: float32(o.width)}.Op())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Sound cues (-sounds) confirm actions without looking at the screen: a
+// blip on color changes, a falling pair of tones on clearing and a rising
+// one on exports. The cues are short WAVs made at startup and played
+// with whichever of the usual command-line players is installed, which
+// keeps audio libraries out of the build. Without a player, or when one
+// fails, there is no sound and nothing else changes.
+
+type cue int
+
+const (
+	cueColor cue = iota
+	cueClear
+	cueExport
+	numCues
+)
+
+var cueNames = [numCues]string{cueColor: "color", cueClear: "clear", cueExport: "export"}
+
+// cueTones are the tones (Hz) of each cue, played one after the other.
+var cueTones = [numCues][]float64{
+	cueColor:  {1046},
+	cueClear:  {660, 440},
+	cueExport: {523, 784},
+}
+
+const (
+	cueRate = 22050             // samples per second
+	cueTone = cueRate * 7 / 100 // samples per tone, 70ms
+)
+
+// cuePlayers are the players tried, with the arguments before the file.
+var cuePlayers = [][]string{
+	{"paplay"},
+	{"pw-play"},
+	{"aplay", "-q"},
+}
+
+type soundPlayer struct {
+	cmd   []string
+	dir   string
+	files [numCues]string
+}
+
+// newSoundPlayer finds a player and writes the cues for it, or returns nil
+// (after saying why) if that cannot be done.
+func newSoundPlayer() *soundPlayer {
+	var p soundPlayer
+	for _, c := range cuePlayers {
+		if _, err := exec.LookPath(c[0]); err == nil {
+			p.cmd = c
+			break
+		}
+	}
+	if p.cmd == nil {
+		log.Printf("-sounds: no paplay, pw-play or aplay found; staying silent")
+		return nil
+	}
+	var err error
+	if p.dir, err = os.MkdirTemp("", "screenpengo-cues"); err != nil {
+		log.Printf("-sounds: %v; staying silent", err)
+		return nil
+	}
+	for c := range numCues {
+		p.files[c] = filepath.Join(p.dir, cueNames[c]+".wav")
+		if err := os.WriteFile(p.files[c], cueWAV(cueTones[c]), 0o644); err != nil {
+			log.Printf("-sounds: %v; staying silent", err)
+			p.remove()
+			return nil
+		}
+	}
+	return &p
+}
+
+// remove deletes the cue files.
+func (p *soundPlayer) remove() {
+	if p != nil {
+		os.RemoveAll(p.dir)
+	}
+}
+
+// play starts the cue and returns; a nil player plays nothing.
+func (p *soundPlayer) play(c cue) {
+	if p == nil {
+		return
+	}
+	cmd := exec.Command(p.cmd[0], append(p.cmd[1:], p.files[c])...)
+	if cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
+
+// cueWAV renders tones as a 16-bit mono WAV file, each tone faded in and
+// out so it does not click.
+func cueWAV(tones []float64) []byte {
+	n := cueTone
+	samples := make([]int16, 0, n*len(tones))
+	for _, f := range tones {
+		for i := range n {
+			t := float64(i) / cueRate
+			env := math.Min(1, math.Min(float64(i), float64(n-i))/(0.01*cueRate))
+			samples = append(samples, int16(0.3*env*math.MaxInt16*math.Sin(2*math.Pi*f*t)))
+		}
+	}
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	data := uint32(2 * len(samples))
+	b.WriteString("RIFF")
+	binary.Write(&b, le, 36+data)
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, le, struct {
+		Size            uint32
+		Format, Chans   uint16
+		Rate, ByteRate  uint32
+		Align, BitDepth uint16
+	}{16, 1, 1, cueRate, 2 * cueRate, 2, 16})
+	b.WriteString("data")
+	binary.Write(&b, le, data)
+	binary.Write(&b, le, samples)
+	return b.Bytes()
+}
+
+// cueColorChange plays the color cue when the pen color differs from the
+// last frame's, whatever changed it.
+func (a *Annotator) cueColorChange() {
+	if a.col != a.cuedCol {
+		a.cuedCol = a.col
+		a.sounds.play(cueColor)
+	}
+}
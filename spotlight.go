@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// spotlightRings is the number of annuli used to approximate the radial
+// falloff at the spotlight edge (Gio has no radial gradient op).
+const spotlightRings = 12
+
+// drawSpotlight dims the whole window except a circle around the pointer.
+// The edge of the hole fades from clear to the dim level over
+// spotFalloffDp, so it reads as a soft focus rather than a cutout.
+func (a *Annotator) drawSpotlight(gtx layout.Context) {
+	dim := a.dimCol
+	size := gtx.Constraints.Max
+	if !a.ptrIn {
+		paint.FillShape(gtx.Ops, dim, clip.Rect{Max: size}.Op())
+		return
+	}
+	c := a.ptr
+	r := dpToPx(gtx, a.spotRadiusDp)
+	f := dpToPx(gtx, a.spotFalloffDp)
+
+	// Everything outside the falloff ring at full dim: window rect
+	// with a counter-wound circle, which the non-zero rule leaves empty.
+	var p clip.Path
+	p.Begin(gtx.Ops)
+	p.MoveTo(f32.Pt(0, 0))
+	p.LineTo(f32.Pt(float32(size.X), 0))
+	p.LineTo(f32.Pt(float32(size.X), float32(size.Y)))
+	p.LineTo(f32.Pt(0, float32(size.Y)))
+	p.Close()
+	circleContour(&p, c, r+f, true)
+	paint.FillShape(gtx.Ops, dim, clip.Outline{Path: p.End()}.Op())
+
+	// Falloff: concentric rings with alpha rising towards the outside.
+	if f < 1 {
+		return
+	}
+	for i := 0; i < spotlightRings; i++ {
+		inner := r + f*float32(i)/spotlightRings
+		outer := r + f*float32(i+1)/spotlightRings
+		// Smoothstep keeps the transition free of visible banding at
+		// both ends of the ring.
+		t := (float64(i) + 0.5) / spotlightRings
+		t = t * t * (3 - 2*t)
+		col := dim
+		col.A = uint8(float64(dim.A) * t)
+
+		var rp clip.Path
+		rp.Begin(gtx.Ops)
+		circleContour(&rp, c, outer, false)
+		circleContour(&rp, c, inner, true)
+		paint.FillShape(gtx.Ops, col, clip.Outline{Path: rp.End()}.Op())
+	}
+}
+
+// circleContour appends a closed polygonal circle to p. ccw selects the
+// winding so that callers can punch holes into an enclosing contour.
+func circleContour(p *clip.Path, c f32.Point, r float32, ccw bool) {
+	const segs = 64
+	dir := 1.0
+	if ccw {
+		dir = -1.0
+	}
+	for i := 0; i <= segs; i++ {
+		th := dir * 2 * math.Pi * float64(i) / segs
+		pt := f32.Pt(c.X+r*float32(math.Cos(th)), c.Y+r*float32(math.Sin(th)))
+		if i == 0 {
+			p.MoveTo(pt)
+		} else {
+			p.LineTo(pt)
+		}
+	}
+	p.Close()
+}
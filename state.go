@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// persistentState is what the tool remembers between runs, stored as
+// JSON under the user config directory.
+type persistentState struct {
+	RecentColors []string `json:"recentColors,omitempty"`
+}
+
+func statePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "screenpengo", "state.json"), nil
+}
+
+// loadState returns the saved state; a missing file is not an error.
+func loadState() (persistentState, error) {
+	var st persistentState
+	p, err := statePath()
+	if err != nil {
+		return st, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	return st, json.Unmarshal(data, &st)
+}
+
+func saveState(st persistentState) error {
+	p, err := statePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"strconv"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Step markers are Strokes with Step set: a disc of diameter Width at the
+// single point Pts[0], with the step number inside. Connectors, faint
+// arrows from each marker to the one with the next number, are derived
+// from the markers when drawing, so moving, deleting or renumbering
+// markers can never leave a stale arrow behind.
+
+// stepDiameter is the marker size that goes with a pen width.
+func stepDiameter(widthDp float32) float32 {
+	return 1.6 * textSizeDp(widthDp)
+}
+
+// nextStep is the number the next marker gets: one past the highest so
+// far, so deleting a marker does not make the next one repeat a number.
+func (a *Annotator) nextStep() int {
+	n := 0
+	for i := range a.strokes {
+		n = max(n, a.strokes[i].Step)
+	}
+	return n + 1
+}
+
+// placeStep adds the next numbered marker at p.
+func (a *Annotator) placeStep(gtx layout.Context, p f32.Point) {
+	a.strokes = append(a.strokes, Stroke{
+		Pts:   []f32.Point{p},
+		Col:   a.col,
+		Width: dpToPx(gtx, stepDiameter(a.widthDp)),
+		At:    gtx.Now,
+		Step:  a.nextStep(),
+	})
+}
+
+// stepConnectors returns arrows joining the markers among strokes in the
+// order of their numbers, each in its start marker's color made fainter.
+// The arrows run between the disc edges rather than the centers. If
+// visible is not nil, markers it rejects are skipped.
+func stepConnectors(strokes []Stroke, visible func(*Stroke) bool) []Stroke {
+	var steps []*Stroke
+	for i := range strokes {
+		s := &strokes[i]
+		if s.Step > 0 && len(s.Pts) > 0 && (visible == nil || visible(s)) {
+			steps = append(steps, s)
+		}
+	}
+	slices.SortStableFunc(steps, func(a, b *Stroke) int { return a.Step - b.Step })
+	var arrows []Stroke
+	for i := 1; i < len(steps); i++ {
+		s0, s1 := steps[i-1], steps[i]
+		p0, p1 := s0.Pts[0], s1.Pts[0]
+		d := dist(p0, p1)
+		width := max(2, s0.Width/10)
+		g0, g1 := s0.Width/2+width, s1.Width/2+width
+		if d <= g0+g1+2*width {
+			// Touching or overlapping markers leave no room.
+			continue
+		}
+		dir := p1.Sub(p0).Mul(1 / d)
+		col := s0.Col
+		col.A /= 2
+		arrows = append(arrows, Stroke{
+			Pts:   arrowPoints(p0.Add(dir.Mul(g0)), p1.Sub(dir.Mul(g1)), width),
+			Col:   col,
+			Width: width,
+		})
+	}
+	return arrows
+}
+
+// stepTextColor is black or white, whichever stands out on the marker.
+func stepTextColor(c color.NRGBA) color.NRGBA {
+	if 299*int(c.R)+587*int(c.G)+114*int(c.B) > 150_000 {
+		return color.NRGBA{A: 0xff}
+	}
+	return color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+}
+
+// stepBounds is the disc of a marker.
+func stepBounds(s *Stroke) image.Rectangle {
+	c, r := s.Pts[0], s.Width/2
+	return image.Rect(int(c.X-r), int(c.Y-r), int(c.X+r+0.5), int(c.Y+r+0.5))
+}
+
+func (a *Annotator) drawStep(gtx layout.Context, s *Stroke) {
+	r := stepBounds(s)
+	paint.FillShape(gtx.Ops, s.Col, clip.Ellipse(r).Op(gtx.Ops))
+
+	lbl := material.Label(a.theme(), unit.Sp(s.Width/2/gtx.Metric.PxPerSp), strconv.Itoa(s.Step))
+	lbl.Color = stepTextColor(s.Col)
+	lbl.Alignment = text.Middle
+	macro := op.Record(gtx.Ops)
+	gtx.Constraints = layout.Exact(image.Pt(r.Dx(), r.Dy()))
+	gtx.Constraints.Min.Y = 0
+	dims := lbl.Layout(gtx)
+	call := macro.Stop()
+	defer op.Offset(image.Pt(r.Min.X, r.Min.Y+(r.Dy()-dims.Size.Y)/2)).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+}
+
+// rasterStep draws a marker onto dst the way drawStep does on screen.
+func rasterStep(dst *image.RGBA, s *Stroke) {
+	r := stepBounds(s).Inset(-1).Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+	mask := image.NewAlpha(r)
+	stampDisc(mask, s.Pts[0], s.Width/2)
+	draw.DrawMask(dst, r, image.NewUniform(s.Col), image.Point{}, mask, r.Min, draw.Over)
+
+	face, err := goFace(float64(s.Width / 2))
+	if err != nil {
+		return
+	}
+	defer face.Close()
+	txt := strconv.Itoa(s.Step)
+	m := face.Metrics()
+	w := font.MeasureString(face, txt)
+	c := s.Pts[0]
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(stepTextColor(s.Col)),
+		Face: face,
+		// Centered on the cap height, which the digits span.
+		Dot: fixed.Point26_6{
+			X: fixed.I(int(c.X)) - w/2,
+			Y: fixed.I(int(c.Y)) + m.CapHeight/2,
+		},
+	}
+	d.DrawString(txt)
+}
+
+// exportStrokes is what exports show of strokes: the strokes, smoothed
+// with -export-smooth, with the step connectors below them when they are
+// on.
+func (a *Annotator) exportStrokes(strokes []Stroke) []Stroke {
+	strokes = smoothStrokes(strokes, a.exportOpts.smooth)
+	if !a.connectSteps {
+		return strokes
+	}
+	return append(stepConnectors(strokes, nil), strokes...)
+}
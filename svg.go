@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"strings"
+
+	"gioui.org/f32"
+)
+
+// strokesSVG serializes strokes as an SVG document of the given canvas
+// size. Each stroke becomes one polyline in window pixels, with the caps
+// and joins it has (round unless set otherwise, linestyle.go), which
+// vector editors (Inkscape, Figma) import as editable paths.
+// Pixelate strokes need the background, so they come out as plain
+// strokes in their translucent fallback color, and chalk strokes as their
+// clean path.
+func strokesSVG(strokes []Stroke, size image.Point) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		size.X, size.Y, size.X, size.Y)
+	strokes = withHalos(strokes)
+	for i := range strokes {
+		s := &strokes[i]
+		if len(s.Pts) == 0 {
+			continue
+		}
+		if s.Text != "" {
+			writeSVGText(&b, s)
+			continue
+		}
+		if s.Step > 0 {
+			writeSVGStep(&b, s)
+			continue
+		}
+		if s.Icon != "" {
+			writeSVGIcon(&b, s)
+			continue
+		}
+		if s.Fill != nil {
+			writeSVGFill(&b, s)
+			continue
+		}
+		style := fmt.Sprintf(`%s stroke="#%02x%02x%02x" stroke-opacity="%.3f" stroke-width="%.1f" stroke-linecap="round" stroke-linejoin="round"`,
+			svgShapeFill(s), s.Col.R, s.Col.G, s.Col.B, float32(s.Col.A)/255, s.Width)
+		if s.Dimension {
+			writeSVGDimension(&b, s, style)
+			continue
+		}
+		if s.Highlight {
+			writeSVGHighlight(&b, s)
+		} else if s.exactShape() {
+			writeSVGShape(&b, s, style)
+		} else if s.Hollow {
+			writeSVGHollow(&b, s)
+		} else if s.Widths != nil && len(s.Pts) > 1 {
+			writeSVGVarWidth(&b, s)
+		} else if s.styledLine() {
+			writeSVGPolyline(&b, s.Pts, svgLineStyle(style, s))
+		} else {
+			writeSVGPolyline(&b, s.Pts, style)
+		}
+		if txt := measureLabel(s); s.Measure && txt != "" {
+			p := measureLabelAt(s, 8)
+			fmt.Fprintf(&b, `  <text x="%.1f" y="%.1f" font-family="Go, sans-serif" font-size="16" fill="#%02x%02x%02x">%s</text>`+"\n",
+				p.X, p.Y+16, s.Col.R, s.Col.G, s.Col.B, html.EscapeString(txt))
+		}
+		for _, head := range s.heads() {
+			if s.Head.filled() {
+				// The outline rounds the corners as on screen.
+				fill := fmt.Sprintf(`fill="#%02x%02x%02x" fill-opacity="%.3f"`, s.Col.R, s.Col.G, s.Col.B, float32(s.Col.A)/255)
+				writeSVGPolyline(&b, head, strings.Replace(style, `fill="none"`, fill, 1))
+			} else {
+				writeSVGPolyline(&b, head, style)
+			}
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// svgLineStyle is style with the caps and joins of s. A lone point with
+// flat caps shows as the square it is on screen.
+func svgLineStyle(style string, s *Stroke) string {
+	cp := s.Cap
+	if cp == capFlat && len(s.Pts) == 1 {
+		cp = capSquare
+	}
+	return strings.Replace(style, `stroke-linecap="round" stroke-linejoin="round"`,
+		fmt.Sprintf(`stroke-linecap="%s" stroke-linejoin="%s"`, cp.svg(), s.Join), 1)
+}
+
+func writeSVGPolyline(b *strings.Builder, pts []f32.Point, style string) {
+	b.WriteString(`  <polyline points="`)
+	for j, p := range pts {
+		if j > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(b, "%.1f,%.1f", p.X, p.Y)
+	}
+	// A single sample still needs a segment to show its round cap.
+	if len(pts) == 1 {
+		fmt.Fprintf(b, " %.1f,%.1f", pts[0].X, pts[0].Y)
+	}
+	fmt.Fprintf(b, `" %s/>`+"\n", style)
+}
+
+// writeSVGVarWidth writes a dynamic-width stroke as a line per segment.
+// They are drawn opaque and the group made translucent, so the overlaps
+// at the joints do not show.
+func writeSVGVarWidth(b *strings.Builder, s *Stroke) {
+	fmt.Fprintf(b, `  <g opacity="%.3f" stroke="#%02x%02x%02x" stroke-linecap="round">`+"\n",
+		float32(s.Col.A)/255, s.Col.R, s.Col.G, s.Col.B)
+	for j := 1; j < len(s.Pts); j++ {
+		p0, p1 := s.Pts[j-1], s.Pts[j]
+		fmt.Fprintf(b, `    <line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke-width="%.1f"/>`+"\n",
+			p0.X, p0.Y, p1.X, p1.Y, (s.widthAt(j-1)+s.widthAt(j))/2)
+	}
+	b.WriteString("  </g>\n")
+}
+
+// writeSVGHollow writes a hollow stroke as its boundary, a closed
+// polyline over the fill of the path, if it has one.
+func writeSVGHollow(b *strings.Builder, s *Stroke) {
+	if s.FillAlpha != 0 {
+		writeSVGPolyline(b, s.Pts, svgShapeFill(s)+` stroke="none"`)
+	}
+	h := hollowStroke(s)
+	writeSVGPolyline(b, h.Pts, fmt.Sprintf(`fill="none" stroke="#%02x%02x%02x" stroke-opacity="%.3f" stroke-width="%.1f" stroke-linecap="round" stroke-linejoin="round"`,
+		h.Col.R, h.Col.G, h.Col.B, float32(h.Col.A)/255, h.Width))
+}
+
+// writeSVGText writes a text annotation as one <text> with a <tspan> per
+// line. SVG positions text by its baseline; the first one sits roughly
+// one ascent below the top-left corner Gio lays the text out from.
+func writeSVGText(b *strings.Builder, s *Stroke) {
+	fmt.Fprintf(b, `  <text x="%.1f" y="%.1f" font-family="Go, sans-serif" font-size="%.1f" fill="#%02x%02x%02x" fill-opacity="%.3f" xml:space="preserve">`,
+		s.Pts[0].X, s.Pts[0].Y+0.9*s.Width, s.Width, s.Col.R, s.Col.G, s.Col.B, float32(s.Col.A)/255)
+	for i, line := range strings.Split(s.Text, "\n") {
+		dy := "0"
+		if i > 0 {
+			dy = "1.2em"
+		}
+		fmt.Fprintf(b, `<tspan x="%.1f" dy="%s">%s</tspan>`, s.Pts[0].X, dy, html.EscapeString(line))
+	}
+	b.WriteString("</text>\n")
+}
+
+// writeSVGStep writes a step marker as a disc with its number centered.
+func writeSVGStep(b *strings.Builder, s *Stroke) {
+	fg := stepTextColor(s.Col)
+	fmt.Fprintf(b, `  <circle cx="%.1f" cy="%.1f" r="%.1f" fill="#%02x%02x%02x" fill-opacity="%.3f"/>`+"\n",
+		s.Pts[0].X, s.Pts[0].Y, s.Width/2, s.Col.R, s.Col.G, s.Col.B, float32(s.Col.A)/255)
+	fmt.Fprintf(b, `  <text x="%.1f" y="%.1f" font-family="Go, sans-serif" font-size="%.1f" text-anchor="middle" dominant-baseline="central" fill="#%02x%02x%02x">%d</text>`+"\n",
+		s.Pts[0].X, s.Pts[0].Y, s.Width/2, fg.R, fg.G, fg.B, s.Step)
+}
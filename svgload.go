@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gioui.org/f32"
+)
+
+// Loading SVG (-load-svg) turns the straight-segment elements of a simple
+// SVG back into strokes: <polyline>, <line> and <path> with only move,
+// line and close commands (curves are skipped), with their stroke color,
+// opacity, width, caps and joins (round unless given), directly or
+// inherited from <g> groups and style attributes, and <rect> and <ellipse> into shape strokes with their
+// fill opacity. It reads back the lines and shapes strokesSVG writes, so
+// exported pen, arrow, shape and measure strokes round-trip through
+// vector editors; the lines of a dynamic-width group come back as one
+// stroke with per-point widths. The rest of an export does not: highlighter
+// strokes and icons are filled outlines (stroke="none", or no stroke at
+// all, taken as black), step markers circles, fills images, and text and
+// labels <text>. Those, and other elements, are skipped with a warning
+// per kind, and the log then says how many were.
+
+// svgStyle is the presentation state of an element, inherited downwards.
+type svgStyle struct {
+	stroke    string
+	width     float32
+	opacity   float32 // stroke-opacity times the group opacities
+	join      lineJoin
+	cap       lineCap
+	transform bool // a transform was given (and ignored)
+}
+
+// loadSVG reads the strokes of an SVG file, in window px: the viewBox, if
+// any, is mapped onto the width and height.
+func loadSVG(path string) ([]Stroke, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	strokes, err := parseSVG(f, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(strokes) == 0 {
+		return nil, fmt.Errorf("%s: no strokes", path)
+	}
+	return strokes, nil
+}
+
+func parseSVG(r io.Reader, name string) ([]Stroke, error) {
+	dec := xml.NewDecoder(r)
+	var (
+		strokes []Stroke
+		stack   []svgStyle
+		// Stroke being chained from the <line>s of the current group,
+		// and the depth of that group.
+		chain      *Stroke
+		chainDepth int
+		// viewBox mapping.
+		off   f32.Point
+		scale = f32.Pt(1, 1)
+	)
+	style := svgStyle{stroke: "#000000", width: 1, opacity: 1}
+	warned := make(map[string]bool)
+	skipped := 0
+	skip := func(format string, args ...any) {
+		skipped++
+		msg := fmt.Sprintf(format, args...)
+		if !warned[msg] {
+			warned[msg] = true
+			log.Printf("load svg %s: %s; skipped", name, msg)
+		}
+	}
+	warn := func(format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if !warned[msg] {
+			warned[msg] = true
+			log.Printf("load svg %s: %s", name, msg)
+		}
+	}
+	mapPt := func(x, y float32) f32.Point {
+		return f32.Pt((x-off.X)*scale.X, (y-off.Y)*scale.Y)
+	}
+	flush := func() {
+		if chain != nil {
+			strokes = append(strokes, *chain)
+			chain = nil
+		}
+	}
+	add := func(st svgStyle, pts []f32.Point) {
+		if len(pts) == 0 {
+			return
+		}
+		flush()
+		col, err := svgColor(st)
+		if err != nil {
+			skip("%v", err)
+			return
+		}
+		strokes = append(strokes, Stroke{Pts: pts, Col: col, Width: st.width * scale.X, Join: st.join, Cap: st.cap})
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, style)
+			attrs := svgAttrs(t)
+			style = style.inherit(attrs)
+			if style.transform {
+				warn("transforms are ignored")
+			}
+			switch t.Name.Local {
+			case "svg":
+				if vb := svgNumbers(attrs["viewBox"]); len(vb) == 4 && vb[2] > 0 && vb[3] > 0 {
+					w, werr := strconv.ParseFloat(strings.TrimSuffix(attrs["width"], "px"), 32)
+					h, herr := strconv.ParseFloat(strings.TrimSuffix(attrs["height"], "px"), 32)
+					off = f32.Pt(vb[0], vb[1])
+					if werr == nil && herr == nil {
+						scale = f32.Pt(float32(w)/vb[2], float32(h)/vb[3])
+					}
+				}
+			case "g", "title", "desc", "defs", "metadata":
+			case "polyline", "polygon":
+				nums := svgNumbers(attrs["points"])
+				var pts []f32.Point
+				for i := 0; i+1 < len(nums); i += 2 {
+					pts = append(pts, mapPt(nums[i], nums[i+1]))
+				}
+				if t.Name.Local == "polygon" && len(pts) > 0 {
+					pts = append(pts, pts[0])
+				}
+				add(style, pts)
+			case "line":
+				n := func(k string) float32 {
+					v, _ := strconv.ParseFloat(attrs[k], 32)
+					return float32(v)
+				}
+				p0, p1 := mapPt(n("x1"), n("y1")), mapPt(n("x2"), n("y2"))
+				w := style.width * scale.X
+				depth := len(stack)
+				if chain != nil && chainDepth == depth && chain.Pts[len(chain.Pts)-1] == p0 {
+					// The next segment of a dynamic-width stroke.
+					chain.Pts = append(chain.Pts, p1)
+					chain.Widths = append(chain.Widths, w)
+					chain.Width = max(chain.Width, w)
+					break
+				}
+				flush()
+				col, err := svgColor(style)
+				if err != nil {
+					skip("%v", err)
+					break
+				}
+				chain = &Stroke{Pts: []f32.Point{p0, p1}, Col: col, Width: w, Widths: []float32{w, w}}
+				chainDepth = depth
+			case "rect", "ellipse":
+				n := func(k string) float32 {
+					v, _ := strconv.ParseFloat(strings.TrimSuffix(attrs[k], "px"), 32)
+					return float32(v)
+				}
+				var p0, p1 f32.Point
+				if t.Name.Local == "rect" {
+					p0 = mapPt(n("x"), n("y"))
+					p1 = mapPt(n("x")+n("width"), n("y")+n("height"))
+				} else {
+					p0 = mapPt(n("cx")-n("rx"), n("cy")-n("ry"))
+					p1 = mapPt(n("cx")+n("rx"), n("cy")+n("ry"))
+				}
+				if p1.X <= p0.X || p1.Y <= p0.Y {
+					skip("<%s> of no size", t.Name.Local)
+					break
+				}
+				flush()
+				col, err := svgColor(style)
+				if err != nil {
+					skip("%v", err)
+					break
+				}
+				s := Stroke{Col: col, Width: style.width * scale.X, Shape: "rectangle"}
+				if t.Name.Local == "ellipse" {
+					s.Shape, s.Pts = "ellipse", ellipsePoints(p0, p1, max(1, s.Width/2))
+				} else {
+					s.Pts = rectPoints(p0, p1, max(1, s.Width/2))
+				}
+				if f := attrs["fill"]; f != "" && f != "none" {
+					o, err := strconv.ParseFloat(attrs["fill-opacity"], 32)
+					if err != nil {
+						o = 1
+					}
+					s.FillAlpha = uint8(min(max(o, 0), 1)*255 + 0.5)
+				}
+				strokes = append(strokes, s)
+			case "path":
+				subs, err := svgPath(attrs["d"])
+				if err != nil {
+					skip("path: %v", err)
+					break
+				}
+				for _, sub := range subs {
+					pts := make([]f32.Point, len(sub))
+					for i, p := range sub {
+						pts[i] = mapPt(p.X, p.Y)
+					}
+					add(style, pts)
+				}
+			default:
+				skip("<%s> is not supported", t.Name.Local)
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+				style = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case xml.EndElement:
+			if chain != nil && len(stack) < chainDepth {
+				flush()
+			}
+			style = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+	}
+	flush()
+	if skipped > 0 {
+		log.Printf("load svg %s: skipped %d elements", name, skipped)
+	}
+	// Chains of equal widths were plain strokes split into lines.
+	for i := range strokes {
+		s := &strokes[i]
+		uniform := true
+		for _, w := range s.Widths {
+			uniform = uniform && w == s.Width
+		}
+		if uniform {
+			s.Widths = nil
+		}
+	}
+	return strokes, nil
+}
+
+// svgAttrs collects the attributes of t, with the declarations of its
+// style attribute taking precedence as in CSS.
+func svgAttrs(t xml.StartElement) map[string]string {
+	attrs := make(map[string]string, len(t.Attr))
+	for _, a := range t.Attr {
+		attrs[a.Name.Local] = strings.TrimSpace(a.Value)
+	}
+	for _, decl := range strings.Split(attrs["style"], ";") {
+		if k, v, ok := strings.Cut(decl, ":"); ok {
+			attrs[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return attrs
+}
+
+func (st svgStyle) inherit(attrs map[string]string) svgStyle {
+	num := func(k string) (float32, bool) {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(attrs[k], "px"), 32)
+		return float32(v), err == nil
+	}
+	if s, ok := attrs["stroke"]; ok {
+		st.stroke = s
+	}
+	if w, ok := num("stroke-width"); ok {
+		st.width = w
+	}
+	if o, ok := num("stroke-opacity"); ok {
+		st.opacity *= o
+	}
+	if o, ok := num("opacity"); ok {
+		st.opacity *= o
+	}
+	// Round unless given, as the pen is, rather than SVG's miter and butt.
+	if j, ok := parseLineJoin(attrs["stroke-linejoin"]); ok && attrs["stroke-linejoin"] != "" {
+		st.join = j
+	}
+	if c, ok := parseLineCap(attrs["stroke-linecap"]); ok && attrs["stroke-linecap"] != "" {
+		st.cap = c
+	}
+	if _, ok := attrs["transform"]; ok {
+		st.transform = true
+	}
+	return st
+}
+
+// svgColor is the stroke color of st; only hex colors are understood.
+func svgColor(st svgStyle) (color.NRGBA, error) {
+	if st.stroke == "none" {
+		return color.NRGBA{}, fmt.Errorf("no stroke")
+	}
+	s := st.stroke
+	if len(s) == 4 && s[0] == '#' {
+		// #rgb
+		s = string([]byte{'#', s[1], s[1], s[2], s[2], s[3], s[3]})
+	}
+	c, err := parseHexColor(s)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("stroke %q: want a #rrggbb color", st.stroke)
+	}
+	c.A = uint8(float32(c.A)*min(max(st.opacity, 0), 1) + 0.5)
+	return c, nil
+}
+
+// svgNumbers parses a list of numbers separated by spaces and commas.
+func svgNumbers(s string) []float32 {
+	var nums []float32
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		v, err := strconv.ParseFloat(f, 32)
+		if err != nil {
+			return nums
+		}
+		nums = append(nums, float32(v))
+	}
+	return nums
+}
+
+// svgPath parses path data made of M, L, H, V and Z commands (absolute or
+// relative) into its subpaths.
+func svgPath(d string) ([][]f32.Point, error) {
+	var (
+		subs      [][]f32.Point
+		cur       []f32.Point
+		p, start  f32.Point
+		cmd       byte
+		haveCmd   bool
+		remaining = d
+	)
+	next := func() (float32, error) {
+		remaining = strings.TrimLeft(remaining, ", \t\r\n")
+		// A second point, or a sign but after the exponent's e, starts
+		// the next number: "1.5.5" is 1.5 and .5, "1-2" is 1 and -2.
+		end, dot, exp := 0, false, false
+	scan:
+		for end < len(remaining) {
+			c := remaining[end]
+			switch {
+			case c >= '0' && c <= '9':
+			case c == '.' && !dot && !exp:
+				dot = true
+			case (c == 'e' || c == 'E') && !exp && end > 0:
+				exp = true
+			case (c == '-' || c == '+') && (end == 0 || remaining[end-1] == 'e' || remaining[end-1] == 'E'):
+			default:
+				break scan
+			}
+			end++
+		}
+		v, err := strconv.ParseFloat(remaining[:end], 32)
+		if err != nil {
+			return 0, fmt.Errorf("bad number at %q", remaining)
+		}
+		remaining = remaining[end:]
+		return float32(v), nil
+	}
+	endSub := func() {
+		if len(cur) > 1 {
+			subs = append(subs, cur)
+		}
+		cur = nil
+	}
+	for {
+		remaining = strings.TrimLeft(remaining, ", \t\r\n")
+		if remaining == "" {
+			break
+		}
+		if c := remaining[0]; c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' {
+			cmd, haveCmd = c, true
+			remaining = remaining[1:]
+			if cmd == 'Z' || cmd == 'z' {
+				if len(cur) > 0 {
+					cur = append(cur, start)
+				}
+				p = start
+				endSub()
+				continue
+			}
+		} else if !haveCmd {
+			return nil, fmt.Errorf("path must start with a command")
+		}
+		rel := cmd >= 'a'
+		switch cmd {
+		case 'M', 'm', 'L', 'l':
+			x, err := next()
+			if err != nil {
+				return nil, err
+			}
+			y, err := next()
+			if err != nil {
+				return nil, err
+			}
+			q := f32.Pt(x, y)
+			if rel {
+				q = p.Add(q)
+			}
+			if cmd == 'M' || cmd == 'm' {
+				endSub()
+				start = q
+				// Further pairs are implicit line-tos.
+				cmd = 'L' + (cmd - 'M')
+			}
+			p = q
+			cur = append(cur, p)
+		case 'H', 'h', 'V', 'v':
+			v, err := next()
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case cmd == 'H':
+				p.X = v
+			case cmd == 'h':
+				p.X += v
+			case cmd == 'V':
+				p.Y = v
+			default:
+				p.Y += v
+			}
+			cur = append(cur, p)
+		default:
+			return nil, fmt.Errorf("command %q is not supported", string(cmd))
+		}
+	}
+	endSub()
+	return subs, nil
+}
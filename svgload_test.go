@@ -0,0 +1,158 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/f32"
+)
+
+func TestSVGPath(t *testing.T) {
+	for _, tc := range []struct {
+		d    string
+		want [][]f32.Point
+		err  bool
+	}{
+		{d: "M1,2 L3,4", want: [][]f32.Point{{{X: 1, Y: 2}, {X: 3, Y: 4}}}},
+		{d: "M 1 2 L 3 4 5 6", want: [][]f32.Point{{{X: 1, Y: 2}, {X: 3, Y: 4}, {X: 5, Y: 6}}}},
+		// Pairs after a move are line-tos, relative after a relative one.
+		{d: "M1,2 3,4", want: [][]f32.Point{{{X: 1, Y: 2}, {X: 3, Y: 4}}}},
+		{d: "m1,2 3,4", want: [][]f32.Point{{{X: 1, Y: 2}, {X: 4, Y: 6}}}},
+		{d: "M10,10 l5,0 l0,5", want: [][]f32.Point{{{X: 10, Y: 10}, {X: 15, Y: 10}, {X: 15, Y: 15}}}},
+		{d: "M0,0 H10 V5 h-4 v2", want: [][]f32.Point{{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 6, Y: 5}, {X: 6, Y: 7}}}},
+		// Close goes back to the start, and ends the subpath.
+		{d: "M0,0 L4,0 L4,4 Z", want: [][]f32.Point{{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 0}}}},
+		{d: "M0,0 L1,0 Z M5,5 L6,5", want: [][]f32.Point{{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 0}}, {{X: 5, Y: 5}, {X: 6, Y: 5}}}},
+		// A relative move after a close is from the start.
+		{d: "M2,2 L4,2 z m1,1 l1,0", want: [][]f32.Point{{{X: 2, Y: 2}, {X: 4, Y: 2}, {X: 2, Y: 2}}, {{X: 3, Y: 3}, {X: 4, Y: 3}}}},
+		// Numbers run together as SVG allows.
+		{d: "M1-2L3.5.5", want: [][]f32.Point{{{X: 1, Y: -2}, {X: 3.5, Y: 0.5}}}},
+		{d: "M1e1,2E0 L-1e-1,0", want: [][]f32.Point{{{X: 10, Y: 2}, {X: -0.1, Y: 0}}}},
+		// A lone move draws nothing.
+		{d: "M1,1", want: nil},
+		{d: "", want: nil},
+		{d: "1,2 L3,4", err: true},
+		{d: "M1", err: true},
+		{d: "M1,x", err: true},
+		{d: "M0,0 C1,1 2,2 3,3", err: true},
+		{d: "M0,0 Q1,1 2,2", err: true},
+		{d: "M0,0 A1,1 0 0 1 2,2", err: true},
+	} {
+		got, err := svgPath(tc.d)
+		if (err != nil) != tc.err {
+			t.Errorf("svgPath(%q) error %v, want error %v", tc.d, err, tc.err)
+			continue
+		}
+		if err == nil && !slices.EqualFunc(got, tc.want, slices.Equal) {
+			t.Errorf("svgPath(%q) = %v, want %v", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestParseSVG(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	for _, tc := range []struct {
+		name, svg string
+		want      []Stroke
+	}{
+		{
+			name: "polyline",
+			svg:  `<svg><polyline points="1,2 3,4" fill="none" stroke="#ff0000" stroke-width="6"/></svg>`,
+			want: []Stroke{{Pts: []f32.Point{{X: 1, Y: 2}, {X: 3, Y: 4}}, Col: red, Width: 6}},
+		},
+		{
+			name: "inherited style",
+			svg:  `<svg><g style="stroke: #f00; stroke-width: 2px" opacity="0.5"><line x1="0" y1="0" x2="4" y2="0"/></g></svg>`,
+			want: []Stroke{{Pts: []f32.Point{{X: 0, Y: 0}, {X: 4, Y: 0}}, Col: color.NRGBA{R: 0xff, A: 0x80}, Width: 2}},
+		},
+		{
+			name: "viewBox",
+			svg:  `<svg width="200" height="100" viewBox="10 10 100 50"><path d="M10,10 L110,60" stroke="#ff0000" stroke-width="1"/></svg>`,
+			want: []Stroke{{Pts: []f32.Point{{X: 0, Y: 0}, {X: 200, Y: 100}}, Col: red, Width: 2}},
+		},
+		{
+			name: "polygon closes",
+			svg:  `<svg><polygon points="0,0 4,0 4,4" stroke="#ff0000"/></svg>`,
+			want: []Stroke{{Pts: []f32.Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 0}}, Col: red, Width: 1}},
+		},
+		{
+			name: "dynamic width",
+			svg: `<svg><g stroke="#ff0000"><line x1="0" y1="0" x2="1" y2="0" stroke-width="2"/>` +
+				`<line x1="1" y1="0" x2="2" y2="0" stroke-width="4"/></g></svg>`,
+			want: []Stroke{{Pts: []f32.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}, Col: red, Width: 4, Widths: []float32{2, 2, 4}}},
+		},
+		{
+			name: "skipped",
+			svg: `<svg><circle cx="1" cy="1" r="1"/><text x="0" y="0">hi<tspan>there</tspan></text>` +
+				`<path d="M0,0 L1,1Z" fill="#ff0000" stroke="none"/><path d="M0,0 C1,1 2,2 3,3" stroke="#ff0000"/>` +
+				`<polyline points="5,5 6,6" stroke="#ff0000"/></svg>`,
+			want: []Stroke{{Pts: []f32.Point{{X: 5, Y: 5}, {X: 6, Y: 6}}, Col: red, Width: 1}},
+		},
+	} {
+		got, err := parseSVG(strings.NewReader(tc.svg), tc.name)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !slices.EqualFunc(got, tc.want, strokeEqual) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+	if _, err := parseSVG(strings.NewReader(`<svg><polyline`), "truncated"); err == nil {
+		t.Error("truncated: no error")
+	}
+}
+
+// TestSVGRoundTrip loads back what strokesSVG writes of plain strokes.
+func TestSVGRoundTrip(t *testing.T) {
+	strokes := []Stroke{
+		{Pts: []f32.Point{{X: 10, Y: 20}, {X: 30, Y: 40}, {X: 50, Y: 20}}, Col: color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}, Width: 6},
+		{Pts: []f32.Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 20, Y: 0}}, Col: color.NRGBA{G: 0xff, A: 0xff}, Width: 8, Widths: []float32{4, 6, 8}},
+	}
+	got, err := parseSVG(strings.NewReader(strokesSVG(strokes, image.Pt(100, 100))), "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(strokes) {
+		t.Fatalf("%d strokes, want %d", len(got), len(strokes))
+	}
+	if !slices.Equal(got[0].Pts, strokes[0].Pts) || got[0].Col != strokes[0].Col || got[0].Width != strokes[0].Width {
+		t.Errorf("pen stroke: got %+v, want %+v", got[0], strokes[0])
+	}
+	// A line per segment, each of the mean of its ends' widths.
+	if !slices.Equal(got[1].Pts, strokes[1].Pts) || !slices.Equal(got[1].Widths, []float32{5, 5, 7}) {
+		t.Errorf("dynamic-width stroke: got %+v", got[1])
+	}
+}
+
+// TestSVGShapeRoundTrip loads back the <rect> and <ellipse> of shape
+// strokes as the same shapes.
+func TestSVGShapeRoundTrip(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	rect := Stroke{Pts: rectPoints(f32.Pt(10, 20), f32.Pt(50, 40), 2), Col: red, Width: 4, Shape: "rectangle", FillAlpha: 0x80}
+	ellipse := Stroke{Pts: ellipsePoints(f32.Pt(0, 0), f32.Pt(30, 10), 2), Col: red, Width: 2, Shape: "ellipse"}
+	got, err := parseSVG(strings.NewReader(strokesSVG([]Stroke{rect, ellipse}, image.Pt(100, 100))), "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("%d strokes, want 2", len(got))
+	}
+	for i, want := range []Stroke{rect, ellipse} {
+		s := got[i]
+		gotMin, gotMax := bounds(s.Pts)
+		wantMin, wantMax := bounds(want.Pts)
+		if s.Shape != want.Shape || s.Col != want.Col || s.Width != want.Width || s.FillAlpha != want.FillAlpha ||
+			gotMin.Sub(wantMin).Round() != (image.Point{}) || gotMax.Sub(wantMax).Round() != (image.Point{}) {
+			t.Errorf("%s: got %s %v %v box %v-%v fill %#x, want box %v-%v", want.Shape, s.Shape, s.Col, s.Width, gotMin, gotMax, s.FillAlpha, wantMin, wantMax)
+		}
+	}
+}
+
+// strokeEqual compares the fields parseSVG fills in.
+func strokeEqual(s, t Stroke) bool {
+	return slices.Equal(s.Pts, t.Pts) && s.Col == t.Col && s.Width == t.Width && slices.Equal(s.Widths, t.Widths)
+}
@@ -0,0 +1,57 @@
+package main
+
+import "image"
+
+// Symmetry drawing mirrors every pen stroke across the vertical or the
+// horizontal center line of the window (of the drawing region, if one is
+// set), for symmetric sketches and diagrams. The counterpart is derived
+// from the stroke being drawn rather than tracked separately, so whatever
+// happens to that stroke (interpolation, dynamic width, shape recognition)
+// happens to both, and both are kept on release. Measurements, text and
+// step markers are not mirrored.
+
+type symmetry int
+
+const (
+	symmetryOff symmetry = iota
+	symmetryVertical
+	symmetryHorizontal
+)
+
+func (s symmetry) String() string {
+	switch s {
+	case symmetryVertical:
+		return "vertical axis"
+	case symmetryHorizontal:
+		return "horizontal axis"
+	}
+	return "off"
+}
+
+// cycleSymmetry steps through off, vertical and horizontal axis.
+func (a *Annotator) cycleSymmetry() {
+	a.symmetry = (a.symmetry + 1) % 3
+	a.notify("Symmetry: %s", a.symmetry)
+}
+
+// symmetric returns the mirrored counterpart of s, if it has one.
+func (a *Annotator) symmetric(s *Stroke) (Stroke, bool) {
+	if a.symmetry == symmetryOff || s.Measure || s.Text != "" || s.Step != 0 {
+		return Stroke{}, false
+	}
+	area := a.region
+	if area.Empty() {
+		area = image.Rectangle{Max: a.size}
+	}
+	// Twice the axis coordinate.
+	c := area.Min.Add(area.Max)
+	m := cloneStroke(*s)
+	for i, p := range m.Pts {
+		if a.symmetry == symmetryVertical {
+			m.Pts[i].X = float32(c.X) - p.X
+		} else {
+			m.Pts[i].Y = float32(c.Y) - p.Y
+		}
+	}
+	return m, true
+}
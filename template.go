@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// A template (-template) gives a set of screenshots the same structure:
+// it lists placeholders, each a prompt ("arrow to the Save button") with
+// optionally the tool and color to use and where on the canvas it goes,
+// and they are filled in order. The prompt of the current one is shown
+// at the top, its place marked, and committing a stroke fills it and
+// moves on; deleting that stroke again goes back. Tab skips a
+// placeholder, Shift+Tab returns to the previous one.
+//
+//	{
+//	  "name": "settings page",
+//	  "steps": [
+//	    {"prompt": "Circle the gear", "tool": "pen", "at": [0.95, 0.05]},
+//	    {"prompt": "Arrow to Save", "tool": "arrow", "color": "#00c853"}
+//	  ]
+//	}
+
+type templateFile struct {
+	Name  string         `json:"name"`
+	Steps []templateStep `json:"steps"`
+}
+
+type templateStep struct {
+	Prompt string `json:"prompt"`
+	// Tool and Color, if set, are selected when the step comes up.
+	Tool  string `json:"tool,omitempty"`
+	Color string `json:"color,omitempty"`
+	// At marks where the annotation goes, as fractions of the canvas.
+	At *[2]float32 `json:"at,omitempty"`
+}
+
+// placeholder is a step ready for use.
+type placeholder struct {
+	prompt string
+	tool   tool // -1 to keep the current one
+	col    *color.NRGBA
+	at     *f32.Point // fractions of the window
+}
+
+// guide is a template being filled in on one overlay.
+type guide struct {
+	name  string
+	steps []placeholder
+	step  int // len(steps) once done
+	seen  int // strokes as of the last frame
+}
+
+// loadTemplate reads a template file.
+func loadTemplate(path string) (*guide, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tf templateFile
+	if err := json.Unmarshal(data, &tf); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(tf.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", path)
+	}
+	g := &guide{name: tf.Name}
+	for i, st := range tf.Steps {
+		if st.Prompt == "" {
+			return nil, fmt.Errorf("%s: step %d: missing prompt", path, i+1)
+		}
+		p := placeholder{prompt: st.Prompt, tool: -1}
+		if st.Tool != "" {
+			if p.tool, err = parseTool(st.Tool); err != nil {
+				return nil, fmt.Errorf("%s: step %d: %w", path, i+1, err)
+			}
+		}
+		if st.Color != "" {
+			c, err := parseHexColor(st.Color)
+			if err != nil {
+				return nil, fmt.Errorf("%s: step %d: %w", path, i+1, err)
+			}
+			p.col = &c
+		}
+		if st.At != nil {
+			at := f32.Pt(st.At[0], st.At[1])
+			p.at = &at
+		}
+		g.steps = append(g.steps, p)
+	}
+	if g.name == "" {
+		g.name = path
+	}
+	return g, nil
+}
+
+// clone returns a fresh copy of g for another overlay.
+func (g *guide) clone() *guide {
+	c := *g
+	return &c
+}
+
+// startGuide sets up the first placeholder; the strokes already there
+// belong to none.
+func (a *Annotator) startGuide() {
+	g := a.guide
+	if g == nil {
+		return
+	}
+	g.seen = len(a.strokes)
+	a.enterStep(0)
+}
+
+// enterStep makes step i current, selecting its tool and color.
+func (a *Annotator) enterStep(i int) {
+	g := a.guide
+	g.step = min(max(i, 0), len(g.steps))
+	if g.step == len(g.steps) {
+		a.notify("Template %s: all %d steps done", g.name, len(g.steps))
+		return
+	}
+	p := g.steps[g.step]
+	if p.tool >= 0 {
+		a.tool = p.tool
+	}
+	if p.col != nil {
+		a.col = *p.col
+	}
+}
+
+// followGuide moves on when a stroke was committed since the last frame,
+// and back when one was deleted.
+func (a *Annotator) followGuide() {
+	g := a.guide
+	if g == nil {
+		return
+	}
+	n := len(a.strokes)
+	switch {
+	case n > g.seen && g.step < len(g.steps):
+		a.enterStep(g.step + 1)
+	case n < g.seen && g.step > 0:
+		a.enterStep(g.step - 1)
+	}
+	g.seen = n
+}
+
+// skipStep moves to the next (or with back, the previous) placeholder
+// without drawing.
+func (a *Annotator) skipStep(back bool) {
+	if a.guide == nil {
+		return
+	}
+	if back {
+		a.enterStep(a.guide.step - 1)
+	} else {
+		a.enterStep(a.guide.step + 1)
+	}
+}
+
+// drawGuide shows the current prompt at the top and marks its place.
+func (a *Annotator) drawGuide(gtx layout.Context) {
+	g := a.guide
+	if g == nil || g.step >= len(g.steps) {
+		return
+	}
+	p := g.steps[g.step]
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	txt := fmt.Sprintf("%s %d/%d: %s   (Tab skips)", g.name, g.step+1, len(g.steps), p.prompt)
+	a.drawLabel(gtx, image.Pt(gtx.Dp(16), gtx.Dp(16)), txt, white)
+	if p.at == nil {
+		return
+	}
+	c := f32.Pt(p.at.X*float32(a.size.X), p.at.Y*float32(a.size.Y))
+	r := float32(gtx.Dp(20))
+	box := image.Rectangle{Min: c.Sub(f32.Pt(r, r)).Round(), Max: c.Add(f32.Pt(r, r)).Round()}
+	// Dark under light, like the pen ring, to show on any content.
+	for _, o := range []struct {
+		col   color.NRGBA
+		width int
+	}{
+		{color.NRGBA{A: 0xa0}, gtx.Dp(4)},
+		{white, gtx.Dp(2)},
+	} {
+		path := clip.Ellipse(box).Path(gtx.Ops)
+		paint.FillShape(gtx.Ops, o.col, clip.Stroke{Path: path, Width: float32(o.width)}.Op())
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"io"
+	"strings"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Text annotations are Strokes with Text set: Pts[0] is the top-left
+// corner of the text, Width its font size in px and Col its color. Being
+// strokes, they take part in everything strokes do (clearing, the
+// timeline, sessions, export) without a parallel list.
+
+// Limits for pasted text: longer lines are wrapped at spaces (or cut when
+// there are none), and text beyond maxTextLines is dropped.
+const (
+	maxTextLineRunes = 80
+	maxTextLines     = 40
+)
+
+// textSizeDp is the font size that goes with a pen width, so text keeps
+// the "bigger pen, bigger marks" feel of the width keys.
+func textSizeDp(widthDp float32) float32 {
+	return 12 + 2*widthDp
+}
+
+// wrapText normalizes pasted text into at most maxTextLines lines of at
+// most maxTextLineRunes runes.
+func wrapText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	s = strings.ReplaceAll(s, "\t", "    ")
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(s, "\n "), "\n") {
+		r := []rune(strings.TrimRight(line, " "))
+		for len(r) > maxTextLineRunes {
+			cut, next := maxTextLineRunes, maxTextLineRunes
+			for i := maxTextLineRunes; i > 0; i-- {
+				if r[i] == ' ' {
+					cut, next = i, i+1
+					break
+				}
+			}
+			lines = append(lines, string(r[:cut]))
+			r = r[next:]
+		}
+		lines = append(lines, string(r))
+	}
+	if len(lines) > maxTextLines {
+		lines = lines[:maxTextLines]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pasteText places clipboard text (see place.go) as a text annotation in
+// the current color. Text that is a session in JSON, such as a .json
+// export, is placed as those strokes instead.
+func (a *Annotator) pasteText(gtx layout.Context, r io.Reader) {
+	data, err := io.ReadAll(io.LimitReader(r, 4<<20))
+	if err != nil {
+		a.notifyErr(err)
+		return
+	}
+	var sf sessionFile
+	if json.Unmarshal(data, &sf) == nil {
+		if strokes, err := sf.strokes(); err == nil && len(strokes) > 0 {
+			scaleStrokes(strokes, sf.canvas(), a.size)
+			a.startPlacing(strokes)
+			return
+		}
+	}
+	txt := wrapText(string(data))
+	if txt == "" {
+		a.notify("Clipboard has no text")
+		return
+	}
+	a.startPlacing([]Stroke{{
+		Pts:   []f32.Point{{}},
+		Col:   a.col,
+		Width: dpToPx(gtx, textSizeDp(a.widthDp)),
+		At:    time.Now(),
+		Text:  txt,
+	}})
+}
+
+// drawTextStroke lays out a text annotation with the Go fonts.
+func (a *Annotator) drawTextStroke(gtx layout.Context, s *Stroke) {
+	lbl := material.Label(a.theme(), unit.Sp(s.Width/gtx.Metric.PxPerSp), s.Text)
+	lbl.Color = s.Col
+	defer op.Offset(image.Pt(int(s.Pts[0].X), int(s.Pts[0].Y))).Push(gtx.Ops).Pop()
+	gtx.Constraints.Min = image.Point{}
+	gtx.Constraints.Max.X = max(gtx.Constraints.Max.X-int(s.Pts[0].X), 0)
+	lbl.Layout(gtx)
+}
+
+// goFace returns the Go Regular face at size px, for off-screen text.
+func goFace(size float64) (font.Face, error) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// rasterTextStroke draws a text annotation onto dst line by line.
+func rasterTextStroke(dst *image.RGBA, s *Stroke) {
+	face, err := goFace(float64(s.Width))
+	if err != nil {
+		return
+	}
+	defer face.Close()
+	m := face.Metrics()
+	d := font.Drawer{Dst: dst, Src: image.NewUniform(s.Col), Face: face}
+	for i, line := range strings.Split(s.Text, "\n") {
+		d.Dot = fixed.P(int(s.Pts[0].X), int(s.Pts[0].Y))
+		d.Dot.Y += m.Ascent + fixed.Int26_6(i)*m.Height
+		d.DrawString(line)
+	}
+}
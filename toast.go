@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// toastDuration is how long a status message stays on screen.
+const toastDuration = 2500 * time.Millisecond
+
+// toast is a short status message shown in the bottom-left corner, used
+// to confirm (or report the failure of) actions that have no other
+// visible effect, such as clipboard and file operations.
+type toast struct {
+	msg   string
+	err   bool
+	until time.Time
+}
+
+// notify shows msg as a toast. It is also logged, since a toast is easy
+// to miss.
+func (a *Annotator) notify(format string, args ...any) {
+	a.toast = toast{msg: fmt.Sprintf(format, args...), until: time.Now().Add(toastDuration)}
+	log.Print(a.toast.msg)
+}
+
+// notifyErr shows err as an error toast.
+func (a *Annotator) notifyErr(err error) {
+	a.toast = toast{msg: err.Error(), err: true, until: time.Now().Add(toastDuration)}
+	log.Print(a.toast.msg)
+}
+
+func (a *Annotator) drawToast(gtx layout.Context) {
+	if a.toast.msg == "" {
+		return
+	}
+	if !gtx.Now.Before(a.toast.until) {
+		a.toast = toast{}
+		return
+	}
+	fg := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	if a.toast.err {
+		fg = color.NRGBA{R: 255, G: 90, B: 90, A: 255}
+	}
+	m := gtx.Dp(16)
+	pos := image.Pt(m, gtx.Constraints.Max.Y-m-gtx.Dp(32))
+	a.drawLabel(gtx, pos, a.toast.msg, fg)
+	// Redraw once more when the toast expires so it disappears.
+	gtx.Execute(op.InvalidateCmd{At: a.toast.until})
+}
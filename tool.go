@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// tool is what dragging with the primary button draws.
+type tool int
+
+const (
+	toolPen       tool = iota // freehand stroke
+	toolArrow                 // freehand stroke ending in an arrowhead
+	toolPixelate              // freehand redaction of the background
+	toolMeasure               // straight line labeled with its length
+	toolStep                  // numbered step markers, placed by clicking
+	toolFill                  // flood fill of the background, by clicking
+	toolDot                   // a round dot per click
+	toolBlur                  // freehand blur of the background
+	toolHighlight             // translucent freehand marker with a flat nib
+)
+
+// Tool handles the primary button while its tool is active: handlePointer
+// passes it the presses, drags (already clamped to the drawing region)
+// and releases that nothing else (placing, region picking, prompts) took,
+// and frame has it render its in-progress state with the strokes. Tools
+// usually keep that state in a.cur and finish by appending to a.strokes,
+// where the stroke renderer, exports and sessions take over.
+//
+// Built-in tools are in this file; others register themselves with
+// registerTool from files of their own, usually behind a build tag so
+// that they are only compiled in on request, see tool_line.go.
+type Tool interface {
+	Press(a *Annotator, gtx layout.Context, pe pointer.Event)
+	Drag(a *Annotator, gtx layout.Context, pe pointer.Event)
+	Release(a *Annotator, gtx layout.Context, pe pointer.Event)
+	Render(a *Annotator, gtx layout.Context)
+}
+
+// toolNames and toolTable are indexed by tool.
+var toolNames = []string{
+	toolPen:       "pen",
+	toolArrow:     "arrow",
+	toolPixelate:  "pixelate",
+	toolMeasure:   "measure",
+	toolStep:      "step",
+	toolFill:      "fill",
+	toolDot:       "dot",
+	toolBlur:      "blur",
+	toolHighlight: "highlighter",
+}
+
+var toolTable = []Tool{
+	toolPen:       penTool{},
+	toolArrow:     penTool{},
+	toolPixelate:  penTool{},
+	toolMeasure:   penTool{},
+	toolStep:      stepTool{},
+	toolFill:      fillTool{},
+	toolDot:       dotTool{},
+	toolBlur:      penTool{},
+	toolHighlight: penTool{},
+}
+
+// registerTool adds a tool, selected by name with the tool control
+// command; it is meant to be called from init functions.
+func registerTool(name string, t Tool) tool {
+	if _, err := parseTool(name); err == nil {
+		panic(fmt.Sprintf("tool %q registered twice", name))
+	}
+	toolNames = append(toolNames, name)
+	toolTable = append(toolTable, t)
+	return tool(len(toolTable) - 1)
+}
+
+func (t tool) String() string { return toolNames[t] }
+
+func parseTool(s string) (tool, error) {
+	for t, name := range toolNames {
+		if s == name {
+			return tool(t), nil
+		}
+	}
+	return 0, fmt.Errorf("tool %q: want %s", s, strings.Join(toolNames, ", "))
+}
+
+func (a *Annotator) activeTool() Tool { return toolTable[a.tool] }
+
+// toggleTool switches to t, or back to the pen if t is already active.
+func (a *Annotator) toggleTool(t tool) {
+	if a.tool == t {
+		t = toolPen
+	}
+	a.setTool(t)
+}
+
+// setTool switches to t, widening the pen for the tools that want it.
+func (a *Annotator) setTool(t tool) {
+	a.tool = t
+	if (t == toolPixelate || t == toolBlur || t == toolHighlight) && a.widthDp < 20 {
+		// Redaction and highlighting want a wide brush.
+		a.widthDp = 20
+	}
+}
+
+// trackTool notices tool changes, however they were made, so that
+// swapTool can go back: prevTool is the tool before the current one.
+func (a *Annotator) trackTool() {
+	if a.tool == a.lastTool {
+		return
+	}
+	a.prevTool, a.lastTool = a.lastTool, a.tool
+}
+
+// swapTool switches to the previous tool, alternating between the two
+// on repeated use, for drawing and pointing, or marking and measuring.
+func (a *Annotator) swapTool() {
+	if a.prevTool == a.tool {
+		a.notify("No previous tool")
+		return
+	}
+	if a.cur != nil {
+		// Not in the middle of a stroke of the other tool.
+		return
+	}
+	a.tool = a.prevTool
+	a.trackTool()
+	a.notify("Tool: %s", a.tool)
+}
+
+// newStroke starts a stroke with the current tool and pen at p.
+func (a *Annotator) newStroke(gtx layout.Context, p f32.Point) *Stroke {
+	s := &Stroke{Pts: []f32.Point{p}, Col: a.col, Width: dpToPx(gtx, a.widthDp), At: gtx.Now, Join: a.penJoin, Cap: a.penCap}
+	switch a.tool {
+	case toolArrow:
+		s.Arrow, s.Head, s.BothEnds = true, a.arrowStyle, a.arrowBoth
+		s.Chalk, s.Hollow = a.chalk, a.hollow
+	case toolPen:
+		s.Chalk, s.Hollow = a.chalk, a.hollow
+	case toolPixelate:
+		s.Col, s.Pixelate = pixelPenColor, true
+	case toolBlur:
+		s.Col, s.Pixelate, s.Blur = pixelPenColor, true, true
+	case toolMeasure:
+		s.Measure = true
+		return s
+	case toolHighlight:
+		// The nib is flat whatever the speed.
+		s.Col, s.Highlight = highlightColor(a.col), true
+		return s
+	}
+	if a.dynWidth {
+		s.Widths = []float32{s.Width}
+	}
+	return s
+}
+
+// penTool draws the strokes of the pen, arrow, pixelate, blur,
+// highlighter and measure tools, which newStroke tells apart.
+type penTool struct{}
+
+func (penTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if !a.mergePress(gtx, pe) {
+		start := pe.Position
+		if a.joinStrokes != pe.Modifiers.Contain(key.ModShift) {
+			start = a.joinStart(start, max(float32(gtx.Dp(12)), dpToPx(gtx, a.widthDp)))
+		}
+		a.cur = a.newStroke(gtx, start)
+	}
+	a.dragTime = pe.Time
+	a.predictor = predictor{}
+	a.predictor.add(pe.Position, pe.Time, gtx.Now)
+}
+
+func (penTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur == nil {
+		return
+	}
+	pos := pe.Position
+	a.predictor.add(pos, pe.Time, gtx.Now)
+	if a.cur.Measure {
+		// A straight line from the press to the pointer.
+		a.cur.Pts = append(a.cur.Pts[:1], pos)
+		return
+	}
+	last := a.cur.Pts[len(a.cur.Pts)-1]
+	if a.rawPoints {
+		a.cur.Pts = append(a.cur.Pts, pos)
+	} else {
+		// Interpolate points so the line looks continuous (not dotted).
+		appendInterpolated(&a.cur.Pts, last, pos, a.cur.Width/2)
+	}
+	if a.cur.Widths != nil {
+		a.cur.extendWidths(a.velocityWidth(gtx, dist(last, pos), pe.Time))
+	}
+}
+
+func (penTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur != nil && a.cur.Measure && len(a.cur.Pts) < 2 {
+		// A click without a drag measures nothing.
+		a.cur = nil
+	}
+	if a.cur == nil {
+		return
+	}
+	if a.finishMerge() {
+		a.cur = nil
+		a.penReleased(pe.Time)
+		return
+	}
+	if l := pathLength(a.cur.Pts); a.minStrokeDp > 0 && l < dpToPx(gtx, a.minStrokeDp) {
+		// A stray click or twitch (-min-stroke); the dot tool makes
+		// points on purpose.
+		if a.debug {
+			log.Printf("discarded a %.1fpx stroke (-min-stroke %g)", l, a.minStrokeDp)
+		}
+		a.cur = nil
+		return
+	}
+	if a.recognize && !a.cur.Measure {
+		if s, ok := recognizeShape(*a.cur); ok {
+			*a.cur = s
+			a.styleShape(a.cur)
+		}
+	}
+	a.checkClearScribble(a.cur, gtx.Now)
+	a.autoHalo(a.cur)
+	a.strokes = append(a.strokes, *a.cur)
+	if m, ok := a.symmetric(a.cur); ok {
+		a.autoHalo(&m)
+		a.strokes = append(a.strokes, m)
+	}
+	a.cur = nil
+	a.penReleased(pe.Time)
+}
+
+func (penTool) Render(a *Annotator, gtx layout.Context) {
+	if a.cur == nil {
+		return
+	}
+	s := a.predicted(gtx)
+	a.paintStroke(gtx, s)
+	if m, ok := a.symmetric(s); ok {
+		a.paintStroke(gtx, &m)
+	}
+}
+
+// stepTool places a numbered marker per click.
+type stepTool struct{}
+
+func (stepTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.placeStep(gtx, pe.Position)
+}
+
+func (stepTool) Drag(*Annotator, layout.Context, pointer.Event)    {}
+func (stepTool) Release(*Annotator, layout.Context, pointer.Event) {}
+func (stepTool) Render(*Annotator, layout.Context)                 {}
+
+// fillTool fills the background area under each click (fill.go).
+type fillTool struct{}
+
+func (fillTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.fillAt(gtx, pe.Position)
+}
+
+func (fillTool) Drag(*Annotator, layout.Context, pointer.Event)    {}
+func (fillTool) Release(*Annotator, layout.Context, pointer.Event) {}
+func (fillTool) Render(*Annotator, layout.Context)                 {}
+
+// dotTool places a dot of the pen width per click, which -min-stroke
+// would otherwise discard as a stray click.
+type dotTool struct{}
+
+func (dotTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.strokes = append(a.strokes, *a.newStroke(gtx, pe.Position))
+}
+
+func (dotTool) Drag(*Annotator, layout.Context, pointer.Event)    {}
+func (dotTool) Release(*Annotator, layout.Context, pointer.Event) {}
+func (dotTool) Render(*Annotator, layout.Context)                 {}
@@ -0,0 +1,41 @@
+//go:build tool_line
+
+package main
+
+import (
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// The line tool is an example of a tool outside the core, compiled in with
+// -tags tool_line: a straight line from the press to the release, in the
+// current color and width. Select it with the "tool line" control command.
+
+var toolLine = registerTool("line", lineTool{})
+
+type lineTool struct{}
+
+func (lineTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.cur = a.newStroke(gtx, pe.Position)
+}
+
+func (lineTool) Drag(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur != nil {
+		a.cur.Pts = append(a.cur.Pts[:1], pe.Position)
+	}
+}
+
+func (lineTool) Release(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	if a.cur != nil && len(a.cur.Pts) > 1 {
+		a.cur.Widths = nil
+		a.autoHalo(a.cur)
+		a.strokes = append(a.strokes, *a.cur)
+	}
+	a.cur = nil
+}
+
+func (lineTool) Render(a *Annotator, gtx layout.Context) {
+	if a.cur != nil {
+		a.paintStroke(gtx, a.cur)
+	}
+}
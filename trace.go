@@ -0,0 +1,38 @@
+package main
+
+import (
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+)
+
+// The trace layer (-trace) is a previous session shown faintly under the
+// strokes as a guide for annotating a series of screenshots alike. It is
+// not part of the drawing: it cannot be selected, is not cleared, autosaved
+// or exported, until flattening (Ctrl+F) turns it into ordinary strokes.
+
+// traceOpacity is how strongly the trace layer is shown.
+const traceOpacity = 0.3
+
+func (a *Annotator) drawTrace(gtx layout.Context) {
+	if a.trace == nil {
+		return
+	}
+	defer paint.PushOpacity(gtx.Ops, traceOpacity).Pop()
+	for i := range a.trace {
+		a.paintStroke(gtx, &a.trace[i])
+	}
+}
+
+// flattenTrace moves the trace layer below the strokes as strokes of
+// their own.
+func (a *Annotator) flattenTrace() {
+	if a.trace == nil {
+		a.notify("No trace layer (-trace)")
+		return
+	}
+	a.notify("Flattened %d traced strokes", len(a.trace))
+	a.strokes = append(a.trace, a.strokes...)
+	a.trace = nil
+	a.sel = -1
+	a.dropHistory()
+}
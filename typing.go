@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// The text tool (Shift+T) types text annotations: a click puts a caret
+// there, and what is typed goes into the text, in the pen color and the
+// text size of the pen width, instead of running the keys, so R types an
+// R rather than picking red. Backspace deletes, Enter starts a new line,
+// and Escape or a click elsewhere commits the text (the click starting
+// the next one); text left empty is dropped. The text is a Text stroke
+// like pasted text (text.go), wrapped the same way.
+
+var toolText = registerTool("text", textTool{})
+
+// startTyping commits the text being typed, if any, and puts the caret
+// at p.
+func (a *Annotator) startTyping(gtx layout.Context, p f32.Point) {
+	a.commitTyping()
+	a.typing = &Stroke{
+		Pts:   []f32.Point{p},
+		Col:   a.col,
+		Width: dpToPx(gtx, textSizeDp(a.widthDp)),
+		At:    gtx.Now,
+	}
+}
+
+// commitTyping adds the text being typed to the strokes.
+func (a *Annotator) commitTyping() {
+	s := a.typing
+	a.typing = nil
+	if s == nil {
+		return
+	}
+	if s.Text = wrapText(s.Text); strings.TrimSpace(s.Text) != "" {
+		a.strokes = append(a.strokes, *s)
+	}
+}
+
+// typingEdit consumes typed text.
+func (a *Annotator) typingEdit(txt string) {
+	a.typing.Text += strings.Map(func(r rune) rune {
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, txt)
+}
+
+// typingKey handles the editing keys while typing; the others do
+// nothing.
+func (a *Annotator) typingKey(ke key.Event) {
+	switch ke.Name {
+	case key.NameDeleteBackward:
+		if r := []rune(a.typing.Text); len(r) > 0 {
+			a.typing.Text = string(r[:len(r)-1])
+		}
+	case key.NameReturn, key.NameEnter:
+		a.typing.Text += "\n"
+	case key.NameEscape:
+		a.commitTyping()
+	}
+}
+
+// drawTyping shows the text being typed with a caret at its end.
+func (a *Annotator) drawTyping(gtx layout.Context) {
+	if a.typing == nil {
+		return
+	}
+	s := *a.typing
+	s.Text += "_"
+	a.drawTextStroke(gtx, &s)
+}
+
+// textTool starts a text per click.
+type textTool struct{}
+
+func (textTool) Press(a *Annotator, gtx layout.Context, pe pointer.Event) {
+	a.startTyping(gtx, pe.Position)
+}
+
+func (textTool) Drag(*Annotator, layout.Context, pointer.Event)    {}
+func (textTool) Release(*Annotator, layout.Context, pointer.Event) {}
+func (textTool) Render(*Annotator, layout.Context)                 {}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Undo (Ctrl+Z) takes the last stroke off, onto the redo stack, and redo
+// (Ctrl+Shift+Z or Ctrl+Y) puts it back; with nothing left to undo or
+// redo they do nothing. Undo while a stroke is being drawn drops that
+// stroke instead, as Escape would. Locked strokes stay, as they do for
+// the other ways of removing strokes. Changes made to strokes already
+// there are steps of their own, which undo reverts rather than taking a
+// stroke off: a drag of the eraser (eraser.go) brings back what it took,
+// deleting (Delete) or duplicating (Ctrl+D) one, locking it (Ctrl+Shift+L),
+// relabeling a dimension line (Ctrl+Shift+M), turning the last stroke
+// into an arrow (>), resizing one with the handles (handles.go), moving
+// it to the front or back (PgUp, PgDn), rotating it (Ctrl+[, Ctrl+]) or
+// giving it the pen width (E) gives back the strokes as they were. Each change keeps the strokes as
+// they were before it and comes back once undo has gone back to where
+// it was made. Any other change of the strokes, a new one or a clear,
+// drops the redo stack, since what it holds no longer goes on top of
+// what is there; replacing the strokes as a whole drops the changes too.
+//
+// New strokes are only ever appended and undone from the end, which the
+// mark of the strokes tells; every other edit is a change, which stamps
+// the strokes with a generation of their own. A change is the one to undo
+// while both its mark and its generation are those of the strokes.
+
+// strokesMark is what drawing, merging, undo and clearing change of the
+// strokes: their number, or the points of the last.
+type strokesMark struct{ n, last int }
+
+func (a *Annotator) strokesMark() strokesMark {
+	m := strokesMark{n: len(a.strokes)}
+	if m.n > 0 {
+		m.last = len(a.strokes[m.n-1].Pts)
+	}
+	return m
+}
+
+// change is an edit of the strokes that undo takes back as a whole: the
+// strokes before it, the mark of those after, and the generations of the
+// strokes before and after.
+type change struct {
+	before    []Stroke
+	mark      strokesMark
+	base, gen int
+}
+
+// undoStep is an undone stroke, or an undone change with the strokes it
+// had made.
+type undoStep struct {
+	stroke Stroke
+	change *change
+	after  []Stroke
+}
+
+// cloneStrokes copies strokes, points included, so that edits made in
+// place do not reach the copy.
+func cloneStrokes(strokes []Stroke) []Stroke {
+	c := make([]Stroke, len(strokes))
+	for i, s := range strokes {
+		c[i] = cloneStroke(s)
+	}
+	return c
+}
+
+// beginChange keeps the strokes as they are, for a change about to be
+// made to them.
+func (a *Annotator) beginChange() *change {
+	a.trackUndo()
+	return &change{before: cloneStrokes(a.strokes)}
+}
+
+// commitChange makes c, now made, a step for undo.
+func (a *Annotator) commitChange(c *change) {
+	a.gens++
+	c.mark, c.base, c.gen = a.strokesMark(), a.gen, a.gens
+	a.gen = c.gen
+	a.changes = append(a.changes, c)
+	a.redone = nil
+	a.undoMark = c.mark
+}
+
+// edit runs f, an edit of existing strokes reporting whether it made
+// one, as a step for undo.
+func (a *Annotator) edit(f func() bool) bool {
+	c := a.beginChange()
+	if !f() {
+		return false
+	}
+	a.commitChange(c)
+	return true
+}
+
+// undo takes the last stroke, or the one being drawn, off, or reverts
+// the last change if nothing came after it.
+func (a *Annotator) undo(gtx layout.Context) {
+	defer gtx.Execute(op.InvalidateCmd{})
+	a.trackUndo()
+	if a.cur != nil {
+		a.cur = nil
+		return
+	}
+	if k := len(a.changes); k > 0 && a.changes[k-1].mark == a.strokesMark() && a.changes[k-1].gen == a.gen {
+		c := a.changes[k-1]
+		a.changes = a.changes[:k-1]
+		a.redone = append(a.redone, undoStep{change: c, after: a.strokes})
+		a.strokes = cloneStrokes(c.before)
+		a.sel = -1
+		a.gen = c.base
+		a.undoMark = a.strokesMark()
+		return
+	}
+	n := len(a.strokes)
+	if n == 0 || !a.checkUnlocked(&a.strokes[n-1]) {
+		return
+	}
+	a.redone = append(a.redone, undoStep{stroke: a.strokes[n-1]})
+	a.strokes = a.strokes[:n-1]
+	if a.sel >= len(a.strokes) {
+		a.sel = -1
+	}
+	a.undoMark = a.strokesMark()
+}
+
+// redo puts the last undone stroke back, or makes the last undone change
+// again.
+func (a *Annotator) redo(gtx layout.Context) {
+	a.trackUndo()
+	n := len(a.redone)
+	if n == 0 || a.cur != nil {
+		return
+	}
+	step := a.redone[n-1]
+	a.redone = a.redone[:n-1]
+	if c := step.change; c != nil {
+		a.strokes = step.after
+		a.sel = -1
+		a.gen = c.gen
+		a.changes = append(a.changes, c)
+	} else {
+		a.strokes = append(a.strokes, step.stroke)
+	}
+	a.undoMark = a.strokesMark()
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// dropHistory forgets the changes and the redo stack once the strokes
+// were replaced or rearranged as a whole: cleared, swapped for those of
+// the other pane, cut down to -max-strokes, moved with the background or
+// joined by the trace layer. Their marks would otherwise match the
+// strokes that came instead.
+func (a *Annotator) dropHistory() {
+	a.changes, a.redone, a.erasing = nil, nil, nil
+	a.undoMark = a.strokesMark()
+}
+
+// trackUndo drops the redo stack once the strokes changed other than by
+// undo and redo.
+func (a *Annotator) trackUndo() {
+	if m := a.strokesMark(); m != a.undoMark {
+		a.redone = nil
+		a.undoMark = m
+	}
+}
@@ -0,0 +1,85 @@
+//go:build linux && !android
+
+package main
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"gioui.org/app"
+	"gioui.org/io/event"
+)
+
+// viewEvent handles the events that hand over the native window: on X11
+// the overlay places the window on its monitor and makes it an overlay,
+// on Wayland the compositor places it (wayland.go).
+func (a *Annotator) viewEvent(e event.Event) {
+	switch e := e.(type) {
+	case app.X11ViewEvent:
+		if !a.x11Ready && e.Valid() {
+			a.placeWindow(e)
+			a.x11Ready = true
+		}
+		a.tryEnableOverlay(e)
+	case app.WaylandViewEvent:
+		if a.debug && e.Valid() {
+			log.Printf("wayland: the compositor places the fullscreen window (wayland.go)")
+		}
+	}
+}
+
+// placeWindow moves the window onto its monitor before fullscreen takes
+// effect: the assigned one with -all-monitors, else the pointer's.
+func (a *Annotator) placeWindow(e app.X11ViewEvent) {
+	if a.fullscreen == fullscreenOverride {
+		a.coverMonitor(e.Display, e.Window)
+		return
+	}
+	if a.monitor != nil {
+		p := a.monitor.Min.Add(image.Pt(50, 50))
+		if err := x11MoveWindowTo(e.Display, e.Window, p.X, p.Y); err != nil {
+			if a.debug {
+				log.Printf("x11 move to monitor %v failed: %v", *a.monitor, err)
+			}
+		} else if a.debug {
+			log.Printf("x11 moved window to monitor %v (win=0x%x)", *a.monitor, e.Window)
+		}
+		return
+	}
+	if err := x11MoveWindowToPointer(e.Display, e.Window); err != nil {
+		if a.debug {
+			log.Printf("x11 move-to-pointer failed: %v", err)
+		}
+	} else if a.debug {
+		log.Printf("x11 moved window to pointer monitor (win=0x%x)", e.Window)
+	}
+}
+
+func (a *Annotator) tryEnableOverlay(e app.X11ViewEvent) {
+	if a.x11OverlayTried {
+		return
+	}
+	a.x11OverlayTried = true
+	a.x11Display = e.Display
+	a.x11Window = e.Window
+	netwm := a.fullscreen == fullscreenNetWM || a.fullscreen == fullscreenBoth
+	if err := x11EnableOverlayHints(e.Display, e.Window, netwm); err != nil {
+		if a.debug {
+			log.Printf("x11 overlay hints failed: %v", err)
+		}
+		return
+	}
+	if a.opacity == 0 {
+		a.opacity = 0x50000000 // ~30% opacity
+	}
+	_ = x11SetOpacity(e.Display, e.Window, a.opacity)
+	_ = x11SetClickThrough(e.Display, e.Window, a.clickThrough)
+	if a.debug {
+		log.Printf("x11 overlay enabled (opacity=0x%08x clickThrough=%v)", a.opacity, a.clickThrough)
+	}
+	// Let the WM finish placing the window on its monitor first.
+	if a.bgSrc == nil && a.feed == nil {
+		a.requestCapture(300 * time.Millisecond)
+	}
+}
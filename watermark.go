@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// A watermark (-watermark, -watermark-image) is put onto every PNG
+// export once it is drawn, cropped and flattened: a line of text, an
+// image (a logo, a "CONFIDENTIAL" banner) or both, the text beside the
+// image, in a corner or the center of the picture at -watermark-opacity.
+// Text comes out white with a dark shadow, so it reads on any content.
+// The overlay itself never shows it. Comparison exports get one for the
+// whole picture, not one per pane.
+
+// watermarkMargin is how far the watermark keeps from the edges, and
+// from the image to the text, in px.
+const watermarkMargin = 16
+
+// watermarkCorners are the places -watermark-corner takes.
+var watermarkCorners = []string{"bottom-right", "bottom-left", "top-right", "top-left", "center"}
+
+type watermark struct {
+	text    string
+	size    float64 // of the text, px
+	img     image.Image
+	corner  string
+	opacity float64 // 0..1
+}
+
+// parseWatermark makes the watermark of the flags, or nil for none.
+func parseWatermark(text, imgPath, corner string, size, opacity float64) (*watermark, error) {
+	if text == "" && imgPath == "" {
+		return nil, nil
+	}
+	if !slices.Contains(watermarkCorners, corner) {
+		return nil, fmt.Errorf("corner %q: want %s", corner, strings.Join(watermarkCorners, ", "))
+	}
+	if opacity <= 0 || opacity > 1 {
+		return nil, fmt.Errorf("opacity %g: want more than 0, up to 1", opacity)
+	}
+	w := &watermark{text: text, size: max(size, 6), corner: corner, opacity: opacity}
+	if imgPath != "" {
+		img, err := loadImage(imgPath)
+		if err != nil {
+			return nil, err
+		}
+		w.img = img
+	}
+	return w, nil
+}
+
+// apply returns img with the watermark on it.
+func (w *watermark) apply(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+
+	var face font.Face
+	var textW, ascent, descent int
+	if w.text != "" {
+		var err error
+		if face, err = goFace(w.size); err == nil {
+			defer face.Close()
+			m := face.Metrics()
+			textW, ascent, descent = font.MeasureString(face, w.text).Ceil(), m.Ascent.Ceil(), m.Descent.Ceil()
+		}
+	}
+	var imgSize image.Point
+	if w.img != nil {
+		imgSize = w.img.Bounds().Size()
+	}
+	// The box of the image and the text side by side.
+	size := image.Pt(imgSize.X+textW, max(imgSize.Y, ascent+descent))
+	if imgSize.X > 0 && textW > 0 {
+		size.X += watermarkMargin
+	}
+	var at image.Point
+	switch w.corner {
+	case "top-left":
+		at = b.Min.Add(image.Pt(watermarkMargin, watermarkMargin))
+	case "top-right":
+		at = image.Pt(b.Max.X-watermarkMargin-size.X, b.Min.Y+watermarkMargin)
+	case "bottom-left":
+		at = image.Pt(b.Min.X+watermarkMargin, b.Max.Y-watermarkMargin-size.Y)
+	case "center":
+		at = b.Min.Add(b.Size().Sub(size).Div(2))
+	default:
+		at = b.Max.Sub(size).Sub(image.Pt(watermarkMargin, watermarkMargin))
+	}
+	a8 := uint8(w.opacity*0xff + 0.5)
+	alpha := image.NewUniform(color.Alpha{A: a8})
+	if w.img != nil {
+		min := image.Pt(at.X, at.Y+(size.Y-imgSize.Y)/2)
+		r := image.Rectangle{Min: min, Max: min.Add(imgSize)}
+		draw.DrawMask(dst, r, w.img, w.img.Bounds().Min, alpha, image.Point{}, draw.Over)
+		at.X += imgSize.X + watermarkMargin
+	}
+	if face != nil {
+		baseline := at.Y + (size.Y-ascent-descent)/2 + ascent
+		shadow := color.NRGBA{A: uint8(w.opacity * 0xa0)}
+		text := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: a8}
+		for _, l := range []struct {
+			off image.Point
+			col color.NRGBA
+		}{{image.Pt(1, 1), shadow}, {image.Point{}, text}} {
+			d := font.Drawer{Dst: dst, Src: image.NewUniform(l.col), Face: face, Dot: fixed.P(at.X+l.off.X, baseline+l.off.Y)}
+			d.DrawString(w.text)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Under Wayland a client cannot move its window, and Gio does not pass an
+// output to xdg_toplevel.set_fullscreen, so the compositor decides where
+// the fullscreen overlay goes: Sway, Hyprland and most others put it on
+// the focused output, which with focus following the mouse is the one
+// under the pointer. -output names the output to use instead; the
+// overlay focuses it through the compositor's IPC (swaymsg, hyprctl)
+// before opening, and if that is not possible, it still opens where the
+// compositor puts it. None of this touches the X11 path (XWayland
+// included), which places the window itself.
+
+// onWayland reports whether the session is a Wayland one.
+func onWayland() bool {
+	return os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// focusWaylandOutput asks the compositor to focus the output name, e.g.
+// DP-1 (swaymsg -t get_outputs, hyprctl monitors).
+func focusWaylandOutput(name string) error {
+	var cmd []string
+	switch {
+	case os.Getenv("SWAYSOCK") != "":
+		cmd = []string{"swaymsg", "focus", "output", name}
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		cmd = []string{"hyprctl", "dispatch", "focusmonitor", name}
+	default:
+		return errors.New("only Sway and Hyprland can be asked for an output")
+	}
+	out, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(cmd, " "), err, strings.TrimSpace(string(out)))
+	}
+	// hyprctl reports failures in its output, exiting 0.
+	if msg := strings.TrimSpace(string(out)); cmd[0] == "hyprctl" && msg != "ok" {
+		return fmt.Errorf("%s: %s", strings.Join(cmd, " "), msg)
+	}
+	return nil
+}
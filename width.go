@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/paint"
+)
+
+// Timing of the pen width readout: it shows for widthHintDuration after
+// the last change, fading out over its final widthHintFade.
+const (
+	widthHintDuration = time.Second
+	widthHintFade     = 300 * time.Millisecond
+)
+
+// drawWidthHint shows the pen width next to the pointer whenever it has
+// changed, whether by key, control command or otherwise.
+func (a *Annotator) drawWidthHint(gtx layout.Context) {
+	if a.widthDp != a.hintWidthDp {
+		a.hintWidthDp = a.widthDp
+		a.widthHint = toast{
+			msg:   fmt.Sprintf("%g dp · %.0f px", a.widthDp, dpToPx(gtx, a.widthDp)),
+			until: gtx.Now.Add(widthHintDuration),
+		}
+	}
+	if a.widthHint.msg == "" {
+		return
+	}
+	left := a.widthHint.until.Sub(gtx.Now)
+	if left <= 0 {
+		a.widthHint = toast{}
+		return
+	}
+	// Above and to the right of the pointer, clear of the coordinate
+	// readout; centered when the pointer is outside the window.
+	pos := image.Pt(gtx.Constraints.Max.X/2, gtx.Constraints.Max.Y/2)
+	if a.ptrIn {
+		off := gtx.Dp(18)
+		pos = image.Pt(int(a.ptr.X)+off, int(a.ptr.Y)-off-gtx.Dp(32))
+	}
+	if left < widthHintFade {
+		defer paint.PushOpacity(gtx.Ops, float32(left)/float32(widthHintFade)).Pop()
+		gtx.Execute(op.InvalidateCmd{})
+	} else {
+		gtx.Execute(op.InvalidateCmd{At: a.widthHint.until.Add(-widthHintFade)})
+	}
+	a.drawLabel(gtx, pos, a.widthHint.msg, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+}
+
+// toggleEmphasis switches the pen between its width and twice that. A
+// width changed in other ways meanwhile ends the emphasis, so the next
+// toggle doubles the new width rather than restoring an old one.
+func (a *Annotator) toggleEmphasis() {
+	if a.emphasisDp != 0 && a.widthDp == a.emphasisDp {
+		a.widthDp, a.emphasisDp = a.baseWidthDp, 0
+		return
+	}
+	a.baseWidthDp = a.widthDp
+	a.widthDp = min(2*a.widthDp, 100)
+	a.emphasisDp = a.widthDp
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// The wireframe view (F12, with ANNOTATOR_DEBUG set) shows the point data
+// of the strokes instead of their ink: the points, as small squares, the
+// lines between them and each stroke's point count, to see what
+// interpolation, smoothing and simplification did. It only changes how
+// the overlay paints strokes; exports and sessions are untouched.
+
+// toggleWireframe switches the wireframe view, for debugging only.
+func (a *Annotator) toggleWireframe() {
+	if !a.debug {
+		return
+	}
+	a.wireframe = !a.wireframe
+	if a.wireframe {
+		a.notify("Wireframe: on")
+	} else {
+		a.notify("Wireframe: off")
+	}
+}
+
+// drawWireframe paints the points of s and the lines between them in its
+// color, made opaque so faint strokes show too.
+func (a *Annotator) drawWireframe(gtx layout.Context, s *Stroke) {
+	if len(s.Pts) == 0 {
+		return
+	}
+	col := s.Col
+	col.A = 0xff
+	var p clip.Path
+	p.Begin(gtx.Ops)
+	p.MoveTo(s.Pts[0])
+	for _, pt := range s.Pts[1:] {
+		p.LineTo(pt)
+	}
+	paint.FillShape(gtx.Ops, col, clip.Stroke{Path: p.End(), Width: float32(gtx.Dp(1))}.Op())
+	d := gtx.Dp(2)
+	for _, pt := range s.Pts {
+		c := image.Pt(int(pt.X), int(pt.Y))
+		paint.FillShape(gtx.Ops, col, clip.Rect{Min: c.Sub(image.Pt(d, d)), Max: c.Add(image.Pt(d, d))}.Op())
+	}
+	at := s.Pts[len(s.Pts)-1]
+	off := gtx.Dp(6)
+	a.drawLabel(gtx, image.Pt(int(at.X)+off, int(at.Y)+off), fmt.Sprintf("%d pts", len(s.Pts)), color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+}
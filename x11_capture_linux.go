@@ -0,0 +1,125 @@
+//go:build linux && !android
+
+package main
+
+/*
+#cgo linux LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/Xutil.h>
+#include <stdlib.h>
+
+static int capture_xerr = 0;
+static int capture_err_handler(Display* dpy, XErrorEvent* e) {
+    (void)dpy;
+    capture_xerr = e->error_code;
+    return 0;
+}
+
+// window_root_geometry reports the window's rectangle in root coordinates,
+// clamped to the root window so XGetImage never sees an out-of-bounds area.
+static int window_root_geometry(Display* dpy, Window win, int* x, int* y, int* w, int* h) {
+    XWindowAttributes wa, ra;
+    Window child;
+    if (!XGetWindowAttributes(dpy, win, &wa)) return 0;
+    if (!XGetWindowAttributes(dpy, wa.root, &ra)) return 0;
+    if (!XTranslateCoordinates(dpy, win, wa.root, 0, 0, x, y, &child)) return 0;
+    int x0 = *x < 0 ? 0 : *x;
+    int y0 = *y < 0 ? 0 : *y;
+    int x1 = *x + wa.width > ra.width ? ra.width : *x + wa.width;
+    int y1 = *y + wa.height > ra.height ? ra.height : *y + wa.height;
+    if (x1 <= x0 || y1 <= y0) return 0;
+    *x = x0; *y = y0; *w = x1 - x0; *h = y1 - y0;
+    return 1;
+}
+
+static int mask_shift(unsigned long mask) {
+    int s = 0;
+    if (mask == 0) return 0;
+    while (!(mask & 1)) { mask >>= 1; s++; }
+    return s;
+}
+
+static unsigned char scale_channel(unsigned long px, unsigned long mask, int shift) {
+    unsigned long max = mask >> shift;
+    if (max == 0) return 0;
+    return (unsigned char)(((px & mask) >> shift) * 255 / max);
+}
+
+// capture_root copies a root window area into out as packed RGBA.
+static int capture_root(Display* dpy, int x, int y, int w, int h, unsigned char* out) {
+    int (*old)(Display*, XErrorEvent*) = XSetErrorHandler(capture_err_handler);
+    capture_xerr = 0;
+    XImage* img = XGetImage(dpy, DefaultRootWindow(dpy), x, y, w, h, AllPlanes, ZPixmap);
+    XSync(dpy, False);
+    XSetErrorHandler(old);
+    if (!img || capture_xerr != 0) {
+        if (img) XDestroyImage(img);
+        return 0;
+    }
+    int fast = img->bits_per_pixel == 32 && img->byte_order == LSBFirst &&
+        img->red_mask == 0xff0000 && img->green_mask == 0xff00 && img->blue_mask == 0xff;
+    int rs = mask_shift(img->red_mask), gs = mask_shift(img->green_mask), bs = mask_shift(img->blue_mask);
+    for (int j = 0; j < h; j++) {
+        unsigned char* dst = out + (size_t)j * w * 4;
+        if (fast) {
+            unsigned char* src = (unsigned char*)img->data + (size_t)j * img->bytes_per_line;
+            for (int i = 0; i < w; i++) {
+                dst[4*i+0] = src[4*i+2];
+                dst[4*i+1] = src[4*i+1];
+                dst[4*i+2] = src[4*i+0];
+                dst[4*i+3] = 255;
+            }
+            continue;
+        }
+        for (int i = 0; i < w; i++) {
+            unsigned long px = XGetPixel(img, i, j);
+            dst[4*i+0] = scale_channel(px, img->red_mask, rs);
+            dst[4*i+1] = scale_channel(px, img->green_mask, gs);
+            dst[4*i+2] = scale_channel(px, img->blue_mask, bs);
+            dst[4*i+3] = 255;
+        }
+    }
+    XDestroyImage(img);
+    return 1;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+// x11WindowOrigin reports the window's top-left corner in root (screen)
+// coordinates.
+func x11WindowOrigin(display unsafe.Pointer, window uintptr) (image.Point, error) {
+	if display == nil || window == 0 {
+		return image.Point{}, fmt.Errorf("invalid X11 handles")
+	}
+	var x, y, w, h C.int
+	if C.window_root_geometry((*C.Display)(display), C.Window(window), &x, &y, &w, &h) == 0 {
+		return image.Point{}, fmt.Errorf("query window geometry failed")
+	}
+	return image.Pt(int(x), int(y)), nil
+}
+
+// x11CaptureScreen grabs the screen pixels under window (i.e. the monitor
+// a fullscreen overlay covers). The caller is responsible for hiding the
+// overlay first if it should not appear in the result.
+func x11CaptureScreen(display unsafe.Pointer, window uintptr) (*image.RGBA, error) {
+	if display == nil || window == 0 {
+		return nil, fmt.Errorf("invalid X11 handles")
+	}
+	dpy := (*C.Display)(display)
+	win := C.Window(window)
+	var x, y, w, h C.int
+	if C.window_root_geometry(dpy, win, &x, &y, &w, &h) == 0 {
+		return nil, fmt.Errorf("query window geometry failed")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
+	if C.capture_root(dpy, x, y, w, h, (*C.uchar)(unsafe.Pointer(&img.Pix[0]))) == 0 {
+		return nil, fmt.Errorf("XGetImage failed")
+	}
+	return img, nil
+}
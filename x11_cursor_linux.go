@@ -0,0 +1,57 @@
+//go:build linux && !android
+
+package main
+
+/*
+#cgo linux LDFLAGS: -lX11 -lXfixes
+#include <X11/Xlib.h>
+#include <X11/extensions/Xfixes.h>
+
+static int has_xfixes(Display* dpy) {
+    int event_base, error_base;
+    return XFixesQueryExtension(dpy, &event_base, &error_base);
+}
+
+static void set_cursor_hidden(Display* dpy, Window win, int hidden) {
+    if (hidden) {
+        XFixesHideCursor(dpy, win);
+    } else {
+        XFixesShowCursor(dpy, win);
+    }
+    XFlush(dpy);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// x11HideCursor hides the system cursor until x11ShowCursor (XFixes). The
+// X server shows it again by itself once the connection is closed, so a
+// crash cannot leave it hidden.
+func x11HideCursor(display unsafe.Pointer, window uintptr) error {
+	return x11SetCursorHidden(display, window, true)
+}
+
+// x11ShowCursor undoes x11HideCursor.
+func x11ShowCursor(display unsafe.Pointer, window uintptr) error {
+	return x11SetCursorHidden(display, window, false)
+}
+
+func x11SetCursorHidden(display unsafe.Pointer, window uintptr, hidden bool) error {
+	if display == nil || window == 0 {
+		return fmt.Errorf("invalid X11 handles")
+	}
+	dpy := (*C.Display)(display)
+	if C.has_xfixes(dpy) == 0 {
+		return fmt.Errorf("no XFixes extension")
+	}
+	h := C.int(0)
+	if hidden {
+		h = 1
+	}
+	C.set_cursor_hidden(dpy, C.Window(window), h)
+	return nil
+}
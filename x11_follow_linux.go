@@ -0,0 +1,165 @@
+//go:build linux && !android
+
+package main
+
+/*
+#cgo linux LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+#include <stdlib.h>
+#include <string.h>
+
+static int follow_xerr = 0;
+static int follow_err_handler(Display* dpy, XErrorEvent* e) {
+    (void)dpy;
+    follow_xerr = e->error_code;
+    return 0;
+}
+
+// followed_geometry reports the rectangle of another client's window in
+// root coordinates. The window may be gone by now, which must not take
+// the program down with Xlib's default error handler.
+static int followed_geometry(Display* dpy, Window win, int* x, int* y, int* w, int* h, int* viewable) {
+    int (*old)(Display*, XErrorEvent*) = XSetErrorHandler(follow_err_handler);
+    follow_xerr = 0;
+    XWindowAttributes wa;
+    Window child;
+    int ok = XGetWindowAttributes(dpy, win, &wa) &&
+        XTranslateCoordinates(dpy, win, wa.root, 0, 0, x, y, &child);
+    XSync(dpy, False);
+    XSetErrorHandler(old);
+    if (!ok || follow_xerr != 0) return 0;
+    *w = wa.width;
+    *h = wa.height;
+    *viewable = wa.map_state == IsViewable;
+    return 1;
+}
+
+// top_window_under_pointer returns the top-level window (usually the
+// WM's frame) under the pointer, on its own connection.
+static Window top_window_under_pointer(void) {
+    Display* dpy = XOpenDisplay(NULL);
+    if (!dpy) return 0;
+    Window ret_root, child = 0;
+    int rx, ry, wx, wy;
+    unsigned int mask;
+    XQueryPointer(dpy, DefaultRootWindow(dpy), &ret_root, &child, &rx, &ry, &wx, &wy, &mask);
+    XCloseDisplay(dpy);
+    return child;
+}
+
+// own_title returns a copy of the title of win (UTF-8 _NET_WM_NAME, else
+// WM_NAME), or NULL; the caller frees it.
+static char* own_title(Display* dpy, Window win) {
+    Atom type;
+    int format;
+    unsigned long n, after;
+    unsigned char* data = NULL;
+    Atom net_wm_name = XInternAtom(dpy, "_NET_WM_NAME", False);
+    Atom utf8 = XInternAtom(dpy, "UTF8_STRING", False);
+    if (XGetWindowProperty(dpy, win, net_wm_name, 0, 1024, False, utf8,
+            &type, &format, &n, &after, &data) == Success && data && n > 0) {
+        char* s = strndup((char*)data, n);
+        XFree(data);
+        return s;
+    }
+    if (data) XFree(data);
+    char* name = NULL;
+    if (XFetchName(dpy, win, &name) && name) {
+        char* s = strdup(name);
+        XFree(name);
+        return s;
+    }
+    return NULL;
+}
+
+// window_title returns the title of win or, for a WM frame without one,
+// of the client window inside it, with the same care as followed_geometry.
+static char* window_title(Display* dpy, Window win) {
+    int (*old)(Display*, XErrorEvent*) = XSetErrorHandler(follow_err_handler);
+    follow_xerr = 0;
+    char* s = own_title(dpy, win);
+    Window root, parent, *children = NULL;
+    unsigned int n = 0;
+    if (!s && XQueryTree(dpy, win, &root, &parent, &children, &n)) {
+        for (unsigned int i = 0; i < n && !s; i++) {
+            s = own_title(dpy, children[i]);
+        }
+        if (children) XFree(children);
+    }
+    XSync(dpy, False);
+    XSetErrorHandler(old);
+    if (follow_xerr != 0 && s) {
+        free(s);
+        s = NULL;
+    }
+    return s;
+}
+
+static void move_resize_raised(Display* dpy, Window win, int x, int y, int w, int h) {
+    XMoveResizeWindow(dpy, win, x, y, (unsigned)w, (unsigned)h);
+    XRaiseWindow(dpy, win);
+    XFlush(dpy);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"unsafe"
+)
+
+// x11FollowedGeometry reports the rectangle of window, which may belong to
+// another client, in root coordinates; errNotViewable comes with the
+// rectangle of one that is not shown.
+func x11FollowedGeometry(display unsafe.Pointer, window uintptr) (image.Rectangle, error) {
+	if display == nil || window == 0 {
+		return image.Rectangle{}, fmt.Errorf("invalid X11 handles")
+	}
+	var x, y, w, h, viewable C.int
+	if C.followed_geometry((*C.Display)(display), C.Window(window), &x, &y, &w, &h, &viewable) == 0 {
+		return image.Rectangle{}, fmt.Errorf("window 0x%x does not exist", window)
+	}
+	r := image.Rect(int(x), int(y), int(x+w), int(y+h))
+	if viewable == 0 {
+		return r, errNotViewable
+	}
+	return r, nil
+}
+
+// x11WindowTitle returns the title of window, which may belong to another
+// client.
+func x11WindowTitle(display unsafe.Pointer, window uintptr) (string, error) {
+	if display == nil || window == 0 {
+		return "", fmt.Errorf("invalid X11 handles")
+	}
+	s := C.window_title((*C.Display)(display), C.Window(window))
+	if s == nil {
+		return "", fmt.Errorf("window 0x%x has no title", window)
+	}
+	defer C.free(unsafe.Pointer(s))
+	return C.GoString(s), nil
+}
+
+// x11WindowUnderPointer returns the top-level window under the pointer.
+func x11WindowUnderPointer() (uintptr, error) {
+	win := uintptr(C.top_window_under_pointer())
+	if win == 0 {
+		return 0, fmt.Errorf("no window under the pointer")
+	}
+	return win, nil
+}
+
+// x11MoveResizeWindow makes an override-redirect window cover r (root
+// coordinates) and raises it.
+func x11MoveResizeWindow(display unsafe.Pointer, window uintptr, r image.Rectangle) error {
+	if display == nil || window == 0 {
+		return fmt.Errorf("invalid X11 handles")
+	}
+	if r.Empty() {
+		return fmt.Errorf("empty target area %v", r)
+	}
+	C.move_resize_raised((*C.Display)(display), C.Window(window), C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()))
+	return nil
+}
@@ -0,0 +1,65 @@
+//go:build linux && !android
+
+package main
+
+/*
+#cgo linux LDFLAGS: -lX11 -lXinerama
+#include <X11/Xlib.h>
+#include <X11/extensions/Xinerama.h>
+#include <stdlib.h>
+
+// query_monitors fills out (x, y, w, h per monitor, up to max monitors)
+// and returns the number found, or -1 if the display can't be opened.
+static int query_monitors(int* out, int max) {
+    Display* dpy = XOpenDisplay(NULL);
+    if (!dpy) return -1;
+    int n = 0;
+    if (XineramaIsActive(dpy)) {
+        XineramaScreenInfo* s = XineramaQueryScreens(dpy, &n);
+        if (n > max) n = max;
+        for (int i = 0; i < n; i++) {
+            out[4*i+0] = s[i].x_org;
+            out[4*i+1] = s[i].y_org;
+            out[4*i+2] = s[i].width;
+            out[4*i+3] = s[i].height;
+        }
+        if (s) XFree(s);
+    }
+    if (n == 0) {
+        // No Xinerama: the whole root window is one monitor.
+        Screen* scr = DefaultScreenOfDisplay(dpy);
+        out[0] = 0;
+        out[1] = 0;
+        out[2] = WidthOfScreen(scr);
+        out[3] = HeightOfScreen(scr);
+        n = 1;
+    }
+    XCloseDisplay(dpy);
+    return n;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+)
+
+// x11MaxMonitors bounds the monitor query; more than this is unlikely.
+const x11MaxMonitors = 16
+
+// x11Monitors lists the monitor rectangles in root coordinates, using its
+// own X connection so it can run before any window exists.
+func x11Monitors() ([]image.Rectangle, error) {
+	var buf [4 * x11MaxMonitors]C.int
+	n := int(C.query_monitors(&buf[0], x11MaxMonitors))
+	if n < 0 {
+		return nil, fmt.Errorf("cannot open X display")
+	}
+	mons := make([]image.Rectangle, n)
+	for i := range mons {
+		x, y, w, h := int(buf[4*i]), int(buf[4*i+1]), int(buf[4*i+2]), int(buf[4*i+3])
+		mons[i] = image.Rect(x, y, x+w, y+h)
+	}
+	return mons, nil
+}
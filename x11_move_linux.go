@@ -21,11 +21,24 @@ static int move_to_pointer(Display* dpy, Window win) {
     XFlush(dpy);
     return 1;
 }
+
+static int pointer_position(Display* dpy, int* x, int* y) {
+    Window ret_root, ret_child;
+    int win_x, win_y;
+    unsigned int mask;
+    return XQueryPointer(dpy, DefaultRootWindow(dpy), &ret_root, &ret_child, x, y, &win_x, &win_y, &mask);
+}
+
+static void move_to(Display* dpy, Window win, int x, int y) {
+    XMoveWindow(dpy, win, x, y);
+    XFlush(dpy);
+}
 */
 import "C"
 
 import (
 	"fmt"
+	"image"
 	"unsafe"
 )
 
@@ -40,3 +53,25 @@ func x11MoveWindowToPointer(display unsafe.Pointer, window uintptr) error {
 	}
 	return nil
 }
+
+// x11MoveWindowTo moves the window's top-left corner to (x, y) in root
+// coordinates, e.g. inside a given monitor before going fullscreen.
+func x11MoveWindowTo(display unsafe.Pointer, window uintptr, x, y int) error {
+	if display == nil || window == 0 {
+		return fmt.Errorf("invalid X11 handles")
+	}
+	C.move_to((*C.Display)(display), C.Window(window), C.int(x), C.int(y))
+	return nil
+}
+
+// x11PointerPosition reports the pointer position in root coordinates.
+func x11PointerPosition(display unsafe.Pointer) (image.Point, error) {
+	if display == nil {
+		return image.Point{}, fmt.Errorf("invalid X11 handles")
+	}
+	var x, y C.int
+	if C.pointer_position((*C.Display)(display), &x, &y) == 0 {
+		return image.Point{}, fmt.Errorf("XQueryPointer failed")
+	}
+	return image.Pt(int(x), int(y)), nil
+}
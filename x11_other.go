@@ -0,0 +1,51 @@
+//go:build !linux || android
+
+package main
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+
+	"gioui.org/io/event"
+)
+
+// Elsewhere than on Linux there is no X11 window to place, capture or make
+// an overlay of: the window is an ordinary fullscreen one, and what needs
+// X11 (capturing the screen, opacity, click-through, the cursor, following
+// windows, monitors) fails with errNoX11, which the callers already report
+// as they do any X11 error.
+
+var errNoX11 = errors.New("X11 is only available on Linux")
+
+func (a *Annotator) viewEvent(event.Event) {}
+
+func x11WindowOrigin(unsafe.Pointer, uintptr) (image.Point, error) {
+	return image.Point{}, errNoX11
+}
+
+func x11CaptureScreen(unsafe.Pointer, uintptr) (*image.RGBA, error) { return nil, errNoX11 }
+
+func x11HideCursor(unsafe.Pointer, uintptr) error { return errNoX11 }
+
+func x11ShowCursor(unsafe.Pointer, uintptr) error { return errNoX11 }
+
+func x11FollowedGeometry(unsafe.Pointer, uintptr) (image.Rectangle, error) {
+	return image.Rectangle{}, errNoX11
+}
+
+func x11WindowTitle(unsafe.Pointer, uintptr) (string, error) { return "", errNoX11 }
+
+func x11WindowUnderPointer() (uintptr, error) { return 0, errNoX11 }
+
+func x11MoveResizeWindow(unsafe.Pointer, uintptr, image.Rectangle) error { return errNoX11 }
+
+func x11Monitors() ([]image.Rectangle, error) { return nil, errNoX11 }
+
+func x11PointerPosition(unsafe.Pointer) (image.Point, error) { return image.Point{}, errNoX11 }
+
+func x11CoverOverrideRedirect(unsafe.Pointer, uintptr, image.Rectangle) error { return errNoX11 }
+
+func x11SetOpacity(unsafe.Pointer, uintptr, uint32) error { return errNoX11 }
+
+func x11SetClickThrough(unsafe.Pointer, uintptr, bool) error { return errNoX11 }
@@ -70,6 +70,19 @@ static int set_window_type_normal(Display* dpy, Window win) {
                            (unsigned char*)&normal, 1) == Success;
 }
 
+// cover_override_redirect takes the window away from the window manager
+// and makes it cover the given root area exactly. override_redirect only
+// takes effect on map, hence the unmap/map cycle.
+static void cover_override_redirect(Display* dpy, Window win, int x, int y, int w, int h) {
+    XSetWindowAttributes attrs;
+    attrs.override_redirect = True;
+    XUnmapWindow(dpy, win);
+    XChangeWindowAttributes(dpy, win, CWOverrideRedirect, &attrs);
+    XMoveResizeWindow(dpy, win, x, y, (unsigned)w, (unsigned)h);
+    XMapRaised(dpy, win);
+    XSync(dpy, False);
+}
+
 static int set_click_through(Display* dpy, Window win, int enable) {
     int ev, er;
     if (!XShapeQueryExtension(dpy, &ev, &er)) return 0;
@@ -86,10 +99,15 @@ import "C"
 
 import (
 	"fmt"
+	"image"
 	"unsafe"
 )
 
-func x11EnableOverlayHints(display unsafe.Pointer, window uintptr) error {
+// x11EnableOverlayHints asks the window manager to keep the window above
+// others and out of taskbars. With fullscreen it also sets
+// _NET_WM_STATE_FULLSCREEN, supplementing Gio's own fullscreen request on
+// WMs that leave borders or panels uncovered.
+func x11EnableOverlayHints(display unsafe.Pointer, window uintptr, fullscreen bool) error {
 	if display == nil || window == 0 {
 		return fmt.Errorf("invalid X11 handles")
 	}
@@ -103,7 +121,9 @@ func x11EnableOverlayHints(display unsafe.Pointer, window uintptr) error {
 
 	C.set_window_type_normal(dpy, win)
 	C.set_wm_state(dpy, win, above, 1)
-	C.set_wm_state(dpy, win, full, 1)
+	if fullscreen {
+		C.set_wm_state(dpy, win, full, 1)
+	}
 	C.set_wm_state(dpy, win, skipTaskbar, 1)
 	C.set_wm_state(dpy, win, skipPager, 1)
 
@@ -112,6 +132,24 @@ func x11EnableOverlayHints(display unsafe.Pointer, window uintptr) error {
 	return nil
 }
 
+// x11CoverOverrideRedirect makes the window an override-redirect window
+// covering r (root coordinates), for WMs that keep repositioning or
+// shrinking it. Such windows get no focus from the WM, so focus is taken
+// explicitly.
+func x11CoverOverrideRedirect(display unsafe.Pointer, window uintptr, r image.Rectangle) error {
+	if display == nil || window == 0 {
+		return fmt.Errorf("invalid X11 handles")
+	}
+	if r.Empty() {
+		return fmt.Errorf("empty target area %v", r)
+	}
+	dpy := (*C.Display)(display)
+	win := C.Window(window)
+	C.cover_override_redirect(dpy, win, C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()))
+	C.safe_set_input_focus(dpy, win)
+	return nil
+}
+
 func x11SetOpacity(display unsafe.Pointer, window uintptr, opacity uint32) error {
 	if display == nil || window == 0 {
 		return fmt.Errorf("invalid X11 handles")